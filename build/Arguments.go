@@ -0,0 +1,32 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/akyoto/asm"
+)
+
+// addArguments passes the argument count in rdi and the pointer to the argument pointers in rsi to 'main'.
+// The kernel places the argument count at the initial stack pointer followed by the argument pointers.
+// The offset is the number of bytes that have been pushed since program entry.
+func (build *Build) addArguments(code *asm.Assembler, offset uint64) {
+	code.MoveRegisterRegister("rsi", "rsp")
+
+	if offset != 0 {
+		code.AddRegisterNumber("rsi", offset)
+	}
+
+	code.LoadRegister("rdi", "rsi", 0, 8)
+	code.AddRegisterNumber("rsi", 8)
+}
+
+// argumentsAssembly returns the code of addArguments in Intel syntax.
+func (build *Build) argumentsAssembly(offset uint64) string {
+	add := ""
+
+	if offset != 0 {
+		add = fmt.Sprintf("\tadd rsi, %d\n", offset)
+	}
+
+	return fmt.Sprintf("\tmov rsi, rsp\n%s\tmov rdi, qword ptr [rsi]\n\tadd rsi, 8\n", add)
+}
@@ -0,0 +1,110 @@
+package build
+
+import (
+	"math"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// ArrayState handles the state of arrays on the stack.
+type ArrayState struct {
+	stackSize uint64
+}
+
+// AllocateArray reserves the number of bytes given in an expression like `[10]` on the stack
+// and moves the address of the first element into the register.
+// The memory is reserved in the function prologue and stays valid until the function returns.
+func (state *State) AllocateArray(tokens []token.Token, register *register.Register) (*types.Type, error) {
+	if tokens[len(tokens)-1].Kind != token.ArrayEnd {
+		return nil, errors.New(&errors.MissingCharacter{Character: "]"})
+	}
+
+	size, isConstant := state.ConstantInt(tokens[1 : len(tokens)-1])
+
+	if !isConstant {
+		return nil, errors.New(errors.NotConstant)
+	}
+
+	// Each array starts at an 8-byte aligned address
+	alignedSize := (uint64(size) + 7) &^ 7
+
+	if size <= 0 || state.arrayState.stackSize+alignedSize > math.MaxInt32 {
+		return nil, errors.New(errors.InvalidArraySize)
+	}
+
+	if state.arrayState.stackSize == 0 {
+		state.assembler.MoveRegisterRegister(register, state.registers.Stack)
+	} else {
+		state.assembler.LoadAddress(register, state.registers.Stack, nil, 0, int32(state.arrayState.stackSize))
+	}
+
+	state.arrayState.stackSize += alignedSize
+	return types.Pointer, nil
+}
+
+// ArrayElement loads the byte at the index of an array access expression.
+// The array address is already stored in the expression register.
+func (state *State) ArrayElement(sub *expression.Expression, index *expression.Expression) error {
+	if sub.Children[0].Type == types.Float64 {
+		return errors.New(&errors.InvalidType{Name: sub.Children[0].Type.String(), Expected: types.Pointer.String()})
+	}
+
+	sub.Type = types.Int
+
+	if index.IsLeaf() && index.Token.Kind == token.Number {
+		if IsFloatLiteral(index.Token) {
+			return errors.New(&errors.InvalidType{Name: types.Float64.String(), Expected: types.Int.String()})
+		}
+
+		number, err := state.ParseInt(index.Token.Text())
+
+		if err != nil {
+			return err
+		}
+
+		// Small constant indices are encoded as an offset
+		if number >= 0 && number <= math.MaxInt8 {
+			state.assembler.LoadZeroExtend(sub.Register, sub.Register, byte(number), 1)
+			return nil
+		}
+	}
+
+	err := state.calculateOperands("+", sub, sub.Children[0], index)
+
+	if err != nil {
+		return err
+	}
+
+	if index.Type == types.Float64 {
+		return errors.New(&errors.InvalidType{Name: index.Type.String(), Expected: types.Int.String()})
+	}
+
+	state.assembler.LoadZeroExtend(sub.Register, sub.Register, 0, 1)
+	return nil
+}
+
+// ReserveArrays reserves the stack memory for all arrays in the function.
+// The total size is a multiple of 16 so that the stack alignment is preserved.
+func (state *State) ReserveArrays() {
+	if state.arrayState.stackSize == 0 {
+		return
+	}
+
+	size := (state.arrayState.stackSize + 15) &^ 15
+	state.assembler.ReserveStack(state.registers.Stack, size)
+}
+
+// allocatesArrays tells you whether the tokens contain an array allocation like `let a = [10]`.
+func allocatesArrays(tokens []token.Token) bool {
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].Kind == token.ArrayStart && tokens[i-1].Kind == token.Operator && tokens[i-1].Text() == "=" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,221 @@
+package build
+
+import (
+	"math"
+
+	"github.com/akyoto/q/build/assembler/instructions"
+	"github.com/akyoto/q/build/assembler/mnemonics"
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+)
+
+// Operand forms of the instructions in inline assembly.
+const (
+	inlineNone = 1 << iota
+	inlineRegister
+	inlineRegisterRegister
+	inlineRegisterNumber
+	inlineFloatFloat
+	inlineFloatRegister
+	inlineRegisterFloat
+)
+
+// inlineMnemonics contains the mnemonics that can be used in inline assembly
+// and the operand forms they support. Instructions that modify the stack pointer
+// are not allowed because stack variables are addressed relative to it.
+var inlineMnemonics = map[string]int{
+	mnemonics.SYSCALL:   inlineNone,
+	mnemonics.INC:       inlineRegister,
+	mnemonics.DEC:       inlineRegister,
+	mnemonics.NEG:       inlineRegister,
+	mnemonics.NOT:       inlineRegister,
+	mnemonics.MOV:       inlineRegisterRegister | inlineRegisterNumber,
+	mnemonics.CMP:       inlineRegisterRegister | inlineRegisterNumber,
+	mnemonics.ADD:       inlineRegisterRegister | inlineRegisterNumber,
+	mnemonics.SUB:       inlineRegisterRegister | inlineRegisterNumber,
+	mnemonics.MUL:       inlineRegisterRegister | inlineRegisterNumber,
+	mnemonics.AND:       inlineRegisterRegister | inlineRegisterNumber,
+	mnemonics.OR:        inlineRegisterRegister | inlineRegisterNumber,
+	mnemonics.XOR:       inlineRegisterRegister | inlineRegisterNumber,
+	mnemonics.TEST:      inlineRegisterRegister,
+	mnemonics.CMOVL:     inlineRegisterRegister,
+	mnemonics.CMOVG:     inlineRegisterRegister,
+	mnemonics.SHL:       inlineRegisterNumber,
+	mnemonics.SAR:       inlineRegisterNumber,
+	mnemonics.SHR:       inlineRegisterNumber,
+	mnemonics.MOVQ:      inlineFloatRegister | inlineRegisterFloat,
+	mnemonics.ADDSD:     inlineFloatFloat,
+	mnemonics.SUBSD:     inlineFloatFloat,
+	mnemonics.MULSD:     inlineFloatFloat,
+	mnemonics.DIVSD:     inlineFloatFloat,
+	mnemonics.CVTSI2SD:  inlineFloatRegister,
+	mnemonics.CVTTSD2SI: inlineRegisterFloat,
+}
+
+// AssemblyStart handles the start of inline assembly blocks.
+func (state *State) AssemblyStart(tokens []token.Token) error {
+	if len(tokens) != 1 {
+		return errors.New(errors.InvalidInstruction)
+	}
+
+	return nil
+}
+
+// Assembly translates a line of inline assembly to the corresponding instruction.
+// The operands can be register names and integer numbers.
+// Names like `eax` or `al` limit the operation to the lower bytes of the registers.
+// The compiler doesn't know which registers are modified,
+// therefore the code must not overwrite registers that hold variables.
+func (state *State) Assembly(tokens []token.Token) error {
+	name := tokens[0].Text()
+	forms, exists := inlineMnemonics[name]
+
+	if tokens[0].Kind != token.Identifier || !exists {
+		return errors.New(&errors.UnknownMnemonic{Name: name})
+	}
+
+	var operands [][]token.Token
+
+	if len(tokens) > 1 {
+		operands = token.Split(tokens[1:], token.Separator)
+	}
+
+	state.tokenCursor++
+
+	switch len(operands) {
+	case 0:
+		if forms&inlineNone == 0 {
+			return errors.New(&errors.InvalidOperands{Mnemonic: name})
+		}
+
+		state.assembler.Inline(name)
+		return nil
+
+	case 1:
+		destination, byteCount, err := state.inlineRegister(operands[0])
+
+		if err != nil {
+			return err
+		}
+
+		if forms&inlineRegister == 0 || state.isFloatRegister(destination) || (byteCount != 8 && !instructions.SupportsByteCount(name, 1)) {
+			return errors.New(&errors.InvalidOperands{Mnemonic: name})
+		}
+
+		state.assembler.SizedRegister(name, destination, byteCount)
+		return nil
+
+	case 2:
+		destination, byteCount, err := state.inlineRegister(operands[0])
+
+		if err != nil {
+			return err
+		}
+
+		state.tokenCursor += len(operands[0]) + 1
+		source := operands[1]
+
+		if len(source) == 1 && source[0].Kind == token.Identifier {
+			from, sourceByteCount, err := state.inlineRegister(source)
+
+			if err != nil {
+				return err
+			}
+
+			if forms&state.registerForm(destination, from) == 0 || sourceByteCount != byteCount || (byteCount != 8 && !instructions.SupportsByteCount(name, 2)) {
+				return errors.New(&errors.InvalidOperands{Mnemonic: name})
+			}
+
+			state.assembler.SizedRegisterRegister(name, destination, from, byteCount)
+			return nil
+		}
+
+		if forms&inlineRegisterNumber == 0 || state.isFloatRegister(destination) || (byteCount != 8 && !instructions.SupportsByteCountNumber(name)) {
+			return errors.New(&errors.InvalidOperands{Mnemonic: name})
+		}
+
+		number, err := state.inlineNumber(source)
+
+		if err != nil {
+			return err
+		}
+
+		if !fitsImmediate(name, number, byteCount) {
+			return errors.New(&errors.InvalidOperand{Operand: token.List(source).String()})
+		}
+
+		state.assembler.SizedRegisterNumber(name, destination, uint64(number), byteCount)
+		return nil
+
+	default:
+		return errors.New(&errors.InvalidOperands{Mnemonic: name})
+	}
+}
+
+// inlineRegister returns the register with the name of the operand
+// and the number of bytes addressed by the name.
+// The stack pointer can't be used because stack variables are addressed relative to it.
+func (state *State) inlineRegister(operand []token.Token) (*register.Register, byte, error) {
+	if len(operand) != 1 || operand[0].Kind != token.Identifier {
+		return nil, 0, errors.New(&errors.InvalidOperand{Operand: token.List(operand).String()})
+	}
+
+	name, byteCount := register.ByteCount(operand[0].Text())
+	reg := state.registers.All.ByName(name)
+
+	if reg == nil || reg == state.registers.Stack {
+		return nil, 0, errors.New(&errors.InvalidOperand{Operand: operand[0].Text()})
+	}
+
+	return reg, byteCount, nil
+}
+
+// fitsImmediate tells you whether the number can be encoded as an immediate value
+// for an operation on the given number of bytes.
+// Only moves to 64-bit registers can use 64-bit immediate values.
+func fitsImmediate(mnemonic string, number int64, byteCount byte) bool {
+	if byteCount == 8 {
+		return mnemonic == mnemonics.MOV || (number >= math.MinInt32 && number <= math.MaxInt32)
+	}
+
+	bits := uint(byteCount) * 8
+	return number >= -(1<<(bits-1)) && number < 1<<bits
+}
+
+// registerForm returns the operand form of an instruction using the given registers.
+func (state *State) registerForm(destination *register.Register, source *register.Register) int {
+	switch {
+	case state.isFloatRegister(destination) && state.isFloatRegister(source):
+		return inlineFloatFloat
+
+	case state.isFloatRegister(destination):
+		return inlineFloatRegister
+
+	case state.isFloatRegister(source):
+		return inlineRegisterFloat
+
+	default:
+		return inlineRegisterRegister
+	}
+}
+
+// isFloatRegister tells you whether the register is an SSE register.
+func (state *State) isFloatRegister(reg *register.Register) bool {
+	return state.registers.Float.ByName(reg.Name) != nil
+}
+
+// inlineNumber parses an integer operand which can be negative.
+func (state *State) inlineNumber(operand []token.Token) (int64, error) {
+	switch {
+	case len(operand) == 1 && operand[0].Kind == token.Number:
+		return state.ParseInt(operand[0].Text())
+
+	case len(operand) == 2 && operand[0].Kind == token.Operator && operand[0].Text() == "-" && operand[1].Kind == token.Number:
+		number, err := state.ParseInt(operand[1].Text())
+		return -number, err
+
+	default:
+		return 0, errors.New(&errors.InvalidOperand{Operand: token.List(operand).String()})
+	}
+}
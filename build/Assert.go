@@ -0,0 +1,120 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/syscall"
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/token"
+)
+
+const (
+	// AssertLabel is the label of the assertion failure handler shared by all functions.
+	AssertLabel = "assert.trap"
+
+	// AssertExitCode is the exit code of a program with a failed assertion.
+	AssertExitCode = 103
+)
+
+// AssertState handles the state of assert compilation.
+type AssertState struct {
+	counter int
+	list    []Assert
+}
+
+// Assert represents a call to the assert builtin.
+type Assert struct {
+	message   string
+	failLabel string
+}
+
+// Assert checks a condition at runtime and exits the program if it's false.
+// Optimized builds remove all assertions.
+func (state *State) Assert(tokens []token.Token) error {
+	condition := tokens[2 : len(tokens)-1]
+	count := parameterCount(condition)
+
+	if count != 1 {
+		return errors.New(&errors.ParameterCount{
+			FunctionName:  BuiltinAssert,
+			CountGiven:    count,
+			CountRequired: 1,
+		})
+	}
+
+	if state.ignoreContracts {
+		return nil
+	}
+
+	line, column := state.function.SourcePosition(state.tokenCursor)
+	state.assertState.counter++
+	failLabel := fmt.Sprintf("assert_%d_fail", state.assertState.counter)
+
+	state.assertState.list = append(state.assertState.list, Assert{
+		message:   fmt.Sprintf("%s:%d:%d: assert %v", filepath.Base(state.function.File.path), line, column, condition),
+		failLabel: failLabel,
+	})
+
+	// A failed assertion exits the program
+	atomic.AddInt32(&state.function.SideEffects, 1)
+	return state.Condition(condition, failLabel)
+}
+
+// AssertFailures adds the code that shows the message of each failed assertion
+// and jumps to the shared handler.
+func (state *State) AssertFailures() {
+	for _, assert := range state.assertState.list {
+		state.assembler.AddLabel(assert.failLabel)
+		state.printLn(assert.message)
+		state.assembler.Jump(AssertLabel)
+	}
+}
+
+// addAssertHandler adds the assertion failure handler which exits the program.
+func (build *Build) addAssertHandler(code *asm.Assembler) {
+	code.AddLabel(AssertLabel)
+	code.MoveRegisterNumber(syscall.Registers[0], build.Target.SyscallExit)
+	code.MoveRegisterNumber(syscall.Registers[1], AssertExitCode)
+	code.Syscall()
+}
+
+// usesAssert tells you whether one of the functions jumps to the assertion failure handler.
+func usesAssert(functions []*Function) bool {
+	for _, function := range functions {
+		if function.assembler.JumpsTo(AssertLabel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parameterCount returns the number of comma-separated parameters in the tokens.
+func parameterCount(tokens []token.Token) int {
+	if len(tokens) == 0 {
+		return 0
+	}
+
+	count := 1
+	groups := 0
+
+	for _, t := range tokens {
+		switch t.Kind {
+		case token.GroupStart, token.ArrayStart:
+			groups++
+
+		case token.GroupEnd, token.ArrayEnd:
+			groups--
+
+		case token.Separator:
+			if groups == 0 {
+				count++
+			}
+		}
+	}
+
+	return count
+}
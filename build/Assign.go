@@ -2,30 +2,55 @@ package build
 
 import (
 	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/operators"
 	"github.com/akyoto/q/build/token"
 )
 
 // Assignment handles assignment instructions.
 func (state *State) Assignment(tokens []token.Token) error {
-	operatorPos := token.Index(tokens, token.Operator, "=")
+	operatorPos := -1
+
+	for i, t := range tokens {
+		if t.Kind == token.Operator && operators.All[t.Text()].Kind == operators.Assignment {
+			operatorPos = i
+			break
+		}
+	}
 
 	if operatorPos == -1 {
 		return errors.New(errors.MissingAssignmentOperator)
 	}
 
 	left := tokens[:operatorPos]
+	isCompound := tokens[operatorPos].Text() != "="
 
 	if left[operatorPos-1].Kind == token.ArrayEnd {
+		if isCompound {
+			return errors.New(errors.NotImplemented)
+		}
+
 		return state.AssignArrayElement(tokens, operatorPos)
 	}
 
 	for _, t := range left {
+		if t.Kind == token.Keyword && t.Text() == "const" {
+			return state.Const(tokens)
+		}
+
 		if t.Kind == token.Keyword && (t.Text() == "let" || t.Text() == "mut") {
+			if isCompound {
+				return errors.New(errors.MissingAssignmentOperator)
+			}
+
 			_, err := state.AssignVariable(tokens, false)
 			return err
 		}
 
 		if t.Kind == token.Operator && t.Text() == "." {
+			if isCompound {
+				return errors.New(errors.NotImplemented)
+			}
+
 			return state.AssignStructField(tokens, operatorPos)
 		}
 	}
@@ -1,10 +1,12 @@
 package build
 
 import (
-	"strconv"
+	"math"
+	"sync/atomic"
 
 	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
 )
 
 // AssignArrayElement assigns a value to an array element.
@@ -13,7 +15,7 @@ func (state *State) AssignArrayElement(tokens []token.Token, operatorPos token.P
 	right := tokens[operatorPos+1:]
 	suffix := left[1:]
 
-	if suffix[0].Kind != token.ArrayStart || suffix[len(suffix)-1].Kind != token.ArrayEnd {
+	if len(suffix) < 3 || suffix[0].Kind != token.ArrayStart || suffix[len(suffix)-1].Kind != token.ArrayEnd {
 		return errors.New(errors.MissingArrayIndex)
 	}
 
@@ -21,29 +23,74 @@ func (state *State) AssignArrayElement(tokens []token.Token, operatorPos token.P
 		return errors.New(errors.ExpectedVariable)
 	}
 
+	if len(right) == 0 {
+		return errors.MissingAssignmentExpression
+	}
+
 	arrayName := left[0].Text()
 	array := state.scopes.Get(arrayName)
 
-	valueString := right[0].Text()
-	value, err := strconv.Atoi(valueString)
-
-	if err != nil {
-		return err
+	if array == nil {
+		return errors.New(state.UnknownVariableError(arrayName))
 	}
 
+	state.UseVariable(array)
+	address := array.Register()
+	offset := byte(0)
 	indexTokens := suffix[1 : len(suffix)-1]
+	index, isConstant := state.ConstantInt(indexTokens)
+
+	// Small constant indices are encoded as an offset,
+	// all other indices are added to the array address.
+	if isConstant && index >= 0 && index <= math.MaxInt8 {
+		offset = byte(index)
+	} else {
+		address = state.FindFreeRegister()
+
+		if address == nil {
+			return errors.New(errors.ExceededMaxVariables)
+		}
+
+		address.ForceUse(token.List(indexTokens))
+		defer address.Free()
+		typ, err := state.TokensToRegister(indexTokens, address)
+
+		if err != nil {
+			return err
+		}
 
-	if len(indexTokens) > 1 || indexTokens[0].Kind != token.Number {
-		return errors.New(errors.NotImplemented)
+		if typ == types.Float64 {
+			return errors.New(&errors.InvalidType{Name: typ.String(), Expected: types.Int.String()})
+		}
+
+		state.assembler.AddRegisterRegister(address, array.Register())
 	}
 
-	indexString := indexTokens[0].Text()
-	index, err := strconv.Atoi(indexString)
+	// Writes to memory are visible outside of the function
+	atomic.AddInt32(&state.function.SideEffects, 1)
+	value, isConstant := state.ConstantInt(right)
+
+	if isConstant {
+		state.assembler.StoreNumber(address, offset, 1, uint64(value))
+		return nil
+	}
+
+	valueRegister, typ, err := state.EvaluateTokens(right)
 
 	if err != nil {
 		return err
 	}
 
-	state.assembler.StoreNumber(array.Register(), byte(index), byte(1), uint64(value))
+	_, isVariable := valueRegister.User().(*Variable)
+
+	if !isVariable {
+		defer valueRegister.Free()
+	}
+
+	if typ == types.Float64 {
+		return errors.New(&errors.InvalidType{Name: typ.String(), Expected: types.Int.String()})
+	}
+
+	state.assembler.StoreRegister(address, offset, 1, valueRegister)
 	return nil
 }
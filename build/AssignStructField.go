@@ -1,6 +1,8 @@
 package build
 
 import (
+	"sync/atomic"
+
 	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/token"
 )
@@ -22,6 +24,8 @@ func (state *State) AssignStructField(tokens token.List, operatorPos token.Posit
 		return errors.New(UnknownFieldError(fieldName, variable.Type))
 	}
 
+	// Writes to memory are visible outside of the function
+	atomic.AddInt32(&state.function.SideEffects, 1)
 	right := tokens[operatorPos+1:]
 
 	if len(right) == 1 && right[0].Kind == token.Number {
@@ -45,10 +49,10 @@ func (state *State) AssignStructField(tokens token.List, operatorPos token.Posit
 		return errors.New(&errors.InvalidType{Name: rightType.String(), Expected: field.Type.String()})
 	}
 
-	registerUseError := rightRegister.Use(right)
 	state.assembler.StoreRegister(variable.Register(), byte(field.Offset), byte(field.Type.Size), rightRegister)
+	_, isVariable := rightRegister.User().(*Variable)
 
-	if registerUseError == nil {
+	if !isVariable {
 		rightRegister.Free()
 	}
 
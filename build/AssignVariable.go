@@ -1,8 +1,12 @@
 package build
 
 import (
+	"strings"
+
 	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/log"
 	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
 )
 
 // AssignVariable handles assignment instructions and also returns the referenced variable.
@@ -39,6 +43,16 @@ func (state *State) AssignVariable(tokens []token.Token, isNewVariable bool) (*V
 
 	assignPos := state.tokenCursor
 	variableName := left.Text()
+
+	// Values assigned to '_' are discarded
+	if variableName == "_" {
+		if tokens[cursor+1].Text() != "=" {
+			return nil, errors.New(errors.InvalidExpression)
+		}
+
+		state.tokenCursor += 2
+		return nil, state.Discard(tokens[cursor+2:])
+	}
 	variable := state.scopes.Get(variableName)
 
 	if isNewVariable {
@@ -46,7 +60,13 @@ func (state *State) AssignVariable(tokens []token.Token, isNewVariable bool) (*V
 			return variable, errors.New(&errors.VariableAlreadyExists{Name: variable.Name})
 		}
 
-		register := state.registers.General.FindFree()
+		_, isConstant := state.function.File.constants[variableName]
+
+		if isConstant {
+			return nil, errors.New(&errors.VariableAlreadyExists{Name: variableName})
+		}
+
+		register := state.FindFreeRegister()
 
 		if register == nil {
 			return nil, errors.ExceededMaxVariables
@@ -64,6 +84,12 @@ func (state *State) AssignVariable(tokens []token.Token, isNewVariable bool) (*V
 		variable.ForceSetRegister(register)
 		defer state.scopes.Add(variable)
 	} else {
+		_, isConstant := state.function.File.constants[variableName]
+
+		if isConstant || (variable != nil && variable.IsConstant) {
+			return nil, errors.New(&errors.ConstantAssignment{Name: variableName})
+		}
+
 		if variable == nil {
 			return nil, errors.New(state.UnknownVariableError(variableName))
 		}
@@ -71,11 +97,18 @@ func (state *State) AssignVariable(tokens []token.Token, isNewVariable bool) (*V
 		if !variable.Mutable {
 			return variable, errors.New(&errors.ImmutableVariable{Name: variable.Name})
 		}
+
+		// The counter is increased after each iteration regardless of the new value
+		if variable.IsLoopCounter && state.environment.CounterWriteWarnings {
+			warning := state.function.NewError(state.tokenCursor, &errors.LoopCounterWrite{Name: variable.Name})
+			log.Error.Println(warning)
+		}
 	}
 
 	// Skip operator
 	cursor++
 	state.tokenCursor++
+	operator := tokens[cursor].Text()
 
 	// Expression
 	cursor++
@@ -93,8 +126,20 @@ func (state *State) AssignVariable(tokens []token.Token, isNewVariable bool) (*V
 		return variable, nil
 	}
 
-	// Move result of expression to register
-	typ, err := state.TokensToRegister(value, variable.Register())
+	var typ *types.Type
+	var err error
+
+	if operator == "=" && value[0].Kind == token.ArrayStart {
+		// Arrays like [10] are allocated on the stack
+		typ, err = state.AllocateArray(value, variable.Register())
+	} else if operator == "=" {
+		// Move result of expression to register
+		typ, err = state.TokensToRegister(value, variable.Register())
+	} else {
+		// Compound assignments like x += 1 read the old value first
+		state.UseVariable(variable)
+		typ, err = state.CalculateRegisterTokens(strings.TrimSuffix(operator, "="), variable.Register(), value)
+	}
 
 	if err != nil {
 		return variable, err
@@ -102,10 +147,13 @@ func (state *State) AssignVariable(tokens []token.Token, isNewVariable bool) (*V
 
 	if isNewVariable {
 		variable.Type = typ
-	} else if typ != variable.Type {
+	} else if literalType(value, typ, variable.Type) != variable.Type {
 		return variable, errors.New(&errors.InvalidType{Name: typ.String(), Expected: variable.Type.String()})
 	}
 
+	// Texts have the same type as integers and need to be remembered separately
+	variable.IsText = operator == "=" && state.isText(value)
+
 	// Check for ineffective assignments
 	if !isNewVariable {
 		if !variable.LastAssignUsed {
@@ -0,0 +1,13 @@
+package build
+
+// BlockStart handles the start of anonymous blocks.
+// Variables declared inside the block can only be used until the end of the block.
+func (state *State) BlockStart() error {
+	state.scopes.Push()
+	return nil
+}
+
+// BlockEnd handles the end of anonymous blocks.
+func (state *State) BlockEnd() error {
+	return state.PopScope(false)
+}
@@ -0,0 +1,104 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// BoolLiteral returns the numeric value of a `true` or `false` literal.
+func BoolLiteral(t token.Token) (uint64, bool) {
+	if t.Kind != token.Identifier {
+		return 0, false
+	}
+
+	switch t.Text() {
+	case "true":
+		return 1, true
+
+	case "false":
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// BoolCondition compares a boolean expression against zero
+// and jumps to the label if the expression is false.
+func (state *State) BoolCondition(condition []token.Token, elseLabel string) error {
+	if len(condition) == 0 {
+		return errors.New(errors.InvalidExpression)
+	}
+
+	valueRegister, typ, err := state.EvaluateTokens(condition)
+
+	if err != nil {
+		return err
+	}
+
+	_, isVariable := valueRegister.User().(*Variable)
+
+	if !isVariable {
+		defer valueRegister.Free()
+	}
+
+	if typ == nil {
+		return errors.New(&errors.CantInferType{Expression: fmt.Sprint(condition)})
+	}
+
+	if typ != types.Bool {
+		return errors.New(&errors.InvalidType{Name: typ.String(), Expected: types.Bool.String()})
+	}
+
+	state.assembler.CompareRegisterNumber(valueRegister, 0)
+	state.assembler.JumpIfEqual(elseLabel)
+	return nil
+}
+
+// IfTrueSet sets the register to 1 if the previous compare statement was true
+// and to 0 otherwise.
+// Unsigned operands need to be compared via the below and above conditions.
+func (state *State) IfTrueSet(operator string, register *register.Register, unsigned bool) {
+	if unsigned {
+		switch operator {
+		case ">=":
+			state.assembler.SetRegisterIfAboveOrEqual(register)
+			return
+
+		case ">":
+			state.assembler.SetRegisterIfAbove(register)
+			return
+
+		case "<=":
+			state.assembler.SetRegisterIfBelowOrEqual(register)
+			return
+
+		case "<":
+			state.assembler.SetRegisterIfBelow(register)
+			return
+		}
+	}
+
+	switch operator {
+	case ">=":
+		state.assembler.SetRegisterIfGreaterOrEqual(register)
+
+	case ">":
+		state.assembler.SetRegisterIfGreater(register)
+
+	case "<=":
+		state.assembler.SetRegisterIfLessOrEqual(register)
+
+	case "<":
+		state.assembler.SetRegisterIfLess(register)
+
+	case "==":
+		state.assembler.SetRegisterIfEqual(register)
+
+	case "!=":
+		state.assembler.SetRegisterIfNotEqual(register)
+	}
+}
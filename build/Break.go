@@ -0,0 +1,64 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/token"
+)
+
+// BreakState handles the state of break and continue statements.
+type BreakState struct {
+	stack []BreakLabels
+}
+
+// BreakLabels are the jump targets of the innermost loop.
+type BreakLabels struct {
+	labelBreak    string
+	labelContinue string
+}
+
+// Break handles break statements.
+func (state *State) Break(tokens []token.Token) error {
+	state.Skip(token.Keyword)
+
+	if len(tokens) > 1 {
+		return errors.New(errors.InvalidExpression)
+	}
+
+	if len(state.breakState.stack) == 0 {
+		return errors.New(errors.BreakOutsideLoop)
+	}
+
+	labels := state.breakState.stack[len(state.breakState.stack)-1]
+	state.assembler.Jump(labels.labelBreak)
+	return nil
+}
+
+// Continue handles continue statements.
+func (state *State) Continue(tokens []token.Token) error {
+	state.Skip(token.Keyword)
+
+	if len(tokens) > 1 {
+		return errors.New(errors.InvalidExpression)
+	}
+
+	if len(state.breakState.stack) == 0 {
+		return errors.New(errors.ContinueOutsideLoop)
+	}
+
+	labels := state.breakState.stack[len(state.breakState.stack)-1]
+	state.assembler.Jump(labels.labelContinue)
+	return nil
+}
+
+// pushBreakLabels registers the labels that break and continue jump to.
+func (state *State) pushBreakLabels(labelBreak string, labelContinue string) {
+	state.breakState.stack = append(state.breakState.stack, BreakLabels{
+		labelBreak:    labelBreak,
+		labelContinue: labelContinue,
+	})
+}
+
+// popBreakLabels removes the labels of the innermost loop.
+func (state *State) popBreakLabels() {
+	state.breakState.stack = state.breakState.stack[:len(state.breakState.stack)-1]
+}
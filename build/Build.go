@@ -1,28 +1,58 @@
 package build
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/akyoto/asm"
-	"github.com/akyoto/asm/elf"
+	"github.com/akyoto/asm/syscall"
 	"github.com/akyoto/color"
+	"github.com/akyoto/q/build/assembler/instructions"
+	"github.com/akyoto/q/build/dwarf"
 	"github.com/akyoto/q/build/log"
 )
 
 // Build describes a compiler build.
 type Build struct {
-	MainPackage     *Package
-	Environment     *Environment
-	ExecutablePath  string
-	ExecutableName  string
-	WriteExecutable bool
-	Optimize        bool
-	ShowTimings     bool
-	ShowAssembly    bool
+	MainPackage           *Package
+	Environment           *Environment
+	ExecutablePath        string
+	ExecutableName        string
+	WriteExecutable       bool
+	VerifyOnly            bool
+	Optimize              bool
+	OverflowChecks        bool
+	StackGuard            bool
+	Debug                 bool
+	BuildID               bool
+	PureCallWarnings      bool
+	CounterWriteWarnings  bool
+	PIE                   bool
+	ShowTimings           bool
+	ShowAssembly          bool
+	EmitAssembly          io.Writer
+	CacheDirectory        string
+	IntermediateDirectory string
+	InlineThreshold       int
+	Target                *Target
+	CPU                   *CPU
+	debugInfo             *dwarf.Info
+	relativePointers      []asm.Pointer
+}
+
+// identicalFunction is the first function with a specific machine code
+// and its address in the executable.
+type identicalFunction struct {
+	function *Function
+	start    asm.Address
 }
 
 // New creates a new build.
@@ -51,6 +81,9 @@ func New(directory string) (*Build, error) {
 		ExecutablePath:  filepath.Join(directory, executableName),
 		WriteExecutable: true,
 		Environment:     environment,
+		InlineThreshold: DefaultInlineThreshold,
+		Target:          Linux,
+		CPU:             Baseline,
 	}
 
 	return build, nil
@@ -67,7 +100,7 @@ func (build *Build) Run() error {
 
 	// Scan
 	start = time.Now()
-	err := build.Environment.ImportDirectory(build.MainPackage)
+	err := build.Scan()
 
 	if err != nil {
 		return err
@@ -85,9 +118,19 @@ func (build *Build) Run() error {
 
 	compile = time.Since(start)
 
+	// Verification ends after the machine code has been generated
+	if build.VerifyOnly {
+		return nil
+	}
+
+	// Emit the assembly instead of the executable
+	if build.EmitAssembly != nil {
+		return build.WriteAssembly(build.EmitAssembly)
+	}
+
 	// Write
 	start = time.Now()
-	err = writeToDisk(code, build.ExecutablePath)
+	err = writeToDisk(build.executable(code), build.ExecutablePath)
 
 	if err != nil {
 		return err
@@ -95,6 +138,14 @@ func (build *Build) Run() error {
 
 	write = time.Since(start)
 
+	if build.ShowAssembly {
+		err = build.showSizes(code)
+
+		if err != nil {
+			return err
+		}
+	}
+
 	if build.ShowTimings {
 		key := log.FaintColor.Sprint
 		log.Info.Printf(key("%-17s")+" %10v μs\n", "Scan files:", scan.Microseconds())
@@ -107,6 +158,129 @@ func (build *Build) Run() error {
 	return nil
 }
 
+// showSizes shows the number of bytes of the machine code, the data and the executable file.
+func (build *Build) showSizes(code *asm.Assembler) error {
+	stat, err := os.Stat(build.ExecutablePath)
+
+	if err != nil {
+		return err
+	}
+
+	key := log.FaintColor.Sprint
+	log.Info.Println(strings.Repeat("=", 80))
+	log.Info.Printf(key("%-17s")+" %10d bytes\n", "Code:", len(code.Code()))
+	log.Info.Printf(key("%-17s")+" %10d bytes\n", "Data:", len(code.Data()))
+	log.Info.Printf(key("%-17s")+" %10d bytes\n", "Executable:", stat.Size())
+	return nil
+}
+
+// RunExecutable starts the produced executable and waits for it to finish.
+// The standard streams are passed through to the executable.
+// A non-zero exit code is reported as an *exec.ExitError.
+func (build *Build) RunExecutable() error {
+	if !build.WriteExecutable || build.VerifyOnly || build.EmitAssembly != nil {
+		return nil
+	}
+
+	cmd := exec.Command(build.ExecutablePath)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Scan checks the build options and parses the files of the main package and its imports.
+func (build *Build) Scan() error {
+	// Debug information is stored in DWARF sections of ELF files
+	if build.Debug && build.Target != Linux {
+		return fmt.Errorf("Debug information is not supported for target '%s'", build.Target.Name)
+	}
+
+	// The stack limit is accessed via the fs register which can only be set on Linux
+	if build.StackGuard && build.Target != Linux {
+		return fmt.Errorf("Stack guards are not supported for target '%s'", build.Target.Name)
+	}
+
+	// The build ID is stored in a section of ELF files
+	if build.BuildID && build.Target != Linux {
+		return fmt.Errorf("Build IDs are not supported for target '%s'", build.Target.Name)
+	}
+
+	// Position-independent executables are only implemented for ELF files
+	if build.PIE && build.Target != Linux {
+		return fmt.Errorf("Position-independent executables are not supported for target '%s'", build.Target.Name)
+	}
+
+	// The addresses in the debug information would need to be relative to the load address
+	if build.PIE && build.Debug {
+		return errors.New("Position-independent executables don't support debug information")
+	}
+
+	// The named sections are only implemented for executables at a fixed address
+	if build.PIE && build.BuildID {
+		return errors.New("Position-independent executables don't support build IDs")
+	}
+
+	build.Environment.Target = build.Target
+	build.Environment.CPU = build.CPU
+	build.Environment.OverflowChecks = build.OverflowChecks
+	build.Environment.StackGuard = build.StackGuard
+	build.Environment.Debug = build.Debug
+	build.Environment.PureCallWarnings = build.PureCallWarnings
+	build.Environment.CounterWriteWarnings = build.CounterWriteWarnings
+	build.Environment.InlineThreshold = build.InlineThreshold
+
+	if build.CacheDirectory != "" {
+		build.Environment.Cache = NewCache(build.CacheDirectory)
+	}
+
+	return build.Environment.ImportDirectory(build.MainPackage)
+}
+
+// Bytes parses the input files and returns the executable binary
+// without writing it to disk. The cache and the intermediate files
+// are still written if their directories have been set.
+func (build *Build) Bytes() ([]byte, error) {
+	err := build.Scan()
+
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := build.Compile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if code == nil {
+		return nil, errors.New("Bytes requires WriteExecutable to be enabled")
+	}
+
+	buffer := bytes.Buffer{}
+	_, err = build.executable(code).WriteTo(&buffer)
+	return buffer.Bytes(), err
+}
+
+// executable creates the binary file format for the machine code.
+func (build *Build) executable(code *asm.Assembler) Executable {
+	if build.Debug || build.BuildID {
+		var extra []dwarf.Section
+
+		if build.BuildID {
+			extra = buildInfoSections(code)
+		}
+
+		return elfExecutable{dwarf.NewELF(code, build.debugInfo, build.relativePointers, extra)}
+	}
+
+	if build.PIE {
+		return newPositionIndependentELF(code, build.relativePointers)
+	}
+
+	return build.Target.Executable(code, build.relativePointers)
+}
+
 // Compile compiles all the functions in the environment.
 func (build *Build) Compile() (*asm.Assembler, error) {
 	mainFunction := "main"
@@ -118,49 +292,278 @@ func (build *Build) Compile() (*asm.Assembler, error) {
 
 	build.Environment.Compile(build.Optimize, build.ShowAssembly)
 
+	if !build.WriteExecutable {
+		return nil, nil
+	}
+
+	functions, err := build.finalFunctions()
+
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate machine code
 	finalCode := asm.New()
+
+	argumentsOffset := uint64(0)
+
+	if build.StackGuard || usesStackStart(functions) {
+		build.addThreadBlock(finalCode)
+		argumentsOffset = threadBlockSize
+	}
+
+	if len(build.Environment.Functions[mainFunction].Parameters) > 0 {
+		build.addArguments(finalCode, argumentsOffset)
+	}
+
 	finalCode.Call(mainFunction)
-	finalCode.Exit(0)
+	finalCode.MoveRegisterNumber(syscall.Registers[0], build.Target.SyscallExit)
+	finalCode.MoveRegisterNumber(syscall.Registers[1], 0)
+	finalCode.Syscall()
 
-	if !build.WriteExecutable {
-		return nil, nil
+	if build.OverflowChecks {
+		build.addOverflowHandler(finalCode)
 	}
 
-	for _, function := range build.Environment.Functions {
-		if function.Error != nil {
-			return nil, function.Error
-		}
+	if build.StackGuard {
+		build.addStackGuardHandler(finalCode)
+	}
 
-		if function.File.Error != nil {
-			return nil, function.File.Error
+	if build.Debug {
+		build.debugInfo = &dwarf.Info{
+			Producer:  "q",
+			Name:      build.ExecutableName,
+			Directory: build.MainPackage.Path,
+			Functions: []dwarf.Function{{Name: "_start", End: uint64(finalCode.Position())}},
 		}
+	}
 
-		if function.CallCount == 0 {
-			continue
+	var intermediates []intermediate
+	identical := map[string]identicalFunction{}
+
+	for _, function := range functions {
+		start := finalCode.Position()
+		functionCode := function.assembler.Finalize()
+
+		// Functions with identical code share a single copy
+		// and the label of the duplicate refers to the original.
+		if build.Optimize && function.Name != mainFunction {
+			fingerprint := function.assembler.Fingerprint()
+			original, exists := identical[fingerprint]
+
+			if exists {
+				finalCode.AddLabelAt(function.Name, original.start)
+
+				if build.ShowAssembly {
+					log.Info.Println(strings.Repeat("=", 80))
+					log.Info.Println(log.CommentColor.Sprintf("%s is identical to %s", function.Name, original.function.Name))
+				}
+
+				continue
+			}
+
+			identical[fingerprint] = identicalFunction{function: function, start: start}
 		}
 
-		if function.Name != mainFunction && function.CanInline() {
-			continue
+		if build.IntermediateDirectory != "" {
+			intermediates = append(intermediates, intermediate{
+				name:   function.Name,
+				offset: uint32(start),
+				code:   append([]byte(nil), functionCode.Code()...),
+			})
 		}
 
 		// Merge function code into the main finalCode
-		finalCode.Merge(function.assembler.Finalize())
+		dataStart := uint32(len(finalCode.Data()))
+		finalCode.Merge(functionCode)
+
+		for _, pointer := range function.assembler.RelativePointers() {
+			build.relativePointers = append(build.relativePointers, asm.Pointer{
+				Address:  dataStart + pointer.Address,
+				Position: uint32(start) + pointer.Position,
+			})
+		}
+
+		if build.Debug {
+			build.addDebugInfo(function, uint64(start), uint64(finalCode.Position()))
+		}
 
 		// Show assembler code of used functions
 		if build.ShowAssembly {
 			log.Info.Println(strings.Repeat("=", 80))
+			log.Info.Println(log.CommentColor.Sprintf("live variables: %d", function.LiveVariables))
 			function.assembler.WriteTo(log.Info)
 		}
 	}
 
-	err := finalCode.Compile()
+	if usesAssert(functions) {
+		build.addAssertHandler(finalCode)
+	}
+
+	err = finalCode.Compile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if build.IntermediateDirectory != "" {
+		err = writeIntermediates(build.IntermediateDirectory, intermediates)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if build.Environment.Cache != nil {
+		err = build.Environment.Cache.Store()
+	}
+
 	return finalCode, err
 }
 
+// WriteAssembly writes the assembly of the whole program in Intel syntax.
+// The output can be assembled with the GNU assembler.
+func (build *Build) WriteAssembly(writer io.Writer) error {
+	functions, err := build.finalFunctions()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprint(writer, ".intel_syntax noprefix\n.text\n.globl _start\n\n_start:\n")
+
+	if err != nil {
+		return err
+	}
+
+	argumentsOffset := uint64(0)
+
+	if build.StackGuard || usesStackStart(functions) {
+		_, err = fmt.Fprint(writer, build.threadBlockAssembly())
+
+		if err != nil {
+			return err
+		}
+
+		argumentsOffset = threadBlockSize
+	}
+
+	if len(build.Environment.Functions["main"].Parameters) > 0 {
+		_, err = fmt.Fprint(writer, build.argumentsAssembly(argumentsOffset))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(writer, "\tcall %s\n\tmov %s, %d\n\tmov %s, 0\n\tsyscall\n", instructions.Symbol("main"), syscall.Registers[0], build.Target.SyscallExit, syscall.Registers[1])
+
+	if err != nil {
+		return err
+	}
+
+	if build.OverflowChecks {
+		_, err = fmt.Fprintf(writer, "\n%s:\n\tmov %s, %d\n\tmov %s, %d\n\tsyscall\n", instructions.Symbol(OverflowLabel), syscall.Registers[0], build.Target.SyscallExit, syscall.Registers[1], OverflowExitCode)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if build.StackGuard {
+		_, err = fmt.Fprintf(writer, "\n%s:\n\tmov %s, %d\n\tmov %s, %d\n\tsyscall\n", instructions.Symbol(StackGuardLabel), syscall.Registers[0], build.Target.SyscallExit, syscall.Registers[1], StackGuardExitCode)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, function := range functions {
+		_, err = fmt.Fprintln(writer)
+
+		if err != nil {
+			return err
+		}
+
+		err = function.assembler.WriteAssembly(writer)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if usesAssert(functions) {
+		_, err = fmt.Fprintf(writer, "\n%s:\n\tmov %s, %d\n\tmov %s, %d\n\tsyscall\n", instructions.Symbol(AssertLabel), syscall.Registers[0], build.Target.SyscallExit, syscall.Registers[1], AssertExitCode)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprint(writer, "\n.data\n")
+
+	if err != nil {
+		return err
+	}
+
+	for _, function := range functions {
+		err = function.assembler.WriteData(writer)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finalFunctions returns the functions that are part of the final code sorted by name.
+// Functions that are never called, unreachable from 'main' or inlined everywhere
+// are excluded unless their address has been taken.
+func (build *Build) finalFunctions() ([]*Function, error) {
+	var (
+		functions []*Function
+		errorList []error
+	)
+
+	// All errors are collected so that they can be reported together.
+	// Functions with multiple names and functions in the same file share their errors.
+	for _, function := range build.Environment.Functions {
+		err := function.Error
+
+		if err == nil {
+			err = function.File.Error
+		}
+
+		if err != nil {
+			errorList = append(errorList, err)
+			continue
+		}
+
+		if function.CallCount == 0 || !build.Environment.reachable[function] {
+			continue
+		}
+
+		if function.Name != "main" && function.AddressTaken == 0 && function.CanInline() {
+			continue
+		}
+
+		functions = append(functions, function)
+	}
+
+	if len(errorList) > 0 {
+		return nil, newErrorList(errorList)
+	}
+
+	sort.Slice(functions, func(a, b int) bool {
+		return functions[a].Name < functions[b].Name
+	})
+
+	return functions, nil
+}
+
 // writeToDisk writes the executable file to disk.
-func writeToDisk(main *asm.Assembler, filePath string) error {
-	binary := elf.New(main)
+func writeToDisk(binary Executable, filePath string) error {
 	err := binary.WriteToFile(filePath)
 
 	if err != nil {
@@ -169,3 +572,21 @@ func writeToDisk(main *asm.Assembler, filePath string) error {
 
 	return os.Chmod(filePath, 0755)
 }
+
+// addDebugInfo adds the address range and the source lines of a function to the debug information.
+func (build *Build) addDebugInfo(function *Function, start uint64, end uint64) {
+	build.debugInfo.Functions = append(build.debugInfo.Functions, dwarf.Function{
+		Name:  function.Name,
+		Start: start,
+		End:   end,
+	})
+
+	for _, line := range function.assembler.Lines() {
+		build.debugInfo.Lines = append(build.debugInfo.Lines, dwarf.Line{
+			Address: start + uint64(line.Address),
+			File:    line.File,
+			Line:    line.Line,
+			Column:  line.Column,
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package build
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"runtime/debug"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/elf"
+	"github.com/akyoto/q/build/dwarf"
+)
+
+const (
+	// noteTypeBuildID is the type of the GNU note that contains the build ID.
+	noteTypeBuildID = 3
+
+	// noteAlign is the alignment of notes and their fields.
+	noteAlign = 4
+)
+
+// CompilerVersion returns the module version of the compiler.
+// Compilers built from a local checkout report "(devel)".
+func CompilerVersion() string {
+	info, ok := debug.ReadBuildInfo()
+
+	if !ok || info.Main.Version == "" {
+		return "(devel)"
+	}
+
+	return info.Main.Version
+}
+
+// buildInfoSections returns the '.comment' section naming the compiler
+// and the '.note.gnu.build-id' section which contains a hash of the code and data.
+// The hash only depends on the program, therefore identical builds have the same ID.
+func buildInfoSections(code *asm.Assembler) []dwarf.Section {
+	hash := sha1.New()
+	hash.Write(code.Code())
+	hash.Write(code.Data())
+
+	return []dwarf.Section{
+		{
+			Name:  ".comment",
+			Type:  elf.SectionTypePROGBITS,
+			Align: 1,
+			Data:  append([]byte("q "+CompilerVersion()), 0),
+		},
+		{
+			Name:  ".note.gnu.build-id",
+			Type:  elf.SectionTypeNOTE,
+			Align: noteAlign,
+			Data:  note("GNU", noteTypeBuildID, hash.Sum(nil)),
+		},
+	}
+}
+
+// note encodes an ELF note with the null-terminated name and the description.
+// The name and the description are padded to a multiple of 4 bytes.
+//
+//nolint:errcheck
+func note(name string, typ uint32, description []byte) []byte {
+	buffer := bytes.Buffer{}
+	binary.Write(&buffer, binary.LittleEndian, uint32(len(name)+1))
+	binary.Write(&buffer, binary.LittleEndian, uint32(len(description)))
+	binary.Write(&buffer, binary.LittleEndian, typ)
+	buffer.WriteString(name)
+	buffer.WriteByte(0)
+	buffer.Write(make([]byte, (noteAlign-buffer.Len()%noteAlign)%noteAlign))
+	buffer.Write(description)
+	buffer.Write(make([]byte, (noteAlign-buffer.Len()%noteAlign)%noteAlign))
+	return buffer.Bytes()
+}
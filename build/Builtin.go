@@ -5,7 +5,19 @@ import "github.com/akyoto/q/build/types"
 const (
 	BuiltinSyscall = "syscall"
 	BuiltinPrint   = "print"
+	BuiltinWrite   = "write"
+	BuiltinHex     = "printHex"
 	BuiltinStore   = "store"
+	BuiltinLoad    = "load"
+	BuiltinLen     = "len"
+	BuiltinMin     = "min"
+	BuiltinMax     = "max"
+	BuiltinCPUID   = "cpuid"
+	BuiltinSizeOf  = "sizeof"
+	BuiltinAssert  = "assert"
+	BuiltinRead    = "read"
+	BuiltinExit    = "exit"
+	BuiltinEnv     = "env"
 )
 
 // BuiltinFunctions defines the builtin functions.
@@ -15,10 +27,108 @@ var BuiltinFunctions = map[string]*Function{
 		Parameters: []*Parameter{
 			{Name: "text", Type: types.Text},
 		},
+		ReturnTypes:      nil,
+		NoParameterCheck: true,
+		IsBuiltin:        true,
+		SideEffects:      1,
+	},
+	BuiltinWrite: {
+		Name: BuiltinWrite,
+		Parameters: []*Parameter{
+			{Name: "text", Type: types.Text},
+		},
+		ReturnTypes:      nil,
+		NoParameterCheck: true,
+		IsBuiltin:        true,
+		SideEffects:      1,
+	},
+	BuiltinHex: {
+		Name: BuiltinHex,
+		Parameters: []*Parameter{
+			{Name: "number", Type: types.Int},
+		},
+		ReturnTypes: nil,
+		IsBuiltin:   true,
+		SideEffects: 1,
+	},
+	BuiltinLen: {
+		Name: BuiltinLen,
+		Parameters: []*Parameter{
+			{Name: "text", Type: types.Text},
+		},
+		ReturnTypes: []*types.Type{types.Int},
+		IsBuiltin:   true,
+	},
+	BuiltinMin: {
+		Name: BuiltinMin,
+		Parameters: []*Parameter{
+			{Name: "a", Type: types.Int},
+			{Name: "b", Type: types.Int},
+		},
+		ReturnTypes: []*types.Type{types.Int},
+		IsBuiltin:   true,
+	},
+	BuiltinMax: {
+		Name: BuiltinMax,
+		Parameters: []*Parameter{
+			{Name: "a", Type: types.Int},
+			{Name: "b", Type: types.Int},
+		},
+		ReturnTypes: []*types.Type{types.Int},
+		IsBuiltin:   true,
+	},
+	BuiltinCPUID: {
+		Name: BuiltinCPUID,
+		Parameters: []*Parameter{
+			{Name: "leaf", Type: types.Int},
+		},
+		ReturnTypes: []*types.Type{types.Int},
+		IsBuiltin:   true,
+	},
+	BuiltinAssert: {
+		Name: BuiltinAssert,
+		Parameters: []*Parameter{
+			{Name: "condition", Type: types.Bool},
+		},
+		ReturnTypes: nil,
+		IsBuiltin:   true,
+		SideEffects: 1,
+	},
+	BuiltinExit: {
+		Name: BuiltinExit,
+		Parameters: []*Parameter{
+			{Name: "code", Type: types.Int},
+		},
 		ReturnTypes: nil,
 		IsBuiltin:   true,
 		SideEffects: 1,
 	},
+	BuiltinEnv: {
+		Name: BuiltinEnv,
+		Parameters: []*Parameter{
+			{Name: "name", Type: types.Text},
+		},
+		ReturnTypes: []*types.Type{types.Text},
+		IsBuiltin:   true,
+	},
+	BuiltinRead: {
+		Name: BuiltinRead,
+		Parameters: []*Parameter{
+			{Name: "buffer", Type: types.Pointer},
+			{Name: "length", Type: types.Int},
+		},
+		ReturnTypes: []*types.Type{types.Int},
+		IsBuiltin:   true,
+		SideEffects: 1,
+	},
+	BuiltinSizeOf: {
+		Name: BuiltinSizeOf,
+		Parameters: []*Parameter{
+			{Name: "type"},
+		},
+		ReturnTypes: []*types.Type{types.Int},
+		IsBuiltin:   true,
+	},
 	BuiltinStore: {
 		Name: BuiltinStore,
 		Parameters: []*Parameter{
@@ -31,6 +141,16 @@ var BuiltinFunctions = map[string]*Function{
 		IsBuiltin:   true,
 		SideEffects: 1,
 	},
+	BuiltinLoad: {
+		Name: BuiltinLoad,
+		Parameters: []*Parameter{
+			{Name: "ptr", Type: types.Pointer},
+			{Name: "offset", Type: types.Int},
+			{Name: "byteCount", Type: types.Int},
+		},
+		ReturnTypes: []*types.Type{types.Int},
+		IsBuiltin:   true,
+	},
 	BuiltinSyscall: {
 		Name: BuiltinSyscall,
 		Parameters: []*Parameter{
@@ -0,0 +1,22 @@
+package build
+
+// CPU describes the instruction set extensions the generated code can use.
+// Executables built for a newer CPU don't run on older ones.
+type CPU struct {
+	Name string
+	BMI2 bool
+}
+
+var (
+	// Baseline only uses instructions that every x86-64 CPU supports, including conditional moves.
+	Baseline = &CPU{Name: "baseline"}
+
+	// Haswell adds the BMI2 extension which can shift by any register.
+	Haswell = &CPU{Name: "haswell", BMI2: true}
+)
+
+// CPUs defines the supported CPUs by name.
+var CPUs = map[string]*CPU{
+	Baseline.Name: Baseline,
+	Haswell.Name:  Haswell,
+}
@@ -0,0 +1,77 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// CPUID executes the CPUID instruction for the given leaf with the sub-leaf 0
+// and stores the resulting ECX register in the expression register.
+// ECX contains the feature flags of leaf 1 and a part of the vendor string in leaf 0.
+func (state *State) CPUID(expr *expression.Expression, function *Function) error {
+	leaf := state.FindFreeRegister()
+
+	if leaf == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	parameter := expr.Children[0]
+	leaf.ForceUse(parameter)
+	defer leaf.Free()
+
+	typ, err := state.ExpressionToRegister(parameter, leaf)
+
+	if err != nil {
+		return err
+	}
+
+	if parameter.IsLeaf() {
+		typ = literalType([]token.Token{parameter.Token}, typ, function.Parameters[0].Type)
+	}
+
+	if typ != function.Parameters[0].Type {
+		return errors.New(&errors.InvalidType{
+			Name:          typ.String(),
+			Expected:      function.Parameters[0].Type.String(),
+			ParameterName: function.Parameters[0].Name,
+		})
+	}
+
+	expr.Type = types.Int
+
+	if expr.Register == nil {
+		return nil
+	}
+
+	// CPUID overwrites these registers
+	eax := state.registers.All.ByName("rax")
+	ebx := state.registers.All.ByName("rbx")
+	ecx := state.registers.All.ByName("rcx")
+	edx := state.registers.All.ByName("rdx")
+	var saved []*register.Register
+
+	for _, reg := range []*register.Register{eax, ebx, ecx, edx} {
+		state.assembler.UseRegisterID(reg.ID)
+
+		if reg.IsFree() || reg.IsEmpty() || reg == expr.Register {
+			continue
+		}
+
+		saved = append(saved, reg)
+		state.assembler.PushRegister(reg)
+	}
+
+	state.assembler.MoveRegisterRegister(eax, leaf)
+	state.assembler.MoveRegisterNumber(ecx, 0)
+	state.assembler.CPUID()
+	state.assembler.MoveRegisterRegister(expr.Register, ecx)
+
+	for i := len(saved) - 1; i >= 0; i-- {
+		state.assembler.PopRegister(saved[i])
+	}
+
+	return nil
+}
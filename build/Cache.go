@@ -0,0 +1,327 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/akyoto/q/build/assembler"
+	"github.com/akyoto/q/build/token"
+)
+
+// cacheVersion needs to be increased whenever the format of the cache entries changes.
+// Changes of the compiler output are covered by the compiler ID.
+const cacheVersion = 24
+
+var (
+	compilerID     string
+	compilerIDOnce sync.Once
+)
+
+// Cache stores compiled functions on disk so that unchanged functions
+// don't need to be compiled again in the next build.
+// A function is unchanged if the files of all functions it might call,
+// the types, the compiler flags and the compiler build are the same.
+type Cache struct {
+	Directory string
+	Compiler  string
+	Hits      int32
+	keys      map[*Function]string
+	loaded    map[*Function]bool
+	mutex     sync.Mutex
+}
+
+// cacheEntry is the serialized form of a compiled function.
+type cacheEntry struct {
+	Assembler     *assembler.Snapshot
+	SideEffects   int32
+	LiveVariables int
+	Calls         []string
+	References    []string
+}
+
+// NewCache creates a cache in the given directory.
+func NewCache(directory string) *Cache {
+	return &Cache{
+		Directory: directory,
+		Compiler:  CompilerID(),
+		loaded:    map[*Function]bool{},
+	}
+}
+
+// CompilerID identifies the build of the running compiler.
+// Builds from a clean checkout are identified by their VCS revision,
+// all other builds by a hash of the compiler executable.
+func CompilerID() string {
+	compilerIDOnce.Do(func() {
+		compilerID = readCompilerID()
+	})
+
+	return compilerID
+}
+
+// readCompilerID reads the build information of the running compiler.
+// The module version is used if the executable can't be read.
+func readCompilerID() string {
+	info, ok := debug.ReadBuildInfo()
+
+	if ok {
+		revision := ""
+		modified := false
+
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+
+		if revision != "" && !modified {
+			return revision
+		}
+	}
+
+	executable, err := os.Executable()
+
+	if err != nil {
+		return CompilerVersion()
+	}
+
+	data, err := os.ReadFile(executable)
+
+	if err != nil {
+		return CompilerVersion()
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// Prepare calculates the cache keys of the given functions.
+// It needs to be called before any function is compiled.
+func (cache *Cache) Prepare(env *Environment, functions map[*Function]bool, flags string) {
+	byName := env.functionsByName()
+	fileHashes := map[*File][]byte{}
+	typesHash := typesHash(env)
+	cache.keys = make(map[*Function]string, len(functions))
+
+	for function := range functions {
+		h := sha256.New()
+		fmt.Fprintf(h, "%d %s %s %x %s\n", cacheVersion, cache.Compiler, flags, typesHash, function.Name)
+
+		for _, dependency := range dependencies(function, byName) {
+			fileHash, exists := fileHashes[dependency.File]
+
+			if !exists {
+				fileHash = tokensHash(dependency.File.path, dependency.File.tokens)
+				fileHashes[dependency.File] = fileHash
+			}
+
+			fmt.Fprintf(h, "%s %x\n", dependency.Name, fileHash)
+		}
+
+		cache.keys[function] = hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// Load restores a function from the cache.
+// It returns false if the function needs to be compiled.
+func (cache *Cache) Load(function *Function) bool {
+	key, exists := cache.keys[function]
+
+	if !exists {
+		return false
+	}
+
+	file, err := os.Open(filepath.Join(cache.Directory, key))
+
+	if err != nil {
+		return false
+	}
+
+	defer file.Close()
+	entry := cacheEntry{}
+	err = gob.NewDecoder(file).Decode(&entry)
+
+	if err != nil || entry.Assembler == nil {
+		return false
+	}
+
+	functionAssembler, err := assembler.FromSnapshot(entry.Assembler)
+
+	if err != nil {
+		return false
+	}
+
+	for _, name := range entry.Calls {
+		callee := function.File.environment.Functions[name]
+
+		if callee != nil {
+			atomic.AddInt32(&callee.CallCount, 1)
+		}
+	}
+
+	// Entries are only stored after successful builds,
+	// therefore the imports of an unchanged file have been used.
+	for _, imp := range function.File.imports {
+		atomic.AddInt32(&imp.Used, 1)
+	}
+
+	function.assembler = functionAssembler
+	atomic.StoreInt32(&function.SideEffects, entry.SideEffects)
+	function.LiveVariables = entry.LiveVariables
+	atomic.AddInt32(&cache.Hits, 1)
+
+	cache.mutex.Lock()
+	cache.loaded[function] = true
+	cache.mutex.Unlock()
+	return true
+}
+
+// Store saves the compiled functions that haven't been loaded from the cache.
+func (cache *Cache) Store() error {
+	err := os.MkdirAll(cache.Directory, 0755)
+
+	if err != nil {
+		return err
+	}
+
+	for function, key := range cache.keys {
+		if cache.loaded[function] || function.assembler == nil || function.Error != nil {
+			continue
+		}
+
+		snapshot, err := function.assembler.Snapshot()
+
+		if err != nil {
+			return err
+		}
+
+		entry := cacheEntry{
+			Assembler:     snapshot,
+			SideEffects:   atomic.LoadInt32(&function.SideEffects),
+			LiveVariables: function.LiveVariables,
+		}
+
+		for _, callee := range function.calls {
+			entry.Calls = append(entry.Calls, callee.Name)
+		}
+
+		for _, referenced := range function.references {
+			entry.References = append(entry.References, referenced.Name)
+		}
+
+		err = cache.write(key, &entry)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write saves the entry under the given key.
+// The entry is renamed after writing so that concurrent builds never see partial files.
+func (cache *Cache) write(key string, entry *cacheEntry) error {
+	file, err := os.CreateTemp(cache.Directory, key+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(file).Encode(entry)
+	closeErr := file.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), filepath.Join(cache.Directory, key))
+}
+
+// dependencies returns the function and all functions it might call, directly or indirectly.
+// The result is sorted by name so that it can be used for hashing.
+func dependencies(function *Function, byName map[string][]*Function) []*Function {
+	visited := map[*Function]bool{function: true}
+	queue := []*Function{function}
+	result := []*Function{}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		result = append(result, current)
+
+		for _, callee := range current.callees(byName) {
+			if visited[callee] {
+				continue
+			}
+
+			visited[callee] = true
+			queue = append(queue, callee)
+		}
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].Name < result[b].Name
+	})
+
+	return result
+}
+
+// tokensHash returns the hash of the file path and the contents of all tokens.
+func tokensHash(path string, tokens []token.Token) []byte {
+	h := sha256.New()
+	fmt.Fprintln(h, path)
+
+	for _, t := range tokens {
+		writeHashed(h, byte(t.Kind), t.Bytes)
+	}
+
+	return h.Sum(nil)
+}
+
+// typesHash returns the hash of the memory layout of all types.
+func typesHash(env *Environment) []byte {
+	names := make([]string, 0, len(env.Types))
+
+	for name := range env.Types {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	h := sha256.New()
+
+	for _, name := range names {
+		typ := env.Types[name]
+		fmt.Fprintf(h, "%s %s %d %t\n", name, typ.Name, typ.Size, typ.Unsigned)
+
+		for _, field := range typ.Fields {
+			fmt.Fprintf(h, "\t%s %s %d %t\n", field.Name, field.Type, field.Offset, field.Mutable)
+		}
+	}
+
+	return h.Sum(nil)
+}
+
+// writeHashed writes a kind and a length-prefixed byte slice to the hash.
+func writeHashed(h hash.Hash, kind byte, data []byte) {
+	fmt.Fprintf(h, "%d:%d:", kind, len(data))
+	_, _ = h.Write(data)
+}
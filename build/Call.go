@@ -2,14 +2,14 @@ package build
 
 import (
 	"fmt"
-	"strconv"
 	"sync/atomic"
 
-	"github.com/akyoto/asm/syscall"
 	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/log"
 	"github.com/akyoto/q/build/register"
 	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
 )
 
 // Call handles function calls.
@@ -26,8 +26,47 @@ func (state *State) Call(tokens []token.Token) error {
 		return errors.New(&errors.MissingCharacter{Character: ")"})
 	}
 
-	_, err := state.TokensToRegister(tokens, nil)
-	return err
+	// Assertions are compiled like conditions, user-defined functions take precedence
+	if firstToken.Text() == BuiltinAssert && tokens[1].Kind == token.GroupStart && state.environment.Functions[BuiltinAssert] == nil {
+		return state.Assert(tokens)
+	}
+
+	expr, err := expression.FromTokens(tokens)
+
+	if err != nil {
+		return err
+	}
+
+	defer expr.Close()
+	_, err = state.ExpressionToRegister(expr, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if state.environment.PureCallWarnings {
+		state.WarnUnusedReturnValue(expr)
+	}
+
+	return nil
+}
+
+// WarnUnusedReturnValue warns about a call in statement position
+// if the function has no side effects because the call has no effect at all.
+// Recursive calls are ignored because the side effects of the function are not known yet.
+func (state *State) WarnUnusedReturnValue(expr *expression.Expression) {
+	if !expr.IsFunctionCall {
+		return
+	}
+
+	function := state.environment.Functions[PolymorphName(expr.Token.Text(), len(expr.Children))]
+
+	if function == nil || !function.HasReturnValue() || state.isRecursiveCall(function) || atomic.LoadInt32(&function.SideEffects) > 0 {
+		return
+	}
+
+	warning := state.function.NewError(state.tokenCursor, &errors.UnusedReturnValue{FunctionName: function.Name})
+	log.Error.Println(warning)
 }
 
 // CallExpression executes a function call.
@@ -42,6 +81,27 @@ func (state *State) CallExpression(expr *expression.Expression) error {
 		function, isBuiltin = BuiltinFunctions[functionName]
 	}
 
+	if function == nil {
+		variable := state.scopes.Get(functionName)
+
+		if variable != nil {
+			if variable.Type != types.Function {
+				return errors.New(&errors.InvalidType{Name: variable.Type.String(), Expected: types.Function.String()})
+			}
+
+			function = IndirectFunction(variable, len(parameters))
+		}
+	}
+
+	// Calls with a type name and a parameter are casts
+	if function == nil && len(parameters) > 0 {
+		typ := state.function.File.Type(expr.Token.Text())
+
+		if typ != nil {
+			return state.Cast(expr, typ)
+		}
+	}
+
 	if function == nil {
 		typ := state.function.File.Type(functionName)
 
@@ -76,16 +136,46 @@ func (state *State) CallExpression(expr *expression.Expression) error {
 	}
 
 	if isBuiltin {
+		// Calling a builtin with side effects causes our function to have side effects
+		if function.SideEffects > 0 {
+			atomic.AddInt32(&state.function.SideEffects, 1)
+		}
+
 		switch functionName {
 		case BuiltinPrint:
-			parameter := parameters[0]
+			return state.Print(function, parameters, true)
 
-			if parameter.Token.Kind != token.Text {
-				return fmt.Errorf("'%s' requires a text parameter instead of '%s'", function.Name, parameter.Token.Text())
-			}
+		case BuiltinWrite:
+			return state.Print(function, parameters, false)
 
-			state.printLn(parameter.Token.Text())
-			return nil
+		case BuiltinHex:
+			return state.PrintHex(parameters[0])
+
+		case BuiltinLen:
+			return state.Len(expr)
+
+		case BuiltinMin, BuiltinMax:
+			return state.MinMax(expr, function)
+
+		case BuiltinCPUID:
+			return state.CPUID(expr, function)
+
+		case BuiltinAssert:
+			return errors.New(errors.AssertInExpression)
+
+		case BuiltinRead:
+			parameters = state.ReadParameters(expr)
+			function = readSyscall
+			functionName = BuiltinSyscall
+
+		case BuiltinExit:
+			return state.Exit(expr, function)
+
+		case BuiltinEnv:
+			return state.Env(expr, function)
+
+		case BuiltinLoad:
+			return state.Load(expr, nil)
 
 		case BuiltinStore:
 			variableName := parameters[0].Token.Text()
@@ -94,9 +184,9 @@ func (state *State) CallExpression(expr *expression.Expression) error {
 			valueString := parameters[3].Token.Text()
 
 			variable := state.scopes.Get(variableName)
-			offset, _ := strconv.Atoi(offsetString)
-			byteCount, _ := strconv.Atoi(byteCountString)
-			value, _ := strconv.Atoi(valueString)
+			offset, _ := token.ParseInt(offsetString)
+			byteCount, _ := token.ParseInt(byteCountString)
+			value, _ := token.ParseInt(valueString)
 
 			state.UseVariable(variable)
 			state.assembler.StoreNumber(variable.Register(), byte(offset), byte(byteCount), uint64(value))
@@ -104,8 +194,20 @@ func (state *State) CallExpression(expr *expression.Expression) error {
 		}
 	}
 
+	// The register receiving the return value doesn't hold
+	// any value that needs to be preserved during the call.
+	resultUser := fmt.Stringer(nil)
+
+	if expr.Register != nil {
+		resultUser = expr.Register.User()
+	}
+
+	if function.IsIndirect {
+		defer state.KeepFunctionPointer(expr)()
+	}
+
 	// Call the function
-	pushRegisters, callRegisters, err := state.BeforeCall(function, parameters)
+	pushRegisters, callRegisters, err := state.BeforeCall(function, parameters, expr.Register)
 
 	if err != nil {
 		return err
@@ -113,6 +215,10 @@ func (state *State) CallExpression(expr *expression.Expression) error {
 
 	if functionName == BuiltinSyscall {
 		state.assembler.Syscall()
+	} else if function.IsIndirect {
+		variable := state.scopes.Get(functionName)
+		state.UseVariable(variable)
+		state.assembler.CallRegister(variable.Register())
 	} else {
 		if function.CanInline() {
 			function.InlineInto(state.function)
@@ -126,6 +232,10 @@ func (state *State) CallExpression(expr *expression.Expression) error {
 		callRegister.Free()
 	}
 
+	if resultUser != nil && expr.Register.IsFree() {
+		expr.Register.ForceUse(resultUser)
+	}
+
 	// Save return value in temporary register
 	returnValueRegister := state.registers.ReturnValue[0]
 
@@ -147,22 +257,15 @@ func (state *State) CallExpression(expr *expression.Expression) error {
 }
 
 // BeforeCall pushes parameters into registers.
-func (state *State) BeforeCall(function *Function, parameters []*expression.Expression) (register.List, register.List, error) {
+// The result register is excluded from the registers that need to be saved.
+func (state *State) BeforeCall(function *Function, parameters []*expression.Expression, resultRegister *register.Register) (register.List, register.List, error) {
 	// nolint:prealloc
 	var pushRegisters []*register.Register
-	var usedRegisterIDs []register.ID
-
-	if function == state.function {
-		// Recursive call.
-		// We can't determine the used registers for recursive calls
-		// so we'll assume that every register has been used.
-		// This is obviously bad for performance.
-		// NOTE: We could save a recursive call reference here
-		// and revisit it later after the function has been compiled.
-		for _, reg := range state.registers.All {
-			usedRegisterIDs = append(usedRegisterIDs, reg.ID)
-		}
-	} else {
+
+	if function.IsIndirect {
+		// The called function is unknown and might have side effects
+		atomic.AddInt32(&state.function.SideEffects, 1)
+	} else if !state.isRecursiveCall(function) {
 		// Wait for function compilation to finish
 		function.Wait()
 
@@ -176,15 +279,51 @@ func (state *State) BeforeCall(function *Function, parameters []*expression.Expr
 		if function.Error != nil {
 			return nil, nil, function.Error
 		}
+	}
+
+	usedRegisterIDs := state.modifiedRegisterIDs(function)
+
+	// Determine which registers to use for our parameters
+	var callRegisters register.List
+
+	if function.Name == BuiltinSyscall {
+		callRegisters = state.registers.Syscall
+	} else {
+		callRegisters = state.registers.Call
+	}
+
+	// Variables in call registers that are needed for other parameters
+	// are moved before saving registers because the new register
+	// might not need to be saved at all.
+	for i, parameter := range parameters {
+		callRegister := callRegisters[i]
+
+		if callRegister.IsFree() || callRegister == resultRegister {
+			continue
+		}
 
-		usedRegisterIDs = function.UsedRegisterIDs()
+		variable, isVariable := callRegister.User().(*Variable)
+
+		if !isVariable {
+			continue
+		}
+
+		if parameter.IsLeaf() && parameter.Token.Kind == token.Identifier && state.scopes.Get(parameter.Token.Text()) == variable {
+			continue
+		}
+
+		err := state.moveToPreservedRegister(callRegister, usedRegisterIDs)
+
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Determine the registers we need to save
 	for _, registerID := range usedRegisterIDs {
 		callModifiedRegister := state.registers.ByID(registerID)
 
-		if callModifiedRegister.IsFree() {
+		if callModifiedRegister.IsFree() || callModifiedRegister == resultRegister {
 			continue
 		}
 
@@ -207,13 +346,31 @@ func (state *State) BeforeCall(function *Function, parameters []*expression.Expr
 		state.assembler.PushRegister(reg)
 	}
 
-	// Determine which registers to use for our parameters
-	var callRegisters register.List
+	// Parameters containing calls are evaluated in temporary registers first
+	// because the calls would overwrite the parameters in the call registers.
+	temporaries := make([]*register.Register, len(parameters))
+	temporaryTypes := make([]*types.Type, len(parameters))
 
-	if function.Name == BuiltinSyscall {
-		callRegisters = state.registers.Syscall
-	} else {
-		callRegisters = state.registers.Call
+	for i, parameter := range parameters {
+		if !parameter.ContainsCall() {
+			continue
+		}
+
+		temporary := state.FindFreeRegister()
+
+		if temporary == nil {
+			return nil, nil, errors.New(errors.ExceededMaxVariables)
+		}
+
+		temporary.ForceUse(parameter)
+		typ, err := state.ExpressionToRegister(parameter, temporary)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		temporaries[i] = temporary
+		temporaryTypes[i] = typ
 	}
 
 	// Move parameters into registers
@@ -226,6 +383,11 @@ func (state *State) BeforeCall(function *Function, parameters []*expression.Expr
 			variable := state.scopes.Get(parameter.Token.Text())
 
 			if variable != nil && variable.Register() == callRegister {
+				// The extension keeps the value of the variable intact
+				if !function.NoParameterCheck && widens(variable.Type, function.Parameters[i].Type) {
+					state.Widen(callRegister, variable.Type)
+				}
+
 				state.UseVariable(variable)
 				continue
 			}
@@ -233,8 +395,8 @@ func (state *State) BeforeCall(function *Function, parameters []*expression.Expr
 
 		// If one of the call registers is already in use,
 		// move the current user of the register to another one.
-		if !callRegister.IsFree() {
-			freeRegister := state.registers.General.FindFree()
+		if !callRegister.IsFree() && callRegister != resultRegister {
+			freeRegister := state.FindFreeRegister()
 
 			if freeRegister == nil {
 				return nil, nil, errors.New(errors.ExceededMaxVariables)
@@ -255,19 +417,38 @@ func (state *State) BeforeCall(function *Function, parameters []*expression.Expr
 		_ = callRegister.Use(function.Parameters[i])
 
 		// Save the parameter in the call register
-		typ, err := state.ExpressionToRegister(parameter, callRegister)
+		typ := temporaryTypes[i]
 
-		if err != nil {
-			return nil, nil, err
+		if temporaries[i] != nil {
+			state.assembler.MoveRegisterRegister(callRegister, temporaries[i])
+			temporaries[i].Free()
+		} else {
+			var err error
+			typ, err = state.ExpressionToRegister(parameter, callRegister)
+
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if parameter.IsLeaf() {
+			typ = literalType([]token.Token{parameter.Token}, typ, function.Parameters[i].Type)
 		}
 
-		if !function.NoParameterCheck && typ != function.Parameters[i].Type {
+		if function.NoParameterCheck || typ == function.Parameters[i].Type {
+			continue
+		}
+
+		// Smaller integers are extended, narrowing conversions are not allowed
+		if !widens(typ, function.Parameters[i].Type) {
 			return nil, nil, errors.New(&errors.InvalidType{
 				Name:          typ.String(),
 				Expected:      function.Parameters[i].Type.String(),
 				ParameterName: function.Parameters[i].Name,
 			})
 		}
+
+		state.Widen(callRegister, typ)
 	}
 
 	return pushRegisters, callRegisters, nil
@@ -275,7 +456,19 @@ func (state *State) BeforeCall(function *Function, parameters []*expression.Expr
 
 // AfterCall restores saved registers from the stack.
 func (state *State) AfterCall(function *Function, pushedRegisters []*register.Register, callRegisters []*register.Register) {
-	atomic.AddInt32(&function.CallCount, 1)
+	if !function.IsIndirect {
+		atomic.AddInt32(&function.CallCount, 1)
+		state.function.calls = append(state.function.calls, function)
+	}
+
+	// Registers modified by the callee are modified by our function as well.
+	// The saved registers are skipped because they're restored below.
+	// A function calling itself can't modify more registers than it already does.
+	if function != state.function {
+		for _, registerID := range state.modifiedRegisterIDs(function) {
+			state.assembler.UseRegisterID(registerID)
+		}
+	}
 
 	// Restore saved registers
 	for i := len(pushedRegisters) - 1; i >= 0; i-- {
@@ -283,13 +476,83 @@ func (state *State) AfterCall(function *Function, pushedRegisters []*register.Re
 	}
 }
 
+// moveToPreservedRegister moves the variable in the register to a free register.
+// Registers that are not modified by the call are preferred because they don't need to be saved.
+func (state *State) moveToPreservedRegister(reg *register.Register, modifiedRegisterIDs []register.ID) error {
+	freeRegister := state.FindFreeRegister()
+
+	if freeRegister == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	for _, candidate := range state.registers.General {
+		if candidate.IsFree() && !containsRegisterID(modifiedRegisterIDs, candidate.ID) {
+			freeRegister = candidate
+			break
+		}
+	}
+
+	state.assembler.MoveRegisterRegister(freeRegister, reg)
+	variable := reg.User().(*Variable)
+	_ = variable.SetRegister(freeRegister)
+	return nil
+}
+
+// containsRegisterID tells you whether the list contains the register ID.
+func containsRegisterID(registerIDs []register.ID, id register.ID) bool {
+	for _, registerID := range registerIDs {
+		if registerID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// modifiedRegisterIDs returns the IDs of the registers that might be modified by a call to the function.
+func (state *State) modifiedRegisterIDs(function *Function) []register.ID {
+	if !state.isRecursiveCall(function) && !function.IsIndirect {
+		return function.UsedRegisterIDs()
+	}
+
+	// We can't determine the used registers for recursive and indirect calls
+	// so we'll assume that every register has been used.
+	// This is obviously bad for performance.
+	// NOTE: We could save a recursive call reference here
+	// and revisit it later after the function has been compiled.
+	usedRegisterIDs := make([]register.ID, 0, len(state.registers.All))
+
+	for _, reg := range state.registers.All {
+		usedRegisterIDs = append(usedRegisterIDs, reg.ID)
+	}
+
+	return usedRegisterIDs
+}
+
 // printLn adds instructions to print a message to the console.
 func (state *State) printLn(text string) {
-	text += "\n"
+	state.printText(text + "\n")
+}
+
+// printText adds instructions to print a text to the console without a newline.
+// The registers in use are saved because the syscall modifies them.
+func (state *State) printText(text string) {
+	rcx := state.registers.All.ByName("rcx")
+	r11 := state.registers.All.ByName("r11")
+	saved := register.List{state.registers.Syscall[0], state.registers.Syscall[1], state.registers.Syscall[2], state.registers.Syscall[3], rcx, r11}.InUse()
+
+	for _, reg := range saved {
+		state.assembler.PushRegister(reg)
+	}
+
 	address := state.assembler.AddString(text)
-	state.assembler.MoveRegisterNumber(state.registers.Syscall[0], uint64(syscall.Write))
+	state.assembler.MoveRegisterNumber(state.registers.Syscall[0], state.environment.Target.SyscallWrite)
 	state.assembler.MoveRegisterNumber(state.registers.Syscall[1], 1)
-	state.assembler.MoveRegisterAddress(state.registers.Syscall[2], address)
+	state.assembler.LoadStringAddress(state.registers.Syscall[2], address)
 	state.assembler.MoveRegisterNumber(state.registers.Syscall[3], uint64(len(text)))
 	state.assembler.Syscall()
+
+	for i := len(saved) - 1; i >= 0; i-- {
+		state.assembler.PopRegister(saved[i])
+	}
 }
@@ -0,0 +1,103 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/types"
+)
+
+// Cast converts the value of the parameter to the type and stores it in the expression register.
+// Casts look like function calls with the type name as the function name.
+// Integers are truncated or extended to the size of the type and
+// floating-point numbers are truncated towards zero when they're converted to integers.
+func (state *State) Cast(expr *expression.Expression, typ *types.Type) error {
+	if len(expr.Children) != 1 {
+		return errors.New(&errors.ParameterCount{
+			FunctionName:  typ.Name,
+			CountGiven:    len(expr.Children),
+			CountRequired: 1,
+		})
+	}
+
+	value := expr.Register
+
+	if value == nil {
+		value = state.FindFreeRegister()
+
+		if value == nil {
+			return errors.New(errors.ExceededMaxVariables)
+		}
+
+		value.ForceUse(expr)
+		defer value.Free()
+	}
+
+	// Loads of smaller signed integers can be sign-extended directly
+	if state.isBuiltinLoad(expr.Children[0]) && isInteger(typ) && !typ.Unsigned && typ.Size < 8 {
+		load := expr.Children[0]
+		load.Register = value
+		expr.Type = typ
+		return state.Load(load, typ)
+	}
+
+	from, err := state.ExpressionToRegister(expr.Children[0], value)
+
+	if err != nil {
+		return err
+	}
+
+	if !castable(from, typ) {
+		return errors.New(&errors.InvalidCast{From: from.String(), To: typ.String()})
+	}
+
+	expr.Type = typ
+	xmm0 := state.registers.Float[0]
+
+	switch {
+	case from == typ:
+		return nil
+
+	case from == types.Float64:
+		state.assembler.MoveFloatRegisterRegister(xmm0, value)
+		state.assembler.TruncateFloatToIntRegisterRegister(value, xmm0)
+
+	case typ == types.Float64:
+		if from.Size < 8 && from != types.Bool {
+			state.Widen(value, from)
+		}
+
+		state.assembler.IntToFloatRegisterRegister(xmm0, value)
+		state.assembler.MoveFloatRegisterRegister(value, xmm0)
+		return nil
+
+	// The sign or zero extension of a larger value only keeps the lower bytes
+	case from.Size < typ.Size:
+		state.Widen(value, from)
+		return nil
+	}
+
+	if typ.Size < 8 {
+		state.Widen(value, typ)
+	}
+
+	return nil
+}
+
+// castable tells you whether a value of the type can be converted to the other type.
+// Booleans can only be converted to numbers.
+func castable(typ *types.Type, other *types.Type) bool {
+	if !isInteger(other) && other != types.Float64 {
+		return false
+	}
+
+	return isInteger(typ) || typ == types.Float64 || typ == types.Bool
+}
+
+// isBuiltinLoad tells you whether the expression is a call to the builtin load function.
+func (state *State) isBuiltinLoad(expr *expression.Expression) bool {
+	if !expr.IsFunctionCall || expr.Token.Text() != BuiltinLoad || len(expr.Children) != 3 {
+		return false
+	}
+
+	return state.environment.Functions[PolymorphName(BuiltinLoad, 3)] == nil
+}
@@ -20,11 +20,25 @@ func Compile(function *Function, environment *Environment, optimize bool, verbos
 		function.Finished.L.Unlock()
 	}()
 
+	// Functions with an invalid signature can't be compiled
+	if function.Error != nil {
+		return
+	}
+
+	// Unchanged functions are loaded from the cache
+	if environment.Cache != nil && environment.Cache.Load(function) {
+		return
+	}
+
 	scopes := &ScopeStack{}
 	scopes.Push()
 
 	registers := register.NewManager()
-	tokens := function.Tokens()
+
+	// The tokens are copied because references to constants
+	// will be replaced by their values during compilation.
+	tokens := make([]token.Token, len(function.Tokens()))
+	copy(tokens, function.Tokens())
 	identifierLifeTime := IdentifierLifeTimeMap(tokens)
 
 	// Parameters
@@ -44,7 +58,11 @@ func Compile(function *Function, environment *Environment, optimize bool, verbos
 	}
 
 	// Assembler
+	arrays := allocatesArrays(tokens)
 	assembler := assembler.New(verbose)
+	// Tail calls would skip the release of the stack memory used by arrays
+	assembler.TailCalls = optimize && !arrays
+	assembler.TestZero = optimize
 	assembler.AddLabel(function.Name)
 	function.assembler = assembler
 
@@ -59,24 +77,17 @@ func Compile(function *Function, environment *Environment, optimize bool, verbos
 		instructions:       instructions,
 		identifierLifeTime: identifierLifeTime,
 		ignoreContracts:    false,
+		checkOverflow:      environment.OverflowChecks,
+		stackGuard:         environment.StackGuard,
+		debug:              environment.Debug,
 	}
 
+	state.returnState.epilogue = usesEpilogue(instructions, arrays)
+
 	if optimize {
 		state.ignoreContracts = true
-	}
-
-	// Return types
-	if len(function.ReturnTypeTokens) > 0 {
-		typeName := TypeNameFromTokens(function.ReturnTypeTokens)
-		typ := function.File.Type(typeName)
-
-		if typ == nil {
-			err = errors.New(state.environment.UnknownTypeError(typeName))
-			function.Error = NewError(err, function.File.path, function.File.tokens[:function.returnTypeStart+1], function)
-			return
-		}
-
-		function.ReturnTypes = append(function.ReturnTypes, typ)
+		state.foldConstants = true
+		state.reduceStrength = true
 	}
 
 	// Compile the function
@@ -87,7 +98,23 @@ func Compile(function *Function, environment *Environment, optimize bool, verbos
 		return
 	}
 
+	// Returns jump to the shared epilogue
+	if state.returnState.epilogue {
+		assembler.AddLabel("return")
+	}
+
+	// Reaching the end of the function runs the deferred calls
+	if !assembler.IsUnreachable() {
+		err = state.RunDeferred()
+
+		if err != nil {
+			function.Error = function.NewError(state.tokenCursor, err)
+			return
+		}
+	}
+
 	// Check for mistakes in variable usage
+	state.WarnUnusedParameters()
 	err = state.PopScope(false)
 
 	if err != nil {
@@ -97,11 +124,8 @@ func Compile(function *Function, environment *Environment, optimize bool, verbos
 
 	// Return
 	if state.ensureState.counter > 0 {
-		assembler.AddLabel("return")
-
-		if len(state.function.ReturnTypes) == 0 {
-			function.Error = errors.New(errors.EnsureWithoutFunctionType)
-			return
+		if !state.returnState.epilogue {
+			assembler.AddLabel("return")
 		}
 
 		underscore := &Variable{
@@ -114,10 +138,11 @@ func Compile(function *Function, environment *Environment, optimize bool, verbos
 		state.scopes.Add(underscore)
 
 		for _, ensure := range state.ensureState.list {
+			state.tokenCursor = ensure.position
 			err := state.Condition(ensure.condition, ensure.failLabel)
 
 			if err != nil {
-				function.Error = err
+				function.Error = function.NewError(state.tokenCursor, err)
 				return
 			}
 		}
@@ -131,7 +156,7 @@ func Compile(function *Function, environment *Environment, optimize bool, verbos
 	for _, expect := range state.expectState.list {
 		assembler.AddLabel(expect.failLabel)
 		state.printLn(fmt.Sprintf("%s: expect %v", state.function.Name, expect.condition))
-		state.assembler.MoveRegisterNumber(state.registers.Syscall[0], 60)
+		state.assembler.MoveRegisterNumber(state.registers.Syscall[0], state.environment.Target.SyscallExit)
 		state.assembler.MoveRegisterNumber(state.registers.Syscall[1], 1)
 		state.assembler.Syscall()
 	}
@@ -140,38 +165,53 @@ func Compile(function *Function, environment *Environment, optimize bool, verbos
 	for _, ensure := range state.ensureState.list {
 		assembler.AddLabel(ensure.failLabel)
 		state.printLn(fmt.Sprintf("%s: ensure %v", state.function.Name, ensure.condition))
-		state.assembler.MoveRegisterNumber(state.registers.Syscall[0], 60)
+		state.assembler.MoveRegisterNumber(state.registers.Syscall[0], state.environment.Target.SyscallExit)
 		state.assembler.MoveRegisterNumber(state.registers.Syscall[1], 1)
 		state.assembler.Syscall()
 	}
 
+	// Assertion failures
+	state.AssertFailures()
+
+	// Stack memory for arrays
+	state.ReserveArrays()
+
 	// Optimize assembly code
+	state.assembler.RemoveUnreachable()
 	state.assembler.Optimize()
+
+	if optimize {
+		state.assembler.RemoveRedundantInstructions()
+		state.assembler.ForwardMoves()
+		state.assembler.ZeroWithXor()
+	}
+
+	// Stack limit check for functions that call other functions
+	state.GuardStack()
 }
 
 // declareParameters declares the given parameters as variables inside the scope.
 // It also assigns a register to each variable.
 func declareParameters(function *Function, scopes *ScopeStack, registers *register.Manager, identifierLifeTime map[string]token.Position) error {
-	file := function.File
-
 	for i, parameter := range function.Parameters {
 		if i >= len(registers.Call) {
-			return errors.New(errors.ExceededMaxParameters)
+			return NewError(errors.New(errors.ExceededMaxParameters), function.File.path, function.File.tokens[:parameter.Position+1], function)
 		}
 
 		register := registers.Call[i]
-		typeName := TypeNameFromTokens(parameter.TypeTokens)
-		parameter.Type = file.Type(typeName)
 
-		if parameter.Type == nil {
-			return NewError(errors.New(&errors.UnknownType{Name: typeName}), file.path, file.tokens[:parameter.Position+2], function)
+		// Parameters named '_' are intentionally ignored
+		if parameter.Name == "_" {
+			continue
 		}
 
 		variable := &Variable{
-			Name:       parameter.Name,
-			Type:       parameter.Type,
-			Position:   0,
-			AliveUntil: identifierLifeTime[parameter.Name],
+			Name:        parameter.Name,
+			Type:        parameter.Type,
+			Position:    0,
+			AliveUntil:  identifierLifeTime[parameter.Name],
+			IsParameter: true,
+			IsText:      TypeNameFromTokens(parameter.TypeTokens) == textTypeName,
 		}
 
 		_ = variable.SetRegister(register)
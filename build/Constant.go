@@ -0,0 +1,222 @@
+package build
+
+import (
+	"strconv"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/operators"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// Const handles local constant declarations.
+func (state *State) Const(tokens []token.Token) error {
+	state.Skip(token.Keyword)
+
+	if len(tokens) < 2 || tokens[1].Kind != token.Identifier {
+		return errors.New(errors.ExpectedVariable)
+	}
+
+	if len(tokens) < 3 || tokens[2].Text() != "=" {
+		return errors.New(errors.MissingAssignmentOperator)
+	}
+
+	name := tokens[1].Text()
+	position := state.tokenCursor
+	_, exists := state.function.File.constants[name]
+
+	if exists || state.scopes.Get(name) != nil {
+		return errors.New(&errors.VariableAlreadyExists{Name: name})
+	}
+
+	state.tokenCursor += 2
+	value, err := state.evaluateConstant(tokens[3:])
+
+	if err != nil {
+		return err
+	}
+
+	state.scopes.Add(&Variable{
+		Name:           name,
+		Type:           types.Int,
+		Position:       position,
+		LastAssign:     position,
+		LastAssignUsed: true,
+		IsConstant:     true,
+		Value:          value,
+	})
+
+	state.tokenCursor += len(tokens) - 3
+	return nil
+}
+
+// ResolveConstants replaces all references to constants with their values.
+func (state *State) ResolveConstants(tokens []token.Token) {
+	for i, t := range tokens {
+		if t.Kind != token.Identifier {
+			continue
+		}
+
+		if i+1 < len(tokens) {
+			next := tokens[i+1]
+
+			// Function calls, package access and assignment targets
+			if next.Kind == token.GroupStart || (next.Kind == token.Operator && (next.Text() == "." || operators.All[next.Text()].Kind == operators.Assignment)) {
+				continue
+			}
+		}
+
+		// Struct fields
+		if i > 0 && tokens[i-1].Kind == token.Operator && tokens[i-1].Text() == "." {
+			continue
+		}
+
+		value, isConstant := state.constant(t.Text())
+
+		if !isConstant {
+			continue
+		}
+
+		tokens[i] = token.Token{
+			Kind:     token.Number,
+			Position: t.Position,
+			Bytes:    strconv.AppendInt(nil, value, 10),
+		}
+	}
+}
+
+// constant returns the value of the constant with the given name.
+// Local constants are looked up before the constants of the file.
+func (state *State) constant(name string) (int64, bool) {
+	variable := state.scopes.Get(name)
+
+	if variable != nil {
+		if !variable.IsConstant {
+			return 0, false
+		}
+
+		state.UseVariable(variable)
+		return variable.Value, true
+	}
+
+	value, exists := state.function.File.constants[name]
+	return value, exists
+}
+
+// scanConstant scans a top-level constant declaration.
+func (file *File) scanConstant(tokens token.List, index token.Position) (token.Position, error) {
+	index++
+
+	if index >= len(tokens) || tokens[index].Kind != token.Identifier {
+		return index, NewError(errors.New(errors.ExpectedVariable), file.path, tokens[:index+1], nil)
+	}
+
+	name := tokens[index].Text()
+	_, exists := file.constants[name]
+
+	if exists {
+		return index, NewError(errors.New(&errors.VariableAlreadyExists{Name: name}), file.path, tokens[:index+1], nil)
+	}
+
+	index++
+
+	if index >= len(tokens) || tokens[index].Text() != "=" {
+		return index, NewError(errors.New(errors.MissingAssignmentOperator), file.path, tokens[:index+1], nil)
+	}
+
+	index++
+	start := index
+
+	for index < len(tokens) && tokens[index].Kind != token.NewLine {
+		index++
+	}
+
+	// Constants can refer to previously declared constants
+	value := make([]token.Token, index-start)
+	copy(value, tokens[start:index])
+
+	for i, t := range value {
+		number, isConstant := file.constants[t.Text()]
+
+		if t.Kind != token.Identifier || !isConstant {
+			continue
+		}
+
+		value[i] = token.Token{
+			Kind:     token.Number,
+			Position: t.Position,
+			Bytes:    strconv.AppendInt(nil, number, 10),
+		}
+	}
+
+	number, err := evaluateConstant(value)
+
+	if err != nil {
+		return index, NewError(err, file.path, tokens[:start+1], nil)
+	}
+
+	file.constants[name] = number
+	return index, nil
+}
+
+// evaluateConstant calculates the value of a constant integer expression.
+func evaluateConstant(tokens []token.Token) (int64, error) {
+	if len(tokens) == 0 {
+		return 0, errors.New(errors.MissingAssignmentExpression)
+	}
+
+	expr, err := expression.FromTokens(tokens)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer expr.Close()
+	return evaluateConstantExpression(expr)
+}
+
+// evaluateConstant calculates the value of a constant integer expression
+// that can also contain builtins which are resolved at compile time.
+func (state *State) evaluateConstant(tokens []token.Token) (int64, error) {
+	if len(tokens) == 0 {
+		return 0, errors.New(errors.MissingAssignmentExpression)
+	}
+
+	expr, err := expression.FromTokens(tokens)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer expr.Close()
+	err = state.ResolveSizeOf(expr)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return evaluateConstantExpression(expr)
+}
+
+// evaluateConstantExpression calculates the value of a constant integer expression tree.
+// Operations on number literals in the tree are replaced by their result.
+func evaluateConstantExpression(expr *expression.Expression) (int64, error) {
+	err := expr.Fold()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !expr.IsLeaf() || expr.Token.Kind != token.Number || IsFloatLiteral(expr.Token) {
+		return 0, errors.New(errors.NotConstant)
+	}
+
+	number, err := token.ParseInt(expr.Token.Text())
+
+	if err != nil {
+		return 0, errors.New(&errors.NotANumber{Expression: expr.Token.Text()})
+	}
+
+	return number, nil
+}
@@ -0,0 +1,97 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/token"
+)
+
+// DeferState handles the state of defer statements.
+type DeferState struct {
+	list []Deferred
+}
+
+// Deferred represents a function call that runs when the function exits.
+type Deferred struct {
+	call     []token.Token
+	position token.Position
+}
+
+// Defer registers a function call that runs before every exit of the function.
+// The arguments are evaluated when the function exits.
+func (state *State) Defer(tokens []token.Token) error {
+	state.Skip(token.Keyword)
+	call := tokens[1:]
+
+	// Deferred calls in blocks would only run if the block was executed
+	if state.scopes.Depth() > 1 {
+		return errors.New(errors.DeferInsideBlock)
+	}
+
+	if len(call) == 0 || call[0].Kind != token.Identifier {
+		return errors.New(errors.MissingFunctionName)
+	}
+
+	if call[len(call)-1].Kind != token.GroupEnd {
+		return errors.New(&errors.MissingCharacter{Character: ")"})
+	}
+
+	// Variables used in the call need to stay alive until the function exits
+	for _, t := range call {
+		if t.Kind != token.Identifier {
+			continue
+		}
+
+		variable := state.scopes.Get(t.Text())
+
+		if variable != nil {
+			variable.KeepAlive++
+		}
+	}
+
+	state.deferState.list = append(state.deferState.list, Deferred{
+		call:     call,
+		position: state.tokenCursor,
+	})
+
+	state.tokenCursor += len(call)
+	return nil
+}
+
+// RunDeferred adds the deferred calls in reverse order.
+// The return value is preserved because the calls can overwrite it.
+func (state *State) RunDeferred() error {
+	if len(state.deferState.list) == 0 {
+		return nil
+	}
+
+	cursor := state.tokenCursor
+	returnValue := state.registers.ReturnValue[0]
+	hasReturnValue := state.function.HasReturnValue()
+
+	if hasReturnValue {
+		state.assembler.PushRegister(returnValue)
+	}
+
+	for i := len(state.deferState.list) - 1; i >= 0; i-- {
+		deferred := state.deferState.list[i]
+		state.tokenCursor = deferred.position
+
+		if state.debug {
+			line, column := state.function.SourcePosition(deferred.position)
+			state.assembler.AddSourceLine(state.function.File.path, line, column)
+		}
+
+		err := state.Call(deferred.call)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if hasReturnValue {
+		state.assembler.PopRegister(returnValue)
+	}
+
+	state.tokenCursor = cursor
+	return nil
+}
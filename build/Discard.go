@@ -0,0 +1,71 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/token"
+)
+
+// Discard evaluates an expression that is assigned to '_'.
+// The value itself is thrown away, only side effects remain.
+func (state *State) Discard(value []token.Token) error {
+	if len(value) == 0 {
+		return errors.MissingAssignmentExpression
+	}
+
+	err := state.discardValue(value)
+
+	if err != nil {
+		return err
+	}
+
+	state.tokenCursor += len(value)
+	return nil
+}
+
+// discardValue generates the code for the side effects of the value.
+func (state *State) discardValue(value []token.Token) error {
+	// Single values don't need any code
+	if len(value) == 1 {
+		switch value[0].Kind {
+		case token.Identifier:
+			variable := state.scopes.Get(value[0].Text())
+
+			if variable == nil {
+				return errors.New(state.UnknownVariableError(value[0].Text()))
+			}
+
+			state.UseVariable(variable)
+			return nil
+
+		case token.Number, token.Text:
+			return nil
+		}
+	}
+
+	expr, err := expression.FromTokens(value)
+
+	if err != nil {
+		return err
+	}
+
+	defer expr.Close()
+
+	// Function calls don't need a register for the return value
+	if expr.IsFunctionCall {
+		_, err = state.ExpressionToRegister(expr, nil)
+		return err
+	}
+
+	temporary := state.FindFreeRegister()
+
+	if temporary == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	_ = temporary.Use(expr)
+	defer temporary.Free()
+
+	_, err = state.ExpressionToRegister(expr, temporary)
+	return err
+}
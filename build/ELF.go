@@ -0,0 +1,98 @@
+package build
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/elf"
+)
+
+// elfExecutable is an ELF binary that can be written to any writer,
+// not only to a file.
+type elfExecutable struct {
+	*elf.ELF64
+}
+
+// WriteTo writes the ELF binary to the writer.
+// The layout is the same as the one produced by WriteToFile.
+//
+//nolint:errcheck
+func (executable elfExecutable) WriteTo(writer io.Writer) (int64, error) {
+	buffer := bytes.Buffer{}
+	binary.Write(&buffer, binary.LittleEndian, &executable.Header64)
+
+	for _, program := range executable.Programs {
+		binary.Write(&buffer, binary.LittleEndian, &program.Header)
+	}
+
+	for _, section := range executable.Sections {
+		binary.Write(&buffer, binary.LittleEndian, &section.Header)
+	}
+
+	for _, program := range executable.Programs {
+		buffer.Write(program.Padding)
+		buffer.Write(program.Data)
+	}
+
+	for _, section := range executable.Sections {
+		buffer.Write(section.Padding)
+		buffer.Write(section.Data)
+	}
+
+	return buffer.WriteTo(writer)
+}
+
+// elfTypeDynamic is the ELF file type of position-independent executables.
+const elfTypeDynamic = 3
+
+// elfBaseAddress is the address the asm library loads ELF executables at.
+const elfBaseAddress = 0x400000
+
+// newELF creates an ELF binary with the code in the first program
+// and the data in the first section.
+// The relative pointers are data references that are relative to the instruction pointer.
+func newELF(code *asm.Assembler, relativePointers []asm.Pointer) elfExecutable {
+	file := elf.New(code)
+	text := file.Programs[0]
+	data := file.Sections[0]
+	patchRelativePointers(text.Data, relativePointers, uint32(data.Header.Offset-text.Header.Offset))
+	return elfExecutable{file}
+}
+
+// newPositionIndependentELF creates an ELF binary that can be loaded at any address.
+// The addresses in the file start at zero and the code must only refer to the data
+// via pointers that are relative to the instruction pointer.
+// The segment covers the data as well because it is no longer guaranteed
+// to be mapped together with the code.
+func newPositionIndependentELF(code *asm.Assembler, relativePointers []asm.Pointer) elfExecutable {
+	file := newELF(code, relativePointers)
+	file.Type = elfTypeDynamic
+	file.EntryPointInMemory -= elfBaseAddress
+
+	for _, program := range file.Programs {
+		program.Header.VirtualAddress -= elfBaseAddress
+		program.Header.PhysicalAddress -= elfBaseAddress
+	}
+
+	for _, section := range file.Sections {
+		section.Header.VirtualAddress -= elfBaseAddress
+	}
+
+	text := file.Programs[0]
+	data := file.Sections[0]
+	end := data.Header.Offset + int64(len(data.Data))
+	text.Header.SizeInFileImage = end - text.Header.Offset
+	text.Header.SizeInMemory = text.Header.SizeInFileImage
+	return file
+}
+
+// patchRelativePointers replaces the data addresses in the code by their distance
+// to the end of the instruction. The distance is the offset of the data relative to the code.
+func patchRelativePointers(code []byte, pointers []asm.Pointer, distance uint32) {
+	for _, pointer := range pointers {
+		slice := code[pointer.Position : pointer.Position+4]
+		binary.LittleEndian.PutUint32(slice, distance+pointer.Address-(pointer.Position+4))
+	}
+}
@@ -3,6 +3,7 @@ package build
 import (
 	"fmt"
 
+	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/token"
 )
 
@@ -16,6 +17,7 @@ type EnsureState struct {
 type Ensure struct {
 	condition []token.Token
 	failLabel string
+	position  token.Position
 }
 
 // Ensure specifies a condition that must be true for parameters.
@@ -24,6 +26,10 @@ func (state *State) Ensure(tokens []token.Token) error {
 		return nil
 	}
 
+	if len(state.function.ReturnTypes) == 0 {
+		return errors.New(errors.EnsureWithoutFunctionType)
+	}
+
 	state.Skip(token.Keyword)
 	condition := tokens[1:]
 
@@ -33,6 +39,7 @@ func (state *State) Ensure(tokens []token.Token) error {
 	state.ensureState.list = append(state.ensureState.list, Ensure{
 		condition: condition,
 		failLabel: failLabel,
+		position:  state.tokenCursor,
 	})
 
 	return nil
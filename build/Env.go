@@ -0,0 +1,132 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/akyoto/q/build/assembler"
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/types"
+)
+
+// Env stores a pointer to the value of the environment variable with the given name
+// in the expression register or 0 if the variable doesn't exist.
+// The value is a null-terminated string because it is located in the environment
+// that the kernel placed on the stack at program entry.
+func (state *State) Env(expr *expression.Expression, function *Function) error {
+	// The initial stack pointer is accessed via the fs register which can only be set on Linux
+	if state.environment.Target != Linux {
+		return errors.New(&errors.UnsupportedTarget{Name: function.Name, Target: state.environment.Target.Name})
+	}
+
+	name := state.FindFreeRegister()
+
+	if name == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	parameter := expr.Children[0]
+	name.ForceUse(parameter)
+	defer name.Free()
+
+	typ, err := state.ExpressionToRegister(parameter, name)
+
+	if err != nil {
+		return err
+	}
+
+	if typ != types.Text {
+		return errors.New(&errors.InvalidType{
+			Name:          typ.String(),
+			Expected:      types.Text.String(),
+			ParameterName: function.Parameters[0].Name,
+		})
+	}
+
+	expr.Type = types.Text
+
+	if expr.Register == nil {
+		return nil
+	}
+
+	state.envCounter++
+	labelEntry := fmt.Sprintf("env_%d_entry", state.envCounter)
+	labelCompare := fmt.Sprintf("env_%d_compare", state.envCounter)
+	labelEquals := fmt.Sprintf("env_%d_equals", state.envCounter)
+	labelEnd := fmt.Sprintf("env_%d_end", state.envCounter)
+
+	character := state.registers.All.ByName("rax")
+	length := state.registers.All.ByName("rcx")
+	entry := state.registers.All.ByName("rdx")
+	list := state.registers.All.ByName("rsi")
+	text := state.registers.All.ByName("rdi")
+	cursor := state.registers.All.ByName("r8")
+	remaining := state.registers.All.ByName("r9")
+	expected := state.registers.All.ByName("r10")
+	var saved []*register.Register
+
+	for _, reg := range []*register.Register{character, length, entry, list, text, cursor, remaining, expected} {
+		state.assembler.UseRegisterID(reg.ID)
+
+		if reg.IsFree() || reg.IsEmpty() || reg == expr.Register || reg == name {
+			continue
+		}
+
+		saved = append(saved, reg)
+		state.assembler.PushRegister(reg)
+	}
+
+	state.assembler.MoveRegisterRegister(text, name)
+	offset := -assembler.StringLengthSize
+	state.assembler.LoadRegister(length, text, byte(offset), assembler.StringLengthSize)
+
+	// The environment pointers follow argc, the argument pointers and a null pointer
+	state.assembler.LoadStackStart(list)
+	state.assembler.LoadRegister(character, list, 0, 8)
+	state.assembler.LoadAddress(list, list, character, 8, 16)
+
+	// Each entry has the form 'NAME=value'
+	state.assembler.AddLabel(labelEntry)
+	state.assembler.LoadRegister(entry, list, 0, 8)
+	state.assembler.CompareRegisterNumber(entry, 0)
+	state.assembler.JumpIfEqual(labelEnd)
+	state.assembler.AddRegisterNumber(list, 8)
+	state.assembler.MoveRegisterRegister(cursor, text)
+	state.assembler.MoveRegisterRegister(remaining, length)
+	state.assembler.AddLabel(labelCompare)
+	state.assembler.CompareRegisterNumber(remaining, 0)
+	state.assembler.JumpIfEqual(labelEquals)
+	state.assembler.LoadZeroExtend(character, entry, 0, 1)
+	state.assembler.LoadZeroExtend(expected, cursor, 0, 1)
+	state.assembler.CompareRegisterRegister(character, expected)
+	state.assembler.JumpIfNotEqual(labelEntry)
+	state.assembler.IncreaseRegister(entry)
+	state.assembler.IncreaseRegister(cursor)
+	state.assembler.DecreaseRegister(remaining)
+	state.assembler.Jump(labelCompare)
+	state.assembler.AddLabel(labelEquals)
+	state.assembler.LoadZeroExtend(character, entry, 0, 1)
+	state.assembler.CompareRegisterNumber(character, '=')
+	state.assembler.JumpIfNotEqual(labelEntry)
+	state.assembler.IncreaseRegister(entry)
+	state.assembler.AddLabel(labelEnd)
+	state.assembler.MoveRegisterRegister(expr.Register, entry)
+
+	for i := len(saved) - 1; i >= 0; i-- {
+		state.assembler.PopRegister(saved[i])
+	}
+
+	return nil
+}
+
+// usesStackStart tells you whether one of the functions needs the stack pointer at program entry.
+func usesStackStart(functions []*Function) bool {
+	for _, function := range functions {
+		if function.assembler.LoadsStackStart() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,7 @@
 package build
 
 import (
+	"fmt"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -10,10 +11,20 @@ import (
 
 // Environment represents the global state.
 type Environment struct {
-	Packages        map[string]*Package
-	Functions       map[string]*Function
-	Types           map[string]*types.Type
-	StandardLibrary string
+	Packages             map[string]*Package
+	Functions            map[string]*Function
+	Types                map[string]*types.Type
+	StandardLibrary      string
+	Target               *Target
+	CPU                  *CPU
+	OverflowChecks       bool
+	StackGuard           bool
+	Debug                bool
+	PureCallWarnings     bool
+	CounterWriteWarnings bool
+	InlineThreshold      int
+	Cache                *Cache
+	reachable            map[*Function]bool
 }
 
 // NewEnvironment creates a new build environment.
@@ -29,6 +40,9 @@ func NewEnvironment() (*Environment, error) {
 		Functions:       map[string]*Function{},
 		Types:           types.Default,
 		StandardLibrary: standardLibrary,
+		Target:          Linux,
+		CPU:             Baseline,
+		InlineThreshold: DefaultInlineThreshold,
 	}
 
 	return environment, nil
@@ -42,11 +56,14 @@ func (env *Environment) ImportDirectory(pkg *Package) error {
 
 // Import imports the given functions and imports to the environment.
 func (env *Environment) Import(pkg *Package, functions <-chan *Function, structs <-chan *types.Type, imports <-chan *Import, errors <-chan error) error {
+	// The errors of all files are collected so that they can be reported together
+	var errorList []error
+
 	for {
 		select {
 		case err, ok := <-errors:
 			if ok {
-				return err
+				errorList = append(errorList, err)
 			}
 
 		case imp, ok := <-imports:
@@ -69,12 +86,12 @@ func (env *Environment) Import(pkg *Package, functions <-chan *Function, structs
 			err := env.ImportDirectory(importPackage)
 
 			if err != nil {
-				return err
+				errorList = append(errorList, err)
 			}
 
 		case typ, ok := <-structs:
 			if !ok {
-				return nil
+				return newErrorList(errorList)
 			}
 
 			if pkg.Name != MainPackageName {
@@ -85,7 +102,7 @@ func (env *Environment) Import(pkg *Package, functions <-chan *Function, structs
 
 		case function, ok := <-functions:
 			if !ok {
-				return nil
+				return newErrorList(errorList)
 			}
 
 			if pkg.Name != MainPackageName {
@@ -97,11 +114,49 @@ func (env *Environment) Import(pkg *Package, functions <-chan *Function, structs
 	}
 }
 
-// Compile compiles all functions.
+// Compile compiles all functions that can be reached from the main package.
+// Functions in the main package are always compiled so that their errors are reported,
+// but only the functions reachable from 'main' are part of the final code.
 func (env *Environment) Compile(optimize bool, verbose bool) {
 	wg := sync.WaitGroup{}
+	var roots []*Function
+
+	for _, function := range env.Functions {
+		if function.File.pkg.Name == MainPackageName {
+			roots = append(roots, function)
+		}
+	}
+
+	reachable := env.ReachableFunctions(roots)
+	env.reachable = env.ReachableFunctions([]*Function{env.Functions["main"]})
+	env.markRecursion(reachable)
+
+	// All signatures are known before the first call is compiled
+	for function := range reachable {
+		err := function.ResolveSignature()
+
+		if err != nil {
+			function.Error = err
+			continue
+		}
+
+		err = function.checkForcedInline()
+
+		if err != nil {
+			function.Error = err
+		}
+	}
+
+	if env.Cache != nil {
+		flags := fmt.Sprintf("optimize=%t verbose=%t overflow=%t stackguard=%t debug=%t purecalls=%t counterwrites=%t target=%s cpu=%s inline=%d", optimize, verbose, env.OverflowChecks, env.StackGuard, env.Debug, env.PureCallWarnings, env.CounterWriteWarnings, env.Target.Name, env.CPU.Name, env.InlineThreshold)
+		env.Cache.Prepare(env, reachable, flags)
+	}
 
 	for _, function := range env.Functions {
+		if !reachable[function] {
+			continue
+		}
+
 		wg.Add(1)
 
 		go func(function *Function) {
@@ -120,3 +175,50 @@ func (env *Environment) Compile(optimize bool, verbose bool) {
 
 	wg.Wait()
 }
+
+// ReachableFunctions returns the functions that can be reached from the given functions.
+// Every identifier that refers to a function counts as a reference,
+// therefore functions that are only used as values are included as well.
+func (env *Environment) ReachableFunctions(roots []*Function) map[*Function]bool {
+	byName := env.functionsByName()
+	reachable := map[*Function]bool{}
+	var queue []*Function
+
+	for _, function := range roots {
+		if function == nil || reachable[function] {
+			continue
+		}
+
+		reachable[function] = true
+		queue = append(queue, function)
+	}
+
+	for len(queue) > 0 {
+		function := queue[0]
+		queue = queue[1:]
+
+		for _, callee := range function.callees(byName) {
+			if reachable[callee] {
+				continue
+			}
+
+			reachable[callee] = true
+			queue = append(queue, callee)
+		}
+	}
+
+	return reachable
+}
+
+// functionsByName returns the functions grouped by their name without the package prefix.
+func (env *Environment) functionsByName() map[string][]*Function {
+	byName := map[string][]*Function{}
+
+	for _, function := range env.Functions {
+		name := UnpolymorphName(function.Name)
+		name = name[strings.LastIndex(name, ".")+1:]
+		byName[name] = append(byName[name], function)
+	}
+
+	return byName
+}
@@ -31,10 +31,12 @@ func NewError(err error, path string, tokens []token.Token, function *Function)
 		lineStart = -1
 	)
 
-	for i, oldToken := range tokens {
-		if oldToken.Kind == token.NewLine && i != len(tokens)-1 {
-			lineCount++
-			lineStart = int(oldToken.Position)
+	for _, oldToken := range tokens[:len(tokens)-1] {
+		count, last := oldToken.LineBreaks()
+
+		if count > 0 {
+			lineCount += count
+			lineStart = last
 		}
 	}
 
@@ -52,16 +54,7 @@ func NewError(err error, path string, tokens []token.Token, function *Function)
 
 // Error generates the string representation.
 func (e *Error) Error() string {
-	path := e.Path
-	cwd, err := os.Getwd()
-
-	if err == nil {
-		relativePath, err := filepath.Rel(cwd, e.Path)
-
-		if err == nil {
-			path = relativePath
-		}
-	}
+	path := e.relativePath()
 
 	if e.Function != nil {
 		return fmt.Sprintf("%s:%d:%d: [%s] %s", path, e.Line, e.Column, e.Function.Name, e.Err)
@@ -69,3 +62,35 @@ func (e *Error) Error() string {
 
 	return fmt.Sprintf("%s:%d:%d: %s", path, e.Line, e.Column, e.Err)
 }
+
+// Code returns the code of the underlying error.
+func (e *Error) Code() string {
+	return errors.Code(e.Err)
+}
+
+// Message returns the error message without the position and the stack.
+func (e *Error) Message() string {
+	return errors.Message(e.Err)
+}
+
+// Position returns the location of the error.
+func (e *Error) Position() errors.Position {
+	return errors.Position{Path: e.relativePath(), Line: e.Line, Column: e.Column}
+}
+
+// relativePath returns the path relative to the working directory if possible.
+func (e *Error) relativePath() string {
+	cwd, err := os.Getwd()
+
+	if err != nil {
+		return e.Path
+	}
+
+	relativePath, err := filepath.Rel(cwd, e.Path)
+
+	if err != nil {
+		return e.Path
+	}
+
+	return relativePath
+}
@@ -0,0 +1,147 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/akyoto/q/build/errors"
+)
+
+// ErrorList is a list of compiler errors sorted by their position.
+type ErrorList []error
+
+// Error generates the string representation of all errors.
+func (list ErrorList) Error() string {
+	messages := make([]string, 0, len(list))
+
+	for _, err := range list {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n\n")
+}
+
+// MaxErrors is the maximum number of errors in a report.
+const MaxErrors = 20
+
+// newErrorList returns the error itself if there is only one error
+// and a list sorted by position if there are multiple errors.
+// Errors with the same message at the same position are only reported once
+// and the list is cut off after MaxErrors errors.
+func newErrorList(list []error) error {
+	var (
+		unique   []error
+		reported = map[string]bool{}
+	)
+
+	for _, err := range list {
+		for _, err := range flatten(err) {
+			key := fmt.Sprintf("%v %s", errorPosition(err), errors.Message(err))
+
+			if reported[key] {
+				continue
+			}
+
+			reported[key] = true
+			unique = append(unique, err)
+		}
+	}
+
+	switch len(unique) {
+	case 0:
+		return nil
+
+	case 1:
+		return unique[0]
+	}
+
+	sort.SliceStable(unique, func(a, b int) bool {
+		return positionLess(errorPosition(unique[a]), errorPosition(unique[b]))
+	})
+
+	if len(unique) > MaxErrors {
+		omitted := len(unique) - MaxErrors
+		unique = append(unique[:MaxErrors], &errors.TooManyErrors{Omitted: omitted})
+	}
+
+	return ErrorList(unique)
+}
+
+// flatten returns the errors contained in the error.
+func flatten(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	list, isList := err.(ErrorList)
+
+	if isList {
+		return list
+	}
+
+	return []error{err}
+}
+
+// errorPosition returns the position of the error or an empty position if it's unknown.
+func errorPosition(err error) errors.Position {
+	diagnostic, ok := err.(errors.Diagnostic)
+
+	if !ok {
+		return errors.Position{}
+	}
+
+	return diagnostic.Position()
+}
+
+// positionLess tells you whether the position comes before the other position.
+func positionLess(a errors.Position, b errors.Position) bool {
+	if a.Path != b.Path {
+		return a.Path < b.Path
+	}
+
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+
+	return a.Column < b.Column
+}
+
+// errorJSON is the JSON representation of a compiler error.
+type errorJSON struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteErrorsJSON writes the errors as a JSON array of objects with the file, line, column, code and message.
+// Errors without a position have an empty file name and line and column 0.
+func WriteErrorsJSON(writer io.Writer, err error) error {
+	list, isList := err.(ErrorList)
+
+	if !isList {
+		list = ErrorList{err}
+	}
+
+	objects := make([]errorJSON, 0, len(list))
+
+	for _, err := range list {
+		position := errorPosition(err)
+
+		objects = append(objects, errorJSON{
+			File:    position.Path,
+			Line:    position.Line,
+			Column:  position.Column,
+			Code:    errors.Code(err),
+			Message: errors.Message(err),
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(objects)
+}
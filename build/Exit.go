@@ -0,0 +1,54 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/token"
+)
+
+// Exit terminates the program with the given exit code.
+// The syscall never returns, therefore the registers don't need to be preserved
+// and the calling function doesn't need a return statement.
+func (state *State) Exit(expr *expression.Expression, function *Function) error {
+	parameter := expr.Children[0]
+	code := state.registers.Syscall[1]
+	target := code
+
+	// Calls in the parameter would overwrite the syscall registers
+	if parameter.ContainsCall() {
+		target = state.FindFreeRegister()
+
+		if target == nil {
+			return errors.New(errors.ExceededMaxVariables)
+		}
+
+		target.ForceUse(parameter)
+		defer target.Free()
+	}
+
+	typ, err := state.ExpressionToRegister(parameter, target)
+
+	if err != nil {
+		return err
+	}
+
+	if parameter.IsLeaf() {
+		typ = literalType([]token.Token{parameter.Token}, typ, function.Parameters[0].Type)
+	}
+
+	if typ != function.Parameters[0].Type {
+		return errors.New(&errors.InvalidType{
+			Name:          typ.String(),
+			Expected:      function.Parameters[0].Type.String(),
+			ParameterName: function.Parameters[0].Name,
+		})
+	}
+
+	if target != code {
+		state.assembler.MoveRegisterRegister(code, target)
+	}
+
+	state.assembler.MoveRegisterNumber(state.registers.Syscall[0], state.environment.Target.SyscallExit)
+	state.assembler.Syscall()
+	return nil
+}
@@ -2,10 +2,12 @@ package build
 
 import (
 	"fmt"
+	"math"
 	"sync/atomic"
 
 	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/operators"
 	"github.com/akyoto/q/build/register"
 	"github.com/akyoto/q/build/token"
 	"github.com/akyoto/q/build/types"
@@ -13,8 +15,11 @@ import (
 )
 
 // EvaluateTokens evaluates the token expression and stores the result in a register.
+// Temporary registers are marked as used and need to be freed by the caller.
 func (state *State) EvaluateTokens(tokens []token.Token) (*register.Register, *types.Type, error) {
-	if len(tokens) == 1 && tokens[0].Kind == token.Identifier {
+	_, isBool := BoolLiteral(tokens[0])
+
+	if len(tokens) == 1 && tokens[0].Kind == token.Identifier && !isBool && state.ReferencedFunction(tokens[0].Text()) == nil {
 		variableName := tokens[0].Text()
 		variable := state.scopes.Get(variableName)
 
@@ -26,12 +31,13 @@ func (state *State) EvaluateTokens(tokens []token.Token) (*register.Register, *t
 		return variable.Register(), variable.Type, nil
 	}
 
-	freeRegister := state.registers.General.FindFree()
+	freeRegister := state.FindFreeRegister()
 
 	if freeRegister == nil {
 		return nil, nil, errors.New(errors.ExceededMaxVariables)
 	}
 
+	freeRegister.ForceUse(token.List(tokens))
 	typ, err := state.TokensToRegister(tokens, freeRegister)
 	return freeRegister, typ, err
 }
@@ -55,17 +61,53 @@ func (state *State) TokensToRegister(tokens []token.Token, register *register.Re
 
 // ExpressionToRegister moves the result of an expression into the given register.
 func (state *State) ExpressionToRegister(root *expression.Expression, finalRegister *register.Register) (*types.Type, error) {
+	err := state.ResolveSizeOf(root)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Text literals are concatenated at compile time
+	err = root.FoldTexts()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate operations on number literals at compile time
+	if state.foldConstants {
+		err := root.Fold()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if root.IsLeaf() {
+		// Calls that were resolved at compile time don't need any instructions if the result is unused
+		if finalRegister == nil && root.Token.Kind == token.Number {
+			return root.Type, nil
+		}
+
 		return state.TokenToRegister(root.Token, finalRegister)
 	}
 
 	// Resolve package access
-	err := state.ResolveAccessors(root)
+	err = state.ResolveAccessors(root)
 
 	if err != nil {
 		return nil, err
 	}
 
+	// Multiply-add patterns only need a single instruction
+	if state.reduceStrength && finalRegister != nil {
+		typ, ok := state.MultiplyAdd(root, finalRegister)
+
+		if ok {
+			return typ, nil
+		}
+	}
+
 	// Save the temporary registers so we can easily free them later
 	var temporaryRegisters []*register.Register
 
@@ -77,6 +119,13 @@ func (state *State) ExpressionToRegister(root *expression.Expression, finalRegis
 	if finalRegister != nil {
 		root.Register = finalRegister
 
+		// Mark the final register as used so that operations
+		// with implicit registers don't overwrite intermediate results.
+		if finalRegister.IsFree() {
+			finalRegister.ForceUse(root)
+			defer finalRegister.Free()
+		}
+
 		// Assign final register to the left operands in the left tree
 		left := root
 
@@ -91,7 +140,7 @@ func (state *State) ExpressionToRegister(root *expression.Expression, finalRegis
 		if sub.IsFunctionCall {
 			// Allocate a temporary register if necessary
 			if sub.Register == nil && sub.Parent != nil {
-				sub.Register = state.registers.General.FindFree()
+				sub.Register = state.FindFreeRegister()
 
 				if sub.Register == nil {
 					return errors.New(errors.ExceededMaxVariables)
@@ -104,12 +153,27 @@ func (state *State) ExpressionToRegister(root *expression.Expression, finalRegis
 			return state.CallExpression(sub)
 		}
 
+		if sub.IsLogical() {
+			// Allocate a temporary register if necessary
+			if sub.Register == nil {
+				sub.Register = state.FindFreeRegister()
+
+				if sub.Register == nil {
+					return errors.New(errors.ExceededMaxVariables)
+				}
+
+				_ = sub.Register.Use(sub)
+				temporaryRegisters = append(temporaryRegisters, sub.Register)
+			}
+
+			return state.LogicalExpression(sub)
+		}
+
 		left := sub.Children[0]
-		right := sub.Children[1]
 
 		// Allocate a temporary register if necessary
 		if left.Register == nil {
-			left.Register = state.registers.General.FindFree()
+			left.Register = state.FindFreeRegister()
 
 			if left.Register == nil {
 				return errors.New(errors.ExceededMaxVariables)
@@ -132,7 +196,7 @@ func (state *State) ExpressionToRegister(root *expression.Expression, finalRegis
 			}
 
 			state.UseVariable(variable)
-			fieldName := right.Token.Text()
+			fieldName := sub.Children[1].Token.Text()
 			field := variable.Type.FieldByName(fieldName)
 
 			if field == nil {
@@ -158,42 +222,47 @@ func (state *State) ExpressionToRegister(root *expression.Expression, finalRegis
 			left.Register.Free()
 		}
 
-		if sub.Type == nil {
-			sub.Type = left.Type
+		// Array element access
+		if sub.IsArrayAccess() {
+			return state.ArrayElement(sub, sub.Children[1])
 		}
 
-		// Right operand is a leaf node
-		if right.IsLeaf() {
-			switch right.Token.Kind {
-			case token.Identifier:
-				variableName := right.Token.Text()
-				variable := state.scopes.Get(variableName)
+		op, exists := operators.All[operator]
 
-				if variable == nil {
-					return errors.New(state.UnknownVariableError(variableName))
-				}
+		if !exists {
+			return errors.New(errors.MissingOperator)
+		}
 
-				state.UseVariable(variable)
-				right.Type = variable.Type
-				return state.CalculateRegisterRegister(operator, sub.Register, variable.Register())
+		if sub.Type == nil {
+			sub.Type = left.Type
 
-			case token.Number:
-				right.Type = types.Int
-				return state.CalculateRegisterNumber(operator, sub.Register, right)
+			// Comparisons store their result as a boolean
+			if op.Kind == operators.Comparison {
+				sub.Type = types.Bool
+			}
+		}
 
-			default:
-				return fmt.Errorf("Invalid operand %s", right.Token)
+		// Unary operations
+		if len(sub.Children) == 1 {
+			if sub.Type == types.Float64 {
+				return state.CalculateFloatRegister(operator, sub.Register)
 			}
+
+			return state.CalculateRegister(operator, sub.Register)
 		}
 
-		// Right operand is an expression
-		err := state.CalculateRegisterRegister(operator, sub.Register, right.Register)
+		right := sub.Children[1]
+		err := state.calculateOperands(operator, sub, left, right)
 
 		if err != nil {
 			return err
 		}
 
-		right.Register.Free()
+		// Comparisons only set the flags and the result needs to be stored
+		if op.Kind == operators.Comparison {
+			state.IfTrueSet(operator, sub.Register, isUnsignedOperation(left.Type, right.Type))
+		}
+
 		return nil
 	})
 
@@ -209,15 +278,118 @@ func (state *State) ExpressionToRegister(root *expression.Expression, finalRegis
 	return root.Type, nil
 }
 
+// calculateOperands performs a binary operation on the left operand stored
+// in the expression register and the right operand.
+func (state *State) calculateOperands(operator string, sub *expression.Expression, left *expression.Expression, right *expression.Expression) error {
+	// Right operand is a leaf node
+	if right.IsLeaf() {
+		switch right.Token.Kind {
+		case token.Identifier:
+			value, isBool := BoolLiteral(right.Token)
+
+			if isBool {
+				right.Type = types.Bool
+
+				op, exists := operators.All[operator]
+
+				if !exists || op.Kind != operators.Comparison {
+					return errors.New(&errors.InvalidType{Name: right.Type.String(), Expected: left.Type.String()})
+				}
+
+				state.assembler.CompareRegisterNumber(sub.Register, value)
+				return nil
+			}
+
+			variableName := right.Token.Text()
+			variable := state.scopes.Get(variableName)
+
+			if variable == nil {
+				return errors.New(state.UnknownVariableError(variableName))
+			}
+
+			state.UseVariable(variable)
+			right.Type = variable.Type
+
+			if isFloatOperation(left.Type, right.Type) {
+				return state.CalculateFloatRegisterRegister(operator, sub.Register, variable.Register(), left.Type, right.Type)
+			}
+
+			return state.CalculateRegisterRegister(operator, sub.Register, variable.Register())
+
+		case token.Number:
+			if IsFloatLiteral(right.Token) {
+				right.Type = types.Float64
+				return state.CalculateFloatRegisterNumber(operator, sub.Register, right, left.Type)
+			}
+
+			right.Type = types.Int
+
+			if left.Type == types.Float64 {
+				return errors.New(&errors.InvalidType{Name: right.Type.String(), Expected: left.Type.String()})
+			}
+
+			err := state.CalculateRegisterNumber(operator, sub.Register, right)
+
+			if errors.Unwrap(err) == errors.DivisionByZero {
+				return errors.NewAt(errors.DivisionByZero, sub.Token.Position)
+			}
+
+			return err
+
+		default:
+			return errors.New(&errors.InvalidOperand{Operand: right.Token.String()})
+		}
+	}
+
+	// Right operand is an expression
+	var err error
+
+	if isFloatOperation(left.Type, right.Type) {
+		err = state.CalculateFloatRegisterRegister(operator, sub.Register, right.Register, left.Type, right.Type)
+	} else {
+		err = state.CalculateRegisterRegister(operator, sub.Register, right.Register)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	right.Register.Free()
+	return nil
+}
+
+// ConstantInt returns the value of an integer expression that can be calculated at compile time.
+func (state *State) ConstantInt(tokens []token.Token) (int64, bool) {
+	if len(tokens) == 0 {
+		return 0, false
+	}
+
+	number, err := state.evaluateConstant(tokens)
+	return number, err == nil
+}
+
 // TokenToRegister moves a token into a register.
 // It only works with identifiers, numbers and texts.
 func (state *State) TokenToRegister(singleToken token.Token, register *register.Register) (*types.Type, error) {
 	switch singleToken.Kind {
 	case token.Identifier:
+		value, isBool := BoolLiteral(singleToken)
+
+		if isBool {
+			state.assembler.MoveRegisterNumber(register, value)
+			return types.Bool, nil
+		}
+
 		variableName := singleToken.Text()
 		variable := state.scopes.Get(variableName)
 
 		if variable == nil {
+			function := state.ReferencedFunction(variableName)
+
+			if function != nil {
+				return state.FunctionAddress(function, register), nil
+			}
+
 			return nil, errors.New(state.UnknownVariableError(variableName))
 		}
 
@@ -225,7 +397,7 @@ func (state *State) TokenToRegister(singleToken token.Token, register *register.
 
 		// Moving a variable into its own register is pointless
 		if variable.Register() == register {
-			return nil, nil
+			return variable.Type, nil
 		}
 
 		state.assembler.MoveRegisterRegister(register, variable.Register())
@@ -233,6 +405,18 @@ func (state *State) TokenToRegister(singleToken token.Token, register *register.
 
 	case token.Number:
 		numberString := singleToken.Text()
+
+		if IsFloatLiteral(singleToken) {
+			number, err := state.ParseFloat(numberString)
+
+			if err != nil {
+				return nil, err
+			}
+
+			state.assembler.MoveRegisterNumber(register, math.Float64bits(number))
+			return types.Float64, nil
+		}
+
 		number, err := state.ParseInt(numberString)
 
 		if err != nil {
@@ -244,13 +428,59 @@ func (state *State) TokenToRegister(singleToken token.Token, register *register.
 
 	case token.Text:
 		address := state.assembler.AddString(singleToken.Text())
-		state.assembler.MoveRegisterAddress(register, address)
+		state.assembler.LoadStringAddress(register, address)
 		return types.Text, nil
 	}
 
 	return nil, errors.New(errors.NotImplemented)
 }
 
+// CalculateRegisterTokens performs an operation on a register and the result of a token expression.
+func (state *State) CalculateRegisterTokens(operation string, register *register.Register, tokens []token.Token) (*types.Type, error) {
+	if len(tokens) == 1 && tokens[0].Kind == token.Number && !IsFloatLiteral(tokens[0]) {
+		operand := expression.FromToken(tokens[0])
+		defer operand.Close()
+		return operand.Type, state.CalculateRegisterNumber(operation, register, operand)
+	}
+
+	operandRegister, typ, err := state.EvaluateTokens(tokens)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == types.Float64 {
+		err = state.CalculateFloatRegisterRegister(operation, register, operandRegister, typ, typ)
+	} else {
+		err = state.CalculateRegisterRegister(operation, register, operandRegister)
+	}
+
+	_, isVariable := operandRegister.User().(*Variable)
+
+	if !isVariable {
+		operandRegister.Free()
+	}
+
+	return typ, err
+}
+
+// CalculateRegister performs a unary operation on a register.
+func (state *State) CalculateRegister(operation string, register *register.Register) error {
+	switch operation {
+	case "-":
+		state.assembler.NegateRegister(register)
+		state.CheckOverflow()
+
+	case "~":
+		state.assembler.NotRegister(register)
+
+	default:
+		return errors.New(errors.NotImplemented)
+	}
+
+	return nil
+}
+
 // CalculateRegisterNumber performs an operation on a register and a number.
 func (state *State) CalculateRegisterNumber(operation string, register *register.Register, operand *expression.Expression) error {
 	number, err := state.ParseInt(operand.Token.Text())
@@ -259,42 +489,75 @@ func (state *State) CalculateRegisterNumber(operation string, register *register
 		return err
 	}
 
+	// Powers of two can use shifts instead
+	if state.reduceStrength && state.ReduceStrength(operation, register, number) {
+		return nil
+	}
+
+	// Immediate values are limited to 32 bits,
+	// larger numbers need a temporary register.
+	if number < math.MinInt32 || number > math.MaxInt32 {
+		switch operation {
+		case "+", "-", "*", "&", "|", "^", "==", "!=", "<", "<=", ">", ">=":
+			return state.CalculateRegisterTemporary(operation, register, operand, number)
+		}
+	}
+
 	switch operation {
 	case "+":
 		if number == 1 {
 			state.assembler.IncreaseRegister(register)
-			return nil
+		} else {
+			state.assembler.AddRegisterNumber(register, uint64(number))
 		}
 
-		state.assembler.AddRegisterNumber(register, uint64(number))
+		state.CheckOverflow()
 
 	case "-":
 		if number == 1 {
 			state.assembler.DecreaseRegister(register)
-			return nil
+		} else {
+			state.assembler.SubRegisterNumber(register, uint64(number))
 		}
 
-		state.assembler.SubRegisterNumber(register, uint64(number))
+		state.CheckOverflow()
 
 	case "*":
 		state.assembler.MulRegisterNumber(register, uint64(number))
+		state.CheckOverflow()
 
-	case "/":
-		temporary := state.registers.General.FindFree()
+	case "&":
+		state.assembler.AndRegisterNumber(register, uint64(number))
 
-		if temporary == nil {
-			return errors.New(errors.ExceededMaxVariables)
+	case "|":
+		state.assembler.OrRegisterNumber(register, uint64(number))
+
+	case "^":
+		state.assembler.XorRegisterNumber(register, uint64(number))
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		state.assembler.CompareRegisterNumber(register, uint64(number))
+
+	case "<<":
+		state.assembler.ShiftLeftRegisterNumber(register, uint64(number))
+
+	case ">>":
+		state.assembler.ShiftRightRegisterNumber(register, uint64(number))
+
+	case "/", "%":
+		if number == 0 {
+			return errors.New(errors.DivisionByZero)
 		}
 
-		temporary.ForceUse(operand)
-		state.assembler.MoveRegisterNumber(temporary, uint64(number))
-		err := state.CalculateRegisterRegister("/", register, temporary)
+		if state.reduceStrength {
+			reduced, err := state.DivideByConstant(operation, register, number)
 
-		if err != nil {
-			return err
+			if reduced || err != nil {
+				return err
+			}
 		}
 
-		temporary.Free()
+		return state.CalculateRegisterTemporary(operation, register, operand, number)
 
 	default:
 		return errors.New(errors.NotImplemented)
@@ -303,56 +566,213 @@ func (state *State) CalculateRegisterNumber(operation string, register *register
 	return nil
 }
 
+// CalculateRegisterTemporary moves the number into a temporary register
+// and then performs the operation on both registers.
+func (state *State) CalculateRegisterTemporary(operation string, register *register.Register, operand *expression.Expression, number int64) error {
+	temporary := state.FindFreeRegister()
+
+	if temporary == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	temporary.ForceUse(operand)
+	state.assembler.MoveRegisterNumber(temporary, uint64(number))
+	err := state.CalculateRegisterRegister(operation, register, temporary)
+
+	if err != nil {
+		return err
+	}
+
+	temporary.Free()
+	return nil
+}
+
 // CalculateRegisterRegister performs an operation on two registers.
 func (state *State) CalculateRegisterRegister(operation string, registerTo *register.Register, registerFrom *register.Register) error {
 	switch operation {
 	case "+":
 		state.assembler.AddRegisterRegister(registerTo, registerFrom)
+		state.CheckOverflow()
 
 	case "-":
 		state.assembler.SubRegisterRegister(registerTo, registerFrom)
+		state.CheckOverflow()
 
 	case "*":
 		state.assembler.MulRegisterRegister(registerTo, registerFrom)
+		state.CheckOverflow()
+
+	case "&":
+		state.assembler.AndRegisterRegister(registerTo, registerFrom)
+
+	case "|":
+		state.assembler.OrRegisterRegister(registerTo, registerFrom)
+
+	case "^":
+		state.assembler.XorRegisterRegister(registerTo, registerFrom)
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		state.assembler.CompareRegisterRegister(registerTo, registerFrom)
+
+	case "<<", ">>":
+		return state.ShiftRegisterRegister(operation, registerTo, registerFrom)
+
+	case "/", "%":
+		return state.DivideRegisterRegister(operation, registerTo, registerFrom)
+
+	default:
+		return errors.New(errors.NotImplemented)
+	}
+
+	return nil
+}
+
+// ShiftRegisterRegister shifts a register by the number of bits stored in another register.
+// The CPU expects the shift count to be in the CL register unless it supports BMI2.
+func (state *State) ShiftRegisterRegister(operation string, registerTo *register.Register, registerFrom *register.Register) error {
+	if state.environment.CPU.BMI2 {
+		switch operation {
+		case "<<":
+			state.assembler.ShiftLeftRegisterRegisterBMI2(registerTo, registerFrom)
+
+		case ">>":
+			state.assembler.ShiftRightRegisterRegisterBMI2(registerTo, registerFrom)
+		}
 
-	case "/":
-		rax := state.registers.All.ByName("rax")
-		rdx := state.registers.All.ByName("rdx")
+		return nil
+	}
 
-		if rax != registerTo {
-			err := state.TryFreeRegister(rax)
+	rcx := state.registers.All.ByName("rcx")
+	destination := registerTo
+
+	// The shift count occupies rcx,
+	// therefore the value needs to be shifted in a different register.
+	if registerTo == rcx {
+		destination = state.FindFreeRegister()
+
+		if destination == nil {
+			return errors.New(errors.ExceededMaxVariables)
+		}
+
+		state.assembler.MoveRegisterRegister(destination, rcx)
+	}
+
+	if registerFrom != rcx {
+		if registerTo != rcx {
+			err := state.TryFreeRegister(rcx)
 
 			if err != nil {
 				return err
 			}
-
-			state.assembler.MoveRegisterRegister(rax, registerTo)
 		}
 
-		err := state.TryFreeRegister(rdx)
+		state.assembler.MoveRegisterRegister(rcx, registerFrom)
+	}
+
+	switch operation {
+	case "<<":
+		state.assembler.ShiftLeftRegisterRegister(destination, rcx)
 
-		if err != nil {
-			return err
+	case ">>":
+		state.assembler.ShiftRightRegisterRegister(destination, rcx)
+	}
+
+	if destination != registerTo {
+		state.assembler.MoveRegisterRegister(registerTo, destination)
+	}
+
+	return nil
+}
+
+// DivideRegisterRegister divides registerTo by registerFrom and stores
+// either the quotient or the remainder in registerTo.
+// The division implicitly uses rax for the dividend and rdx for the remainder.
+func (state *State) DivideRegisterRegister(operation string, registerTo *register.Register, registerFrom *register.Register) error {
+	rax := state.registers.All.ByName("rax")
+	rdx := state.registers.All.ByName("rdx")
+
+	// The divisor must survive the sign extension of the dividend
+	if registerFrom == rax || registerFrom == rdx {
+		divisor := state.FindFreeRegister()
+
+		if divisor == nil {
+			return errors.New(errors.ExceededMaxVariables)
 		}
 
-		state.assembler.SignExtendToDX(rax)
-		state.assembler.DivRegister(registerFrom)
-		state.assembler.MoveRegisterRegister(registerTo, rax)
+		divisor.ForceUse(registerFrom)
+		state.assembler.MoveRegisterRegister(divisor, registerFrom)
+		defer divisor.Free()
+		registerFrom = divisor
+	}
 
-	default:
-		return errors.New(errors.NotImplemented)
+	saved, err := state.SaveRegisters(registerTo, rax, rdx)
+	defer state.RestoreRegisters(saved)
+
+	if err != nil {
+		return err
+	}
+
+	if rax != registerTo {
+		state.assembler.MoveRegisterRegister(rax, registerTo)
+	}
+
+	state.assembler.SignExtendToDX(rax)
+	state.assembler.DivRegister(registerFrom)
+
+	// The quotient is stored in rax and the remainder in rdx
+	if operation == "%" {
+		state.assembler.MoveRegisterRegister(registerTo, rdx)
+	} else {
+		state.assembler.MoveRegisterRegister(registerTo, rax)
 	}
 
 	return nil
 }
 
+// SaveRegisters prepares registers that are implicitly modified by an instruction.
+// Variables are moved to a different register and temporary values are pushed to the stack.
+// The saved registers need to be restored via RestoreRegisters.
+func (state *State) SaveRegisters(except *register.Register, registers ...*register.Register) ([]*register.Register, error) {
+	var saved []*register.Register
+
+	for _, reg := range registers {
+		if reg == except || reg.IsFree() {
+			continue
+		}
+
+		_, isVariable := reg.User().(*Variable)
+
+		if isVariable {
+			err := state.TryFreeRegister(reg)
+
+			if err != nil {
+				return saved, err
+			}
+
+			continue
+		}
+
+		state.assembler.PushRegister(reg)
+		saved = append(saved, reg)
+	}
+
+	return saved, nil
+}
+
+// RestoreRegisters restores the registers saved by SaveRegisters.
+func (state *State) RestoreRegisters(saved []*register.Register) {
+	for i := len(saved) - 1; i >= 0; i-- {
+		state.assembler.PopRegister(saved[i])
+	}
+}
+
 // TryFreeRegister tries to free a register by moving its current user to another register.
 func (state *State) TryFreeRegister(reg *register.Register) error {
 	if reg.IsFree() {
 		return nil
 	}
 
-	freeRegister := state.registers.General.FindFree()
+	freeRegister := state.FindFreeRegister()
 
 	if freeRegister == nil {
 		return errors.New(errors.ExceededMaxVariables)
@@ -383,7 +803,7 @@ func (state *State) ResolveAccessors(root *expression.Expression) error {
 
 // ResolveAccessor combines the children in the dot operator to a single function name.
 func (state *State) ResolveAccessor(root *expression.Expression) error {
-	if root.Token.Text() != "." || !root.Children[1].IsFunctionCall {
+	if root.Token.Kind != token.Operator || root.Token.Text() != "." || !root.Children[1].IsFunctionCall {
 		return nil
 	}
 
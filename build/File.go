@@ -1,7 +1,7 @@
 package build
 
 import (
-	"fmt"
+	"bytes"
 	"io"
 	"os"
 	"sync/atomic"
@@ -15,6 +15,7 @@ import (
 type File struct {
 	tokens        []token.Token
 	imports       map[string]*Import
+	constants     map[string]int64
 	environment   *Environment
 	pkg           *Package
 	path          string
@@ -25,8 +26,9 @@ type File struct {
 // NewFile creates a new compiler for a single file.
 func NewFile(inputFile string) *File {
 	file := &File{
-		path:    inputFile,
-		imports: make(map[string]*Import),
+		path:      inputFile,
+		imports:   make(map[string]*Import),
+		constants: make(map[string]int64),
 	}
 
 	return file
@@ -97,6 +99,32 @@ func (file *File) Tokenize() error {
 			Expression: string(remaining[:until]),
 		})
 
+		if token.IsNumberStart(remaining) {
+			err = errors.New(&errors.InvalidNumber{
+				Expression: string(token.NumberPrefix(remaining)),
+			})
+		}
+
+		if token.IsCharacterStart(remaining) {
+			err = errors.New(&errors.InvalidCharacterLiteral{
+				Expression: string(token.CharacterPrefix(remaining)),
+			})
+		}
+
+		sequence := token.InvalidEscapeSequence(remaining)
+
+		if sequence != nil {
+			err = errors.New(&errors.InvalidEscapeSequence{
+				Sequence: string(sequence),
+			})
+		}
+
+		// Unterminated comments are reported at the start of the comment
+		if bytes.HasPrefix(remaining, []byte("/*")) {
+			start := token.Token{Kind: token.Comment, Position: processed}
+			return NewError(errors.New(errors.UnterminatedComment), file.path, append(tokens, start), nil)
+		}
+
 		return NewError(err, file.path, tokens, nil)
 	}
 
@@ -113,7 +141,7 @@ func (file *File) Tokens() []token.Token {
 func (file *File) Close() {
 	for _, imp := range file.imports {
 		if atomic.LoadInt32(&imp.Used) == 0 {
-			file.Error = NewError(fmt.Errorf("Import '%s' has never been used", imp.Path), file.path, file.tokens[:imp.Position+1], nil)
+			file.Error = NewError(&errors.UnusedImport{Path: imp.Path}, file.path, file.tokens[:imp.Position+1], nil)
 			break
 		}
 	}
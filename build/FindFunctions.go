@@ -32,7 +32,7 @@ func findFunctions(pkg *Package, env *Environment, functions chan<- *Function, s
 	wg := sync.WaitGroup{}
 
 	directory.Walk(pkg.Path, func(name string) {
-		if !strings.HasSuffix(name, ".q") {
+		if !strings.HasSuffix(name, ".q") || !env.Target.IncludesFile(name) {
 			return
 		}
 
@@ -0,0 +1,100 @@
+package build
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// IsFloatLiteral tells you whether the number token has a decimal point.
+func IsFloatLiteral(t token.Token) bool {
+	return bytes.IndexByte(t.Bytes, '.') != -1
+}
+
+// isFloatOperation tells you whether one of the operand types is a floating-point number.
+func isFloatOperation(left *types.Type, right *types.Type) bool {
+	return left == types.Float64 || right == types.Float64
+}
+
+// CalculateFloatRegister performs a unary operation on a floating-point number.
+func (state *State) CalculateFloatRegister(operation string, register *register.Register) error {
+	if operation != "-" {
+		return errors.New(errors.NotImplemented)
+	}
+
+	// Negation only flips the sign bit
+	temporary := state.FindFreeRegister()
+
+	if temporary == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	state.assembler.MoveRegisterNumber(temporary, 1<<63)
+	state.assembler.XorRegisterRegister(register, temporary)
+	temporary.Free()
+	return nil
+}
+
+// CalculateFloatRegisterNumber performs an operation on a floating-point number and a floating-point literal.
+func (state *State) CalculateFloatRegisterNumber(operation string, register *register.Register, operand *expression.Expression, typ *types.Type) error {
+	if typ != types.Float64 {
+		return errors.New(&errors.InvalidType{Name: types.Float64.String(), Expected: typ.String()})
+	}
+
+	number, err := state.ParseFloat(operand.Token.Text())
+
+	if err != nil {
+		return err
+	}
+
+	temporary := state.FindFreeRegister()
+
+	if temporary == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	temporary.ForceUse(operand)
+	state.assembler.MoveRegisterNumber(temporary, math.Float64bits(number))
+	err = state.CalculateFloatRegisterRegister(operation, register, temporary, typ, types.Float64)
+	temporary.Free()
+	return err
+}
+
+// CalculateFloatRegisterRegister performs an operation on two floating-point numbers.
+// The values are stored in general purpose registers and are moved
+// to SSE registers for the duration of the calculation.
+func (state *State) CalculateFloatRegisterRegister(operation string, registerTo *register.Register, registerFrom *register.Register, typeTo *types.Type, typeFrom *types.Type) error {
+	if typeTo != typeFrom {
+		return errors.New(&errors.InvalidType{Name: typeFrom.String(), Expected: typeTo.String()})
+	}
+
+	a := state.registers.Float[0]
+	b := state.registers.Float[1]
+	state.assembler.MoveFloatRegisterRegister(a, registerTo)
+	state.assembler.MoveFloatRegisterRegister(b, registerFrom)
+
+	switch operation {
+	case "+":
+		state.assembler.AddFloatRegisterRegister(a, b)
+
+	case "-":
+		state.assembler.SubFloatRegisterRegister(a, b)
+
+	case "*":
+		state.assembler.MulFloatRegisterRegister(a, b)
+
+	case "/":
+		state.assembler.DivFloatRegisterRegister(a, b)
+
+	default:
+		return errors.New(errors.NotImplemented)
+	}
+
+	state.assembler.MoveFloatRegisterRegister(registerTo, a)
+	return nil
+}
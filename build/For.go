@@ -19,9 +19,11 @@ type ForState struct {
 type ForLoop struct {
 	labelStart    string
 	labelEnd      string
+	labelContinue string
 	counter       *register.Register
 	limit         *register.Register
 	limitVariable *Variable
+	step          int64
 }
 
 // ForStart handles the start of for loops.
@@ -30,23 +32,76 @@ func (state *State) ForStart(tokens []token.Token) error {
 	state.scopes.Push()
 	expression := tokens[1:]
 
+	if tokens[0].Text() == "repeat" {
+		return state.RepeatStart(expression)
+	}
+
 	rangePos := token.IndexKind(expression, token.Range)
 
 	if rangePos == -1 {
 		return errors.New(errors.MissingRange)
 	}
 
-	operatorPos := token.IndexKind(expression, token.Operator)
+	upperLimit := expression[rangePos+1:]
+	start := expression[:rangePos]
+	step := int64(1)
+	stepPos := token.IndexKind(upperLimit, token.Keyword)
+
+	// The counter is increased by the optional step value after each iteration
+	if stepPos != -1 {
+		if upperLimit[stepPos].Text() != "step" {
+			return errors.New(errors.InvalidExpression)
+		}
+
+		value, isConstant := state.ConstantInt(upperLimit[stepPos+1:])
+
+		if !isConstant {
+			return errors.New(errors.NotConstant)
+		}
+
+		if value <= 0 {
+			return errors.New(errors.InvalidStep)
+		}
+
+		step = value
+		upperLimit = upperLimit[:stepPos]
+	}
+
+	operatorPos := -1
+
+	// Other operators like a unary minus belong to the start value
+	for i, t := range start {
+		if t.Kind == token.Operator && t.Text() == "=" {
+			operatorPos = i
+			break
+		}
+	}
+
+	if operatorPos != -1 {
+		start = expression[operatorPos+1 : rangePos]
+
+		// The loop counter is not accessible via '_'
+		if expression[0].Text() == "_" {
+			operatorPos = -1
+		}
+	}
+
+	// Constant ranges can be checked at compile time
+	startValue, startIsConstant := state.ConstantInt(start)
+	limitValue, limitIsConstant := state.ConstantInt(upperLimit)
+
+	if startIsConstant && limitIsConstant && startValue >= limitValue {
+		return errors.New(&errors.EmptyRange{Start: startValue, Limit: limitValue})
+	}
+
 	var register *register.Register
 
 	if operatorPos == -1 {
-		start := expression[:rangePos]
-
 		if len(start) == 0 {
 			return errors.New(errors.MissingRangeStart)
 		}
 
-		register = state.registers.General.FindFree()
+		register = state.FindFreeRegister()
 
 		if register == nil {
 			return errors.New(errors.ExceededMaxVariables)
@@ -70,21 +125,51 @@ func (state *State) ForStart(tokens []token.Token) error {
 			return err
 		}
 
+		variable.IsLoopCounter = true
 		register = variable.Register()
 	}
 
-	state.forState.counter++
-
-	labelStart := fmt.Sprintf("for_%d", state.forState.counter)
-	labelEnd := fmt.Sprintf("for_%d_end", state.forState.counter)
-
-	upperLimit := expression[rangePos+1:]
-
 	if len(upperLimit) == 0 {
 		return errors.New(errors.MissingRangeLimit)
 	}
 
 	state.tokenCursor++
+	return state.forLoop(register, upperLimit, step)
+}
+
+// RepeatStart handles the start of loops like `repeat 5` which run the body a number of times.
+// The count is evaluated once and the loop counts from 0 up to the count
+// in a register that can't be accessed by the body.
+func (state *State) RepeatStart(count []token.Token) error {
+	if len(count) == 0 {
+		return errors.New(errors.MissingRepeatCount)
+	}
+
+	value, isConstant := state.ConstantInt(count)
+
+	if isConstant && value <= 0 {
+		return errors.New(&errors.EmptyRepeat{Count: value})
+	}
+
+	register := state.FindFreeRegister()
+
+	if register == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	register.ForceUse(token.List(count))
+	state.assembler.MoveRegisterNumber(register, 0)
+	return state.forLoop(register, count, 1)
+}
+
+// forLoop starts a loop that increases the counter register by the step value
+// until it reaches the upper limit.
+func (state *State) forLoop(register *register.Register, upperLimit []token.Token, step int64) error {
+	state.forState.counter++
+
+	labelStart := fmt.Sprintf("for_%d", state.forState.counter)
+	labelEnd := fmt.Sprintf("for_%d_end", state.forState.counter)
+	labelContinue := fmt.Sprintf("for_%d_continue", state.forState.counter)
 
 	// NOTE: Don't ignore type, check it.
 	temporary, _, err := state.CompareRegisterExpression(register, upperLimit, labelStart)
@@ -94,10 +179,12 @@ func (state *State) ForStart(tokens []token.Token) error {
 	}
 
 	forLoop := ForLoop{
-		labelStart: labelStart,
-		labelEnd:   labelEnd,
-		counter:    register,
-		limit:      temporary,
+		labelStart:    labelStart,
+		labelEnd:      labelEnd,
+		labelContinue: labelContinue,
+		counter:       register,
+		limit:         temporary,
+		step:          step,
 	}
 
 	// If we use an existing variable without a temporary register,
@@ -109,8 +196,12 @@ func (state *State) ForStart(tokens []token.Token) error {
 		forLoop.limitVariable = variable
 	}
 
-	state.assembler.JumpIfEqual(labelEnd)
+	// The loop ends when the counter reaches or exceeds the limit.
+	// Step values that skip the limit terminate as well
+	// and ranges with a start value beyond the limit don't execute.
+	state.assembler.JumpIfGreaterOrEqual(labelEnd)
 	state.forState.stack = append(state.forState.stack, forLoop)
+	state.pushBreakLabels(labelEnd, labelContinue)
 	return nil
 }
 
@@ -125,9 +216,17 @@ func (state *State) ForEnd() error {
 	loop := state.forState.stack[len(state.forState.stack)-1]
 	state.forState.stack = state.forState.stack[:len(state.forState.stack)-1]
 
-	state.assembler.IncreaseRegister(loop.counter)
+	state.assembler.AddLabel(loop.labelContinue)
+
+	if loop.step == 1 {
+		state.assembler.IncreaseRegister(loop.counter)
+	} else {
+		state.assembler.AddRegisterNumber(loop.counter, uint64(loop.step))
+	}
+
 	state.assembler.Jump(loop.labelStart)
 	state.assembler.AddLabel(loop.labelEnd)
+	state.popBreakLabels()
 	loop.counter.Free()
 
 	if loop.limit != nil {
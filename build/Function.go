@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/akyoto/q/build/assembler"
+	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/register"
 	"github.com/akyoto/q/build/token"
 	"github.com/akyoto/q/build/types"
@@ -12,24 +13,32 @@ import (
 
 // Function represents a function.
 type Function struct {
-	Name             string
-	Parameters       []*Parameter
-	ReturnTypes      []*types.Type
-	ReturnTypeTokens []token.Token
-	File             *File
-	TokenStart       token.Position
-	TokenEnd         token.Position
-	Error            error
-	NoParameterCheck bool
-	IsBuiltin        bool
-	IsFinished       bool
-	SideEffects      int32
-	CallCount        int32
-	Finished         *sync.Cond
-	FinishedMutex    sync.Mutex
-	assembler        *assembler.Assembler
-	parameterStart   token.Position
-	returnTypeStart  token.Position
+	Name               string
+	Parameters         []*Parameter
+	ReturnTypes        []*types.Type
+	ReturnTypeTokens   []token.Token
+	File               *File
+	TokenStart         token.Position
+	TokenEnd           token.Position
+	Error              error
+	NoParameterCheck   bool
+	IsBuiltin          bool
+	IsIndirect         bool
+	IsFinished         bool
+	SideEffects        int32
+	CallCount          int32
+	AddressTaken       int32
+	LiveVariables      int
+	Inline             Inlining
+	Finished           *sync.Cond
+	FinishedMutex      sync.Mutex
+	assembler          *assembler.Assembler
+	calls              []*Function
+	references         []*Function
+	cycle              int
+	annotationPosition token.Position
+	parameterStart     token.Position
+	returnTypeStart    token.Position
 }
 
 // Tokens returns all tokens within the function body (excluding the braces '{' and '}').
@@ -38,6 +47,7 @@ func (function *Function) Tokens() []token.Token {
 }
 
 // NewError creates an error inside the function.
+// Errors that refer to a specific token are reported at that token instead of the given position.
 func (function *Function) NewError(position token.Position, err error) error {
 	metaError, hasMetaData := err.(*Error)
 
@@ -45,12 +55,85 @@ func (function *Function) NewError(position token.Position, err error) error {
 		return metaError
 	}
 
+	offset, hasOffset := errors.Offset(err)
+
+	if hasOffset {
+		for index, t := range function.Tokens() {
+			if t.Position == offset {
+				position = index
+				break
+			}
+		}
+	}
+
 	return NewError(err, function.File.path, function.File.tokens[:function.TokenStart+position+1], function)
 }
 
-// CanInline returns true if the function call can be inlined.
-func (function *Function) CanInline() bool {
-	return len(function.assembler.Instructions) <= 4
+// ResolveSignature resolves the parameter and return types.
+// Signatures are resolved before any function is compiled
+// so that calls don't depend on the order of function definitions.
+func (function *Function) ResolveSignature() error {
+	file := function.File
+
+	for _, parameter := range function.Parameters {
+		typeName := TypeNameFromTokens(parameter.TypeTokens)
+		parameter.Type = file.Type(typeName)
+
+		if parameter.Type == nil {
+			return NewError(errors.New(file.environment.UnknownTypeError(typeName)), file.path, file.tokens[:parameter.Position+2], function)
+		}
+	}
+
+	if function.Name == "main" && !function.hasMainParameters() {
+		return NewError(errors.New(errors.InvalidMainParameters), file.path, file.tokens[:function.Parameters[0].Position+1], function)
+	}
+
+	if len(function.ReturnTypeTokens) > 0 {
+		typeName := TypeNameFromTokens(function.ReturnTypeTokens)
+		typ := file.Type(typeName)
+
+		if typ == nil {
+			err := errors.New(file.environment.UnknownTypeError(typeName))
+			return NewError(err, file.path, file.tokens[:function.returnTypeStart+1], function)
+		}
+
+		function.ReturnTypes = append(function.ReturnTypes, typ)
+	}
+
+	return nil
+}
+
+// hasMainParameters tells you whether the parameters can be passed to 'main' at program entry.
+// The entry code passes either nothing or the argument count and the pointer to the argument pointers.
+func (function *Function) hasMainParameters() bool {
+	switch len(function.Parameters) {
+	case 0:
+		return true
+
+	case 2:
+		return function.Parameters[0].Type == types.Int && function.Parameters[1].Type == types.Pointer
+
+	default:
+		return false
+	}
+}
+
+// SourcePosition returns the line and column of the token at the given position.
+func (function *Function) SourcePosition(position token.Position) (int, int) {
+	tokens := function.File.tokens[:function.TokenStart+position+1]
+	line := 1
+	lineStart := -1
+
+	for _, t := range tokens[:len(tokens)-1] {
+		count, last := t.LineBreaks()
+
+		if count > 0 {
+			line += count
+			lineStart = last
+		}
+	}
+
+	return line, int(tokens[len(tokens)-1].Position) - lineStart
 }
 
 // InlineInto adds the assembler instructions to another function.
@@ -62,6 +145,37 @@ func (function *Function) InlineInto(other *Function) {
 	other.assembler.Instructions = append(other.assembler.Instructions, inlinedInstructions...)
 }
 
+// callees returns the functions that might be called by this function.
+// Calls are resolved like in CallExpression, qualified calls like 'sys.write'
+// refer to a package function and unqualified calls to a function without a package prefix.
+// Identifiers without a call can refer to the address of a function.
+func (function *Function) callees(byName map[string][]*Function) []*Function {
+	var callees []*Function
+	tokens := function.Tokens()
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != token.Identifier {
+			continue
+		}
+
+		name := tokens[i].Text()
+		fullName := name
+		isQualified := i >= 2 && tokens[i-1].Kind == token.Operator && tokens[i-1].Text() == "." && tokens[i-2].Kind == token.Identifier
+
+		if isQualified {
+			fullName = tokens[i-2].Text() + "." + name
+		}
+
+		for _, callee := range byName[name] {
+			if UnpolymorphName(callee.Name) == fullName {
+				callees = append(callees, callee)
+			}
+		}
+	}
+
+	return callees
+}
+
 // HasReturnValue returns true if the function has a return value.
 func (function *Function) HasReturnValue() bool {
 	return len(function.ReturnTypes) > 0
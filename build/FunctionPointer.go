@@ -0,0 +1,77 @@
+package build
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/types"
+)
+
+// FunctionAddress loads the address of the function into the register.
+// Functions whose address has been taken are always part of the final code
+// because they can be called from anywhere, even if all direct calls were inlined.
+func (state *State) FunctionAddress(function *Function, register *register.Register) *types.Type {
+	atomic.AddInt32(&function.CallCount, 1)
+	atomic.AddInt32(&function.AddressTaken, 1)
+	state.function.references = append(state.function.references, function)
+	state.assembler.MoveRegisterFunctionAddress(register, function.Name)
+	return types.Function
+}
+
+// ReferencedFunction returns the function with the given name
+// if the name refers to a function instead of a variable.
+func (state *State) ReferencedFunction(name string) *Function {
+	if state.scopes.Get(name) != nil {
+		return nil
+	}
+
+	return state.environment.Functions[name]
+}
+
+// KeepFunctionPointer keeps the variable holding the function pointer alive
+// while the parameters are evaluated because calls inside of the parameters
+// would otherwise be allowed to overwrite it. The returned function restores the lifetime.
+func (state *State) KeepFunctionPointer(expr *expression.Expression) func() {
+	variable := state.scopes.Get(expr.Token.Text())
+	aliveUntil := variable.AliveUntil
+	end := state.InstructionEndPosition()
+
+	if aliveUntil >= end {
+		return func() {}
+	}
+
+	for _, parameter := range expr.Children {
+		if parameter.ContainsCall() {
+			variable.AliveUntil = end
+			break
+		}
+	}
+
+	return func() {
+		variable.AliveUntil = aliveUntil
+	}
+}
+
+// IndirectFunction returns the description of a call through the function pointer in the variable.
+// The called function is unknown at compile time, therefore it is assumed to have side effects
+// and to modify every register. The parameters are passed like in a direct call
+// and the return value is an integer.
+func IndirectFunction(variable *Variable, parameterCount int) *Function {
+	function := &Function{
+		Name:             variable.Name,
+		ReturnTypes:      []*types.Type{types.Int},
+		NoParameterCheck: true,
+		IsIndirect:       true,
+	}
+
+	for i := 0; i < parameterCount; i++ {
+		function.Parameters = append(function.Parameters, &Parameter{
+			Name: fmt.Sprintf("%s.%d", variable.Name, i),
+			Type: types.Int,
+		})
+	}
+
+	return function
+}
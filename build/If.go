@@ -4,27 +4,172 @@ import (
 	"fmt"
 
 	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/instruction"
 	"github.com/akyoto/q/build/operators"
 	"github.com/akyoto/q/build/token"
 )
 
 // IfState handles the state of branch compilation.
 type IfState struct {
-	counter int
-	labels  []string
+	counter      int
+	elseCounter  int
+	stack        []Branch
+	labelElseEnd string
+}
+
+// Branch represents an if or else block.
+type Branch struct {
+	labelEnd     string
+	labelElseEnd string
 }
 
 // IfStart handles the start of if conditions.
 func (state *State) IfStart(tokens []token.Token) error {
 	state.Skip(token.Keyword)
 	state.scopes.Push()
-	condition := tokens[1:]
+	return state.branch(tokens[1:], "")
+}
+
+// ElseStart handles the start of else and else if blocks.
+func (state *State) ElseStart(tokens []token.Token) error {
+	labelElseEnd := state.ifState.labelElseEnd
+
+	if labelElseEnd == "" {
+		return errors.New(errors.MissingIf)
+	}
+
+	state.ifState.labelElseEnd = ""
+	state.Skip(token.Keyword)
+	state.scopes.Push()
+
+	// else if
+	if len(tokens) > 1 && tokens[1].Kind == token.Keyword && tokens[1].Text() == "if" {
+		state.Skip(token.Keyword)
+		return state.branch(tokens[2:], labelElseEnd)
+	}
+
+	// else
+	if len(tokens) > 1 {
+		return errors.New(errors.InvalidExpression)
+	}
+
+	state.ifState.stack = append(state.ifState.stack, Branch{labelElseEnd: labelElseEnd})
+	return nil
+}
 
+// branch adds a conditional block to the stack.
+// The else end label is shared by all blocks of an if-else chain.
+// Conditions that are known at compile time don't need a comparison,
+// the code of the branches that are never taken is removed later.
+func (state *State) branch(condition []token.Token, labelElseEnd string) error {
 	state.ifState.counter++
-	elseLabel := fmt.Sprintf("if_%d_end", state.ifState.counter)
-	state.ifState.labels = append(state.ifState.labels, elseLabel)
+	labelEnd := fmt.Sprintf("if_%d_end", state.ifState.counter)
+
+	state.ifState.stack = append(state.ifState.stack, Branch{
+		labelEnd:     labelEnd,
+		labelElseEnd: labelElseEnd,
+	})
+
+	value, isConstant := constantCondition(condition)
+
+	if !isConstant {
+		return state.Condition(condition, labelEnd)
+	}
+
+	if !value {
+		state.assembler.Jump(labelEnd)
+	}
+
+	return nil
+}
+
+// constantCondition calculates the value of a condition that only consists of
+// boolean literals and comparisons of constant integer expressions.
+// It reports false if the condition needs to be evaluated at run time.
+func constantCondition(condition []token.Token) (bool, bool) {
+	if len(condition) == 0 {
+		return false, false
+	}
+
+	expr, err := expression.FromTokens(condition)
+
+	if err != nil {
+		return false, false
+	}
 
-	return state.Condition(condition, elseLabel)
+	defer expr.Close()
+	return constantConditionExpression(expr)
+}
+
+// constantConditionExpression calculates the value of a condition expression tree.
+func constantConditionExpression(expr *expression.Expression) (bool, bool) {
+	if expr.IsLeaf() {
+		value, isBool := BoolLiteral(expr.Token)
+		return value == 1, isBool
+	}
+
+	if expr.IsFunctionCall || expr.Token.Kind != token.Operator || len(expr.Children) != 2 {
+		return false, false
+	}
+
+	operator := expr.Token.Text()
+
+	if operator == "&&" || operator == "||" {
+		left, isConstant := constantConditionExpression(expr.Children[0])
+
+		if !isConstant {
+			return false, false
+		}
+
+		right, isConstant := constantConditionExpression(expr.Children[1])
+
+		if !isConstant {
+			return false, false
+		}
+
+		if operator == "&&" {
+			return left && right, true
+		}
+
+		return left || right, true
+	}
+
+	if operators.All[operator].Kind != operators.Comparison {
+		return false, false
+	}
+
+	left, err := evaluateConstantExpression(expr.Children[0])
+
+	if err != nil {
+		return false, false
+	}
+
+	right, err := evaluateConstantExpression(expr.Children[1])
+
+	if err != nil {
+		return false, false
+	}
+
+	switch operator {
+	case "==":
+		return left == right, true
+
+	case "!=":
+		return left != right, true
+
+	case "<":
+		return left < right, true
+
+	case "<=":
+		return left <= right, true
+
+	case ">":
+		return left > right, true
+
+	default:
+		return left >= right, true
+	}
 }
 
 // Condition encodes a compare instruction for the given condition.
@@ -32,14 +177,18 @@ func (state *State) Condition(condition []token.Token, elseLabel string) error {
 	operatorPos := -1
 
 	for i, t := range condition {
-		if t.Kind == token.Operator && operators.All[t.Text()].Kind == operators.Comparison {
+		// Logical operations are evaluated as a whole
+		if t.Kind == token.Operator && (t.Text() == "&&" || t.Text() == "||") {
+			return state.BoolCondition(condition, elseLabel)
+		}
+
+		if operatorPos == -1 && t.Kind == token.Operator && operators.All[t.Text()].Kind == operators.Comparison {
 			operatorPos = i
-			break
 		}
 	}
 
 	if operatorPos == -1 {
-		return errors.New(errors.InvalidExpression)
+		return state.BoolCondition(condition, elseLabel)
 	}
 
 	left := condition[:operatorPos]
@@ -64,6 +213,9 @@ func (state *State) Condition(condition []token.Token, elseLabel string) error {
 		return errors.New(&errors.CantInferType{Expression: fmt.Sprint(right)})
 	}
 
+	leftType = literalType(left, leftType, rightType)
+	rightType = literalType(right, rightType, leftType)
+
 	if leftType != rightType {
 		return errors.New(&errors.InvalidType{Name: rightType.String(), Expected: leftType.String()})
 	}
@@ -72,13 +224,40 @@ func (state *State) Condition(condition []token.Token, elseLabel string) error {
 		temporary.Free()
 	}
 
+	_, isVariable := leftRegister.User().(*Variable)
+
+	if !isVariable {
+		leftRegister.Free()
+	}
+
 	operator := condition[operatorPos].Text()
-	state.IfFalseJump(operator, elseLabel)
+	state.IfFalseJump(operator, elseLabel, isUnsignedOperation(leftType, rightType))
 	return nil
 }
 
 // IfFalseJump jumps if the previous compare statement was false.
-func (state *State) IfFalseJump(operator string, label string) {
+// Unsigned operands need to be compared via the below and above conditions.
+func (state *State) IfFalseJump(operator string, label string, unsigned bool) {
+	if unsigned {
+		switch operator {
+		case ">=":
+			state.assembler.JumpIfBelow(label)
+			return
+
+		case ">":
+			state.assembler.JumpIfBelowOrEqual(label)
+			return
+
+		case "<=":
+			state.assembler.JumpIfAbove(label)
+			return
+
+		case "<":
+			state.assembler.JumpIfAboveOrEqual(label)
+			return
+		}
+	}
+
 	switch operator {
 	case ">=":
 		state.assembler.JumpIfLess(label)
@@ -100,7 +279,7 @@ func (state *State) IfFalseJump(operator string, label string) {
 	}
 }
 
-// IfEnd handles the end of if conditions.
+// IfEnd handles the end of if conditions and else blocks.
 func (state *State) IfEnd() error {
 	err := state.PopScope(false)
 
@@ -108,8 +287,35 @@ func (state *State) IfEnd() error {
 		return err
 	}
 
-	label := state.ifState.labels[len(state.ifState.labels)-1]
-	state.ifState.labels = state.ifState.labels[:len(state.ifState.labels)-1]
-	state.assembler.AddLabel(label)
+	branch := state.ifState.stack[len(state.ifState.stack)-1]
+	state.ifState.stack = state.ifState.stack[:len(state.ifState.stack)-1]
+	nextIndex := state.instrCursor + 1
+
+	// If the block is followed by an else block,
+	// the true branch needs to jump over it.
+	if nextIndex < len(state.instructions) && state.instructions[nextIndex].Kind == instruction.ElseStart {
+		if branch.labelEnd == "" {
+			return errors.New(errors.MissingIf)
+		}
+
+		if branch.labelElseEnd == "" {
+			state.ifState.elseCounter++
+			branch.labelElseEnd = fmt.Sprintf("else_%d_end", state.ifState.elseCounter)
+		}
+
+		state.assembler.Jump(branch.labelElseEnd)
+		state.assembler.AddLabel(branch.labelEnd)
+		state.ifState.labelElseEnd = branch.labelElseEnd
+		return nil
+	}
+
+	if branch.labelEnd != "" {
+		state.assembler.AddLabel(branch.labelEnd)
+	}
+
+	if branch.labelElseEnd != "" {
+		state.assembler.AddLabel(branch.labelElseEnd)
+	}
+
 	return nil
 }
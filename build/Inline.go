@@ -0,0 +1,77 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/assembler/instructions"
+	"github.com/akyoto/q/build/assembler/mnemonics"
+	"github.com/akyoto/q/build/errors"
+)
+
+// DefaultInlineThreshold is the maximum number of instructions
+// in the body of a function that is inlined automatically.
+const DefaultInlineThreshold = 2
+
+// Inlining decides whether the calls to a function are inlined.
+type Inlining uint8
+
+const (
+	// InlineAuto inlines functions that don't exceed the inline threshold.
+	InlineAuto Inlining = iota
+
+	// InlineAlways inlines functions regardless of their size.
+	InlineAlways
+
+	// InlineNever keeps the calls to a function.
+	InlineNever
+)
+
+// annotations maps the function annotations to their inlining decision.
+var annotations = map[string]Inlining{
+	"@inline":   InlineAlways,
+	"@noinline": InlineNever,
+}
+
+// CanInline returns true if the function call can be inlined.
+// Functions ending with a tail call can't be inlined.
+// Recursive functions can't be inlined because they might not be compiled yet.
+// Labels and returns in the body would be duplicated and the string data
+// belongs to the assembler of the function, therefore these functions are never inlined.
+func (function *Function) CanInline() bool {
+	if function.cycle != 0 || function.Inline == InlineNever {
+		return false
+	}
+
+	all := function.assembler.Instructions
+
+	if all[len(all)-1].Name() != mnemonics.RET {
+		return false
+	}
+
+	body := all[1 : len(all)-1]
+
+	if function.Inline == InlineAuto && len(body) > function.File.environment.InlineThreshold {
+		return false
+	}
+
+	for _, instr := range body {
+		switch instr.(type) {
+		case *instructions.AddLabel, *instructions.RegisterAddress:
+			return false
+		}
+
+		if instr.Name() == mnemonics.RET {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkForcedInline reports recursive functions that are annotated with '@inline'
+// because their inlining would never end.
+func (function *Function) checkForcedInline() error {
+	if function.Inline != InlineAlways || function.cycle == 0 {
+		return nil
+	}
+
+	return NewError(errors.New(errors.RecursiveInline), function.File.path, function.File.tokens[:function.annotationPosition+1], function)
+}
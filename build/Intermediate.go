@@ -0,0 +1,44 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IntermediateIndexFile lists the functions written to the intermediate directory.
+const IntermediateIndexFile = "offsets.txt"
+
+// intermediate is the machine code of a single function before it's merged into the executable.
+type intermediate struct {
+	name   string
+	offset uint32
+	code   []byte
+}
+
+// writeIntermediates writes the machine code of each function to a separate file in the directory.
+// Calls and jumps to other functions are not resolved yet, therefore the files only change
+// when the function itself changes. The index file contains the offset of each function
+// in the code section of the executable, its size and its name.
+func writeIntermediates(directory string, intermediates []intermediate) error {
+	err := os.MkdirAll(directory, 0755)
+
+	if err != nil {
+		return err
+	}
+
+	index := strings.Builder{}
+
+	for _, function := range intermediates {
+		err = os.WriteFile(filepath.Join(directory, function.name+".bin"), function.code, 0644)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&index, "%08x %6d %s\n", function.offset, len(function.code), function.name)
+	}
+
+	return os.WriteFile(filepath.Join(directory, IntermediateIndexFile), []byte(index.String()), 0644)
+}
@@ -0,0 +1,68 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/assembler"
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// Len stores the length of a text in the expression register.
+// Every text is prefixed by its length so it can be loaded from memory.
+func (state *State) Len(expr *expression.Expression) error {
+	parameter := expr.Children[0]
+	expr.Type = types.Int
+
+	// The length of text literals is known at compile time
+	if parameter.IsLeaf() && parameter.Token.Kind == token.Text {
+		if expr.Register != nil {
+			state.assembler.MoveRegisterNumber(expr.Register, uint64(len(parameter.Token.Text())))
+		}
+
+		return nil
+	}
+
+	var text *register.Register
+
+	if parameter.IsLeaf() && parameter.Token.Kind == token.Identifier {
+		variable := state.scopes.Get(parameter.Token.Text())
+
+		if variable == nil {
+			return errors.New(state.UnknownVariableError(parameter.Token.Text()))
+		}
+
+		state.UseVariable(variable)
+		parameter.Type = variable.Type
+		text = variable.Register()
+	} else {
+		text = state.FindFreeRegister()
+
+		if text == nil {
+			return errors.New(errors.ExceededMaxVariables)
+		}
+
+		text.ForceUse(parameter)
+		typ, err := state.ExpressionToRegister(parameter, text)
+		defer text.Free()
+
+		if err != nil {
+			return err
+		}
+
+		parameter.Type = typ
+	}
+
+	// Texts have the same type as integers, therefore the value needs to be checked
+	if !state.isTextExpression(parameter) {
+		return errors.New(&errors.InvalidType{Name: parameter.Type.String(), Expected: textTypeName})
+	}
+
+	if expr.Register != nil {
+		offset := -assembler.StringLengthSize
+		state.assembler.LoadRegister(expr.Register, text, byte(offset), assembler.StringLengthSize)
+	}
+
+	return nil
+}
@@ -36,16 +36,40 @@ func (state *State) KillVariables(from int, until int) {
 			return
 		}
 
-		if variable.KeepAlive > 0 {
+		if variable.KeepAlive > 0 || variable.IsConstant {
 			return
 		}
 
 		// fmt.Println(variable, "died at", state.tokens[:variable.AliveUntil+1])
-		variable.Register().Free()
+		// Spilled variables don't occupy a register
+		if variable.Register() != nil {
+			variable.Register().Free()
+		}
+
 		delete(state.identifierLifeTime, variable.Name)
 	})
 }
 
+// CountLiveVariables updates the peak number of variables
+// that are alive at the given token position.
+func (state *State) CountLiveVariables(position token.Position) {
+	count := 0
+
+	state.scopes.Each(func(variable *Variable) {
+		if variable.IsConstant {
+			return
+		}
+
+		if variable.AliveUntil >= position || variable.KeepAlive > 0 {
+			count++
+		}
+	})
+
+	if count > state.function.LiveVariables {
+		state.function.LiveVariables = count
+	}
+}
+
 // InstructionEndPosition returns the token position of the next instruction.
 func (state *State) InstructionEndPosition() token.Position {
 	instr := state.instructions[state.instrCursor]
@@ -0,0 +1,94 @@
+package build
+
+import (
+	"math"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// Load reads the number with the given byte count at the pointer plus the offset
+// and stores it in the expression register.
+// Numbers with less than 8 bytes are zero-extended unless a signed type is given.
+// In that case only the lower bytes that fit into the type are loaded and sign-extended,
+// which results in the same number as a cast of the loaded number to that type.
+func (state *State) Load(expr *expression.Expression, signed *types.Type) error {
+	parameters := expr.Children
+	offset, err := evaluateConstantExpression(parameters[1])
+
+	if err != nil {
+		return err
+	}
+
+	byteCount, err := evaluateConstantExpression(parameters[2])
+
+	if err != nil {
+		return err
+	}
+
+	if byteCount != 1 && byteCount != 2 && byteCount != 4 && byteCount != 8 {
+		return errors.New(errors.InvalidByteCount)
+	}
+
+	// The offset is encoded as a signed 8-bit displacement
+	if offset < math.MinInt8 || offset > math.MaxInt8 {
+		return errors.New(errors.NotImplemented)
+	}
+
+	pointer := parameters[0]
+	expr.Type = types.Int
+	var address *register.Register
+
+	if pointer.IsLeaf() && pointer.Token.Kind == token.Identifier {
+		variable := state.scopes.Get(pointer.Token.Text())
+
+		if variable == nil {
+			return errors.New(state.UnknownVariableError(pointer.Token.Text()))
+		}
+
+		state.UseVariable(variable)
+		pointer.Type = variable.Type
+		address = variable.Register()
+	} else {
+		address = state.FindFreeRegister()
+
+		if address == nil {
+			return errors.New(errors.ExceededMaxVariables)
+		}
+
+		address.ForceUse(pointer)
+		typ, err := state.ExpressionToRegister(pointer, address)
+		defer address.Free()
+
+		if err != nil {
+			return err
+		}
+
+		pointer.Type = typ
+	}
+
+	if pointer.Type != types.Pointer {
+		return errors.New(&errors.InvalidType{Name: pointer.Type.String(), Expected: types.Pointer.String(), ParameterName: "ptr"})
+	}
+
+	if expr.Register == nil {
+		return nil
+	}
+
+	if signed != nil && int64(signed.Size) <= byteCount {
+		state.assembler.LoadSignExtend(expr.Register, address, byte(offset), byte(signed.Size))
+		return nil
+	}
+
+	if byteCount < 4 {
+		state.assembler.LoadZeroExtend(expr.Register, address, byte(offset), byte(byteCount))
+		return nil
+	}
+
+	// Loading 4 bytes clears the upper half of the register
+	state.assembler.LoadRegister(expr.Register, address, byte(offset), byte(byteCount))
+	return nil
+}
@@ -0,0 +1,56 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/types"
+)
+
+// LogicalExpression evaluates a `&&` or `||` operation and stores the boolean result in the expression register.
+// The right operand is skipped if the left operand already determines the result.
+func (state *State) LogicalExpression(expr *expression.Expression) error {
+	state.logicalCounter++
+	labelEnd := fmt.Sprintf("logical_%d_end", state.logicalCounter)
+
+	err := state.logicalOperand(expr.Children[0], expr.Register)
+
+	if err != nil {
+		return err
+	}
+
+	state.assembler.CompareRegisterNumber(expr.Register, 0)
+
+	if expr.Token.Text() == "&&" {
+		state.assembler.JumpIfEqual(labelEnd)
+	} else {
+		state.assembler.JumpIfNotEqual(labelEnd)
+	}
+
+	err = state.logicalOperand(expr.Children[1], expr.Register)
+
+	if err != nil {
+		return err
+	}
+
+	state.assembler.AddLabel(labelEnd)
+	expr.Type = types.Bool
+	return nil
+}
+
+// logicalOperand evaluates an operand of a logical operation and ensures that it is a boolean.
+func (state *State) logicalOperand(operand *expression.Expression, register *register.Register) error {
+	typ, err := state.ExpressionToRegister(operand, register)
+
+	if err != nil {
+		return err
+	}
+
+	if typ != types.Bool {
+		return errors.New(&errors.InvalidType{Name: typ.String(), Expected: types.Bool.String()})
+	}
+
+	return nil
+}
@@ -15,6 +15,7 @@ func (state *State) LoopStart() error {
 	label := fmt.Sprintf("loop_%d", state.loopState.counter)
 	state.loopState.labels = append(state.loopState.labels, label)
 	state.assembler.AddLabel(label)
+	state.pushBreakLabels(label+"_end", label)
 	return nil
 }
 
@@ -28,6 +29,8 @@ func (state *State) LoopEnd() error {
 
 	label := state.loopState.labels[len(state.loopState.labels)-1]
 	state.assembler.Jump(label)
+	state.assembler.AddLabel(label + "_end")
+	state.popBreakLabels()
 	state.loopState.labels = state.loopState.labels[:len(state.loopState.labels)-1]
 	return nil
 }
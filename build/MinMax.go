@@ -0,0 +1,67 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// MinMax stores the smaller or the larger of two integers in the expression register.
+// The comparison is followed by a conditional move so that no branch is needed.
+func (state *State) MinMax(expr *expression.Expression, function *Function) error {
+	operands := make([]*register.Register, len(expr.Children))
+
+	// Both operands are evaluated in temporary registers
+	// because the first one could overwrite a variable
+	// that is still needed for the second one.
+	for i, parameter := range expr.Children {
+		operand := state.FindFreeRegister()
+
+		if operand == nil {
+			return errors.New(errors.ExceededMaxVariables)
+		}
+
+		operand.ForceUse(parameter)
+		defer operand.Free()
+		operands[i] = operand
+
+		typ, err := state.ExpressionToRegister(parameter, operand)
+
+		if err != nil {
+			return err
+		}
+
+		if parameter.IsLeaf() {
+			typ = literalType([]token.Token{parameter.Token}, typ, function.Parameters[i].Type)
+		}
+
+		if typ != function.Parameters[i].Type {
+			return errors.New(&errors.InvalidType{
+				Name:          typ.String(),
+				Expected:      function.Parameters[i].Type.String(),
+				ParameterName: function.Parameters[i].Name,
+			})
+		}
+	}
+
+	expr.Type = types.Int
+
+	if expr.Register == nil {
+		return nil
+	}
+
+	state.assembler.CompareRegisterRegister(operands[0], operands[1])
+
+	switch function.Name {
+	case BuiltinMin:
+		state.assembler.MoveIfGreater(operands[0], operands[1])
+
+	case BuiltinMax:
+		state.assembler.MoveIfLess(operands[0], operands[1])
+	}
+
+	state.assembler.MoveRegisterRegister(expr.Register, operands[0])
+	return nil
+}
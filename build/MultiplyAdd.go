@@ -0,0 +1,80 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// MultiplyAdd calculates expressions like `a * 4 + c` with a single address calculation.
+// x86 has no fused multiply-add for integers, but `lea` can add a base register
+// to an index register scaled by 1, 2, 4 or 8. This avoids the copy of the left operand
+// and leaves the flags untouched, therefore it's disabled when overflow checks are enabled.
+// It returns false if the expression doesn't match the pattern.
+func (state *State) MultiplyAdd(root *expression.Expression, finalRegister *register.Register) (*types.Type, bool) {
+	if state.checkOverflow || root.IsFunctionCall || root.Token.Text() != "+" || len(root.Children) != 2 {
+		return nil, false
+	}
+
+	product := root.Children[0]
+	addend := root.Children[1]
+
+	if product.Token.Text() != "*" {
+		product, addend = addend, product
+	}
+
+	if product.IsFunctionCall || product.Token.Text() != "*" || len(product.Children) != 2 || !addend.IsLeaf() {
+		return nil, false
+	}
+
+	factor := product.Children[0]
+	scale := product.Children[1]
+
+	if factor.Token.Kind == token.Number {
+		factor, scale = scale, factor
+	}
+
+	if !factor.IsLeaf() || !scale.IsLeaf() || scale.Token.Kind != token.Number {
+		return nil, false
+	}
+
+	number, err := state.ParseInt(scale.Token.Text())
+
+	if err != nil {
+		return nil, false
+	}
+
+	switch number {
+	case 1, 2, 4, 8:
+	default:
+		return nil, false
+	}
+
+	index := state.integerVariable(factor.Token)
+	base := state.integerVariable(addend.Token)
+
+	if index == nil || base == nil {
+		return nil, false
+	}
+
+	state.UseVariable(index)
+	state.UseVariable(base)
+	state.assembler.LoadAddress(finalRegister, base.Register(), index.Register(), byte(number), 0)
+	return types.Int, true
+}
+
+// integerVariable returns the 64-bit integer variable the token refers to or nil.
+func (state *State) integerVariable(operand token.Token) *Variable {
+	if operand.Kind != token.Identifier {
+		return nil
+	}
+
+	variable := state.scopes.Get(operand.Text())
+
+	if variable == nil || variable.Type != types.Int {
+		return nil
+	}
+
+	return variable
+}
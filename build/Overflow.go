@@ -0,0 +1,32 @@
+package build
+
+import (
+	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/syscall"
+)
+
+const (
+	// OverflowLabel is the label of the overflow handler shared by all functions.
+	OverflowLabel = "overflow.trap"
+
+	// OverflowExitCode is the exit code of a program that encountered an integer overflow.
+	OverflowExitCode = 101
+)
+
+// CheckOverflow jumps to the overflow handler if the last arithmetic operation overflowed.
+// The check is only inserted when overflow checks are enabled.
+func (state *State) CheckOverflow() {
+	if !state.checkOverflow {
+		return
+	}
+
+	state.assembler.JumpIfOverflow(OverflowLabel)
+}
+
+// addOverflowHandler adds the overflow handler which exits the program.
+func (build *Build) addOverflowHandler(code *asm.Assembler) {
+	code.AddLabel(OverflowLabel)
+	code.MoveRegisterNumber(syscall.Registers[0], build.Target.SyscallExit)
+	code.MoveRegisterNumber(syscall.Registers[1], OverflowExitCode)
+	code.Syscall()
+}
@@ -0,0 +1,494 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/akyoto/q/build/assembler"
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+const (
+	// printBufferSize is large enough for the sign, 19 integer digits,
+	// the decimal point, the fractional digits and the newline.
+	printBufferSize = 32
+
+	// printFloatPrecision is the maximum number of fractional digits.
+	printFloatPrecision = 1000000
+
+	// printFloatInfinity contains the bits of an infinite number.
+	// Numbers whose absolute value has larger bits are not a number.
+	printFloatInfinity = 0x7ff0000000000000
+
+	// printFloatIntegerLimit contains the bits of 2^63,
+	// the smallest number whose integer part doesn't fit into a register.
+	printFloatIntegerLimit = 0x43e0000000000000
+
+	// printFloatTen contains the bits of the number 10.
+	printFloatTen = 0x4024000000000000
+)
+
+// printRegisters contains the registers used to convert a number to text.
+type printRegisters struct {
+	number    *register.Register
+	remainder *register.Register
+	buffer    *register.Register
+	counter   *register.Register
+	sign      *register.Register
+	backup    *register.Register
+	ten       *register.Register
+	saved     []*register.Register
+}
+
+// Print prints the parameters one after another, optionally followed by a newline.
+// Consecutive text literals are combined into a single write.
+func (state *State) Print(function *Function, parameters []*expression.Expression, newline bool) error {
+	if len(parameters) == 0 {
+		return errors.New(&errors.ParameterCount{
+			FunctionName:  function.Name,
+			CountGiven:    0,
+			CountRequired: 1,
+		})
+	}
+
+	text := ""
+
+	for i, parameter := range parameters {
+		isLast := i == len(parameters)-1
+
+		if parameter.IsLeaf() && parameter.Token.Kind == token.Text {
+			text += parameter.Token.Text()
+
+			if isLast && newline {
+				state.printLn(text)
+			} else if isLast {
+				state.printText(text)
+			}
+
+			continue
+		}
+
+		if text != "" {
+			state.printText(text)
+			text = ""
+		}
+
+		err := state.PrintExpression(function, parameter, isLast && newline)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PrintExpression prints the value of an expression that is not a text literal
+// and adds a newline if requested.
+// Texts have the same type as integers, therefore the value decides how it is printed.
+func (state *State) PrintExpression(function *Function, parameter *expression.Expression, newline bool) error {
+	isText := state.isTextExpression(parameter)
+	value, err := state.printValue(parameter)
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case isText:
+		state.printTextRegister(value, newline)
+
+	case parameter.Type == types.Float64:
+		state.printFloat(value, newline)
+
+	case parameter.Type == types.Bool:
+		state.printBool(value, newline)
+
+	case isInteger(parameter.Type):
+		state.printInt(value, parameter.Type, newline)
+
+	default:
+		return errors.New(&errors.InvalidPrintParameter{FunctionName: function.Name, Parameter: parameter.String()})
+	}
+
+	return nil
+}
+
+// PrintHex prints an integer in hexadecimal notation with the '0x' prefix followed by a newline.
+// Negative numbers are shown in two's complement.
+func (state *State) PrintHex(parameter *expression.Expression) error {
+	value, err := state.printValue(parameter)
+
+	if err != nil {
+		return err
+	}
+
+	if parameter.Type != types.Int && !parameter.Type.Unsigned {
+		return errors.New(&errors.InvalidType{Name: parameter.Type.String(), Expected: types.Int.String()})
+	}
+
+	state.printHex(value)
+	return nil
+}
+
+// printValue returns the register that contains the value of the expression.
+// Temporary registers are only valid until the current instruction ends.
+func (state *State) printValue(parameter *expression.Expression) (*register.Register, error) {
+	if parameter.IsLeaf() && parameter.Token.Kind == token.Identifier {
+		variable := state.scopes.Get(parameter.Token.Text())
+
+		if variable == nil {
+			return nil, errors.New(state.UnknownVariableError(parameter.Token.Text()))
+		}
+
+		state.UseVariable(variable)
+		parameter.Type = variable.Type
+		return variable.Register(), nil
+	}
+
+	value := state.FindFreeRegister()
+
+	if value == nil {
+		return nil, errors.New(errors.ExceededMaxVariables)
+	}
+
+	value.ForceUse(parameter)
+	typ, err := state.ExpressionToRegister(parameter, value)
+	value.Free()
+	parameter.Type = typ
+	return value, err
+}
+
+// printTextRegister adds instructions to print the text in the register.
+// The length is stored in front of the text.
+func (state *State) printTextRegister(value *register.Register, newline bool) {
+	rcx := state.registers.All.ByName("rcx")
+	r11 := state.registers.All.ByName("r11")
+	saved := register.List{state.registers.Syscall[0], state.registers.Syscall[1], state.registers.Syscall[2], state.registers.Syscall[3], rcx, r11}.InUse()
+
+	for _, reg := range saved {
+		state.assembler.PushRegister(reg)
+	}
+
+	offset := -assembler.StringLengthSize
+	state.assembler.MoveRegisterRegister(state.registers.Syscall[2], value)
+	state.assembler.LoadRegister(state.registers.Syscall[3], state.registers.Syscall[2], byte(offset), assembler.StringLengthSize)
+	state.assembler.MoveRegisterNumber(state.registers.Syscall[0], state.environment.Target.SyscallWrite)
+	state.assembler.MoveRegisterNumber(state.registers.Syscall[1], 1)
+	state.assembler.Syscall()
+
+	for i := len(saved) - 1; i >= 0; i-- {
+		state.assembler.PopRegister(saved[i])
+	}
+
+	if newline {
+		state.printText("\n")
+	}
+}
+
+// printBool adds instructions to print 'true' or 'false'.
+func (state *State) printBool(value *register.Register, newline bool) {
+	state.printCounter++
+	labelFalse := fmt.Sprintf("print_%d_false", state.printCounter)
+	labelEnd := fmt.Sprintf("print_%d_end", state.printCounter)
+	suffix := ""
+
+	if newline {
+		suffix = "\n"
+	}
+
+	state.assembler.CompareRegisterNumber(value, 0)
+	state.assembler.JumpIfEqual(labelFalse)
+	state.printText("true" + suffix)
+	state.assembler.Jump(labelEnd)
+	state.assembler.AddLabel(labelFalse)
+	state.printText("false" + suffix)
+	state.assembler.AddLabel(labelEnd)
+}
+
+// printInt adds instructions to print an integer of the given type.
+// Smaller integers are extended to 64 bits first and unsigned integers
+// are divided without a sign.
+func (state *State) printInt(value *register.Register, typ *types.Type, newline bool) {
+	state.printCounter++
+	labelDigits := fmt.Sprintf("print_%d_digits", state.printCounter)
+	labelWrite := fmt.Sprintf("print_%d_write", state.printCounter)
+
+	// The value register might be needed for the conversion
+	xmm0 := state.registers.Float[0]
+	state.assembler.MoveFloatRegisterRegister(xmm0, value)
+	regs := state.beginPrint(newline)
+	state.assembler.MoveFloatRegisterRegister(regs.number, xmm0)
+
+	if typ.Size < 8 {
+		state.Widen(regs.number, typ)
+	}
+
+	if typ.Unsigned {
+		state.assembler.AddLabel(labelDigits)
+		state.writeUnsignedDigit(regs)
+		state.assembler.CompareRegisterNumber(regs.number, 0)
+		state.assembler.JumpIfNotEqual(labelDigits)
+		state.endPrint(regs)
+		return
+	}
+
+	state.assembler.MoveRegisterRegister(regs.sign, regs.number)
+	state.assembler.AddLabel(labelDigits)
+	state.writeDigit(regs, fmt.Sprintf("print_%d_digit", state.printCounter))
+	state.assembler.CompareRegisterNumber(regs.number, 0)
+	state.assembler.JumpIfNotEqual(labelDigits)
+	state.writeSign(regs, labelWrite)
+	state.endPrint(regs)
+}
+
+// printHex adds instructions to print an integer in hexadecimal notation.
+func (state *State) printHex(value *register.Register) {
+	state.printCounter++
+	labelDigits := fmt.Sprintf("print_%d_digits", state.printCounter)
+	labelDigit := fmt.Sprintf("print_%d_digit", state.printCounter)
+	xmm0 := state.registers.Float[0]
+	state.assembler.MoveFloatRegisterRegister(xmm0, value)
+	regs := state.beginPrint(true)
+	state.assembler.MoveFloatRegisterRegister(regs.number, xmm0)
+
+	// Each digit is the lowest 4 bits of the number
+	state.assembler.AddLabel(labelDigits)
+	state.assembler.MoveRegisterRegister(regs.remainder, regs.number)
+	state.assembler.AndRegisterNumber(regs.remainder, 0xf)
+	state.assembler.AddRegisterNumber(regs.remainder, '0')
+	state.assembler.CompareRegisterNumber(regs.remainder, '9')
+	state.assembler.JumpIfLessOrEqual(labelDigit)
+	state.assembler.AddRegisterNumber(regs.remainder, 'a'-'9'-1)
+	state.assembler.AddLabel(labelDigit)
+	state.assembler.DecreaseRegister(regs.buffer)
+	state.assembler.StoreRegister(regs.buffer, 0, 1, regs.remainder)
+	state.assembler.ShiftRightLogicalRegisterNumber(regs.number, 4)
+	state.assembler.CompareRegisterNumber(regs.number, 0)
+	state.assembler.JumpIfNotEqual(labelDigits)
+
+	// Prefix
+	state.assembler.DecreaseRegister(regs.buffer)
+	state.assembler.StoreNumber(regs.buffer, 0, 1, 'x')
+	state.assembler.DecreaseRegister(regs.buffer)
+	state.assembler.StoreNumber(regs.buffer, 0, 1, '0')
+	state.endPrint(regs)
+}
+
+// printFloat adds instructions to print a floating-point number with up to 6 fractional digits.
+// Numbers that are too large for an integer part are printed in scientific notation
+// and the special values are printed as 'nan', 'inf' and '-inf'.
+func (state *State) printFloat(value *register.Register, newline bool) {
+	state.printCounter++
+	labelInfinity := fmt.Sprintf("print_%d_infinity", state.printCounter)
+	labelFinite := fmt.Sprintf("print_%d_finite", state.printCounter)
+	labelScale := fmt.Sprintf("print_%d_scale", state.printCounter)
+	labelExponent := fmt.Sprintf("print_%d_exponent", state.printCounter)
+	labelFixed := fmt.Sprintf("print_%d_fixed", state.printCounter)
+	labelSign := fmt.Sprintf("print_%d_sign", state.printCounter)
+	labelTrim := fmt.Sprintf("print_%d_trim", state.printCounter)
+	labelTrimmed := fmt.Sprintf("print_%d_trimmed", state.printCounter)
+	labelFraction := fmt.Sprintf("print_%d_fraction", state.printCounter)
+	labelDigits := fmt.Sprintf("print_%d_digits", state.printCounter)
+	labelInteger := fmt.Sprintf("print_%d_integer", state.printCounter)
+	labelWrite := fmt.Sprintf("print_%d_write", state.printCounter)
+	xmm0 := state.registers.Float[0]
+	xmm1 := state.registers.Float[1]
+
+	state.assembler.MoveFloatRegisterRegister(xmm0, value)
+	regs := state.beginPrint(newline)
+
+	// Remember the sign and continue with the absolute value
+	state.assembler.MoveFloatRegisterRegister(regs.number, xmm0)
+	state.assembler.MoveRegisterRegister(regs.sign, regs.number)
+	state.assembler.ShiftLeftRegisterNumber(regs.number, 1)
+	state.assembler.ShiftRightLogicalRegisterNumber(regs.number, 1)
+	state.assembler.MoveFloatRegisterRegister(xmm0, regs.number)
+
+	// The bits of positive numbers have the same order as the numbers
+	state.assembler.MoveRegisterNumber(regs.counter, printFloatInfinity)
+	state.assembler.CompareRegisterRegister(regs.number, regs.counter)
+	state.assembler.JumpIfLess(labelFinite)
+	state.assembler.JumpIfEqual(labelInfinity)
+	state.writeText(regs, "nan")
+	state.assembler.Jump(labelWrite)
+	state.assembler.AddLabel(labelInfinity)
+	state.writeText(regs, "inf")
+	state.assembler.Jump(labelSign)
+
+	// Numbers beyond the integer range are divided by 10 until they are below 10
+	state.assembler.AddLabel(labelFinite)
+	state.assembler.MoveRegisterNumber(regs.counter, printFloatIntegerLimit)
+	state.assembler.CompareRegisterRegister(regs.number, regs.counter)
+	state.assembler.JumpIfLess(labelFixed)
+	state.assembler.IntToFloatRegisterRegister(xmm1, regs.ten)
+	state.assembler.MoveRegisterNumber(regs.backup, 0)
+	state.assembler.MoveRegisterNumber(regs.counter, printFloatTen)
+	state.assembler.AddLabel(labelScale)
+	state.assembler.DivFloatRegisterRegister(xmm0, xmm1)
+	state.assembler.IncreaseRegister(regs.backup)
+	state.assembler.MoveFloatRegisterRegister(regs.number, xmm0)
+	state.assembler.CompareRegisterRegister(regs.number, regs.counter)
+	state.assembler.JumpIfGreaterOrEqual(labelScale)
+
+	// The exponent is written first because the buffer is filled backwards
+	state.assembler.MoveRegisterRegister(regs.number, regs.backup)
+	state.assembler.AddLabel(labelExponent)
+	state.writeDigit(regs, "")
+	state.assembler.CompareRegisterNumber(regs.number, 0)
+	state.assembler.JumpIfNotEqual(labelExponent)
+	state.writeText(regs, "e+")
+
+	// Split into the integer part and the rounded fraction
+	state.assembler.AddLabel(labelFixed)
+	state.assembler.TruncateFloatToIntRegisterRegister(regs.backup, xmm0)
+	state.assembler.IntToFloatRegisterRegister(xmm1, regs.backup)
+	state.assembler.SubFloatRegisterRegister(xmm0, xmm1)
+	state.assembler.MoveRegisterNumber(regs.number, printFloatPrecision)
+	state.assembler.IntToFloatRegisterRegister(xmm1, regs.number)
+	state.assembler.MulFloatRegisterRegister(xmm0, xmm1)
+	state.assembler.FloatToIntRegisterRegister(regs.number, xmm0)
+
+	// Rounding can carry over into the integer part
+	state.assembler.CompareRegisterNumber(regs.number, printFloatPrecision)
+	state.assembler.JumpIfLess(labelFraction)
+	state.assembler.SubRegisterNumber(regs.number, printFloatPrecision)
+	state.assembler.IncreaseRegister(regs.backup)
+	state.assembler.AddLabel(labelFraction)
+	state.assembler.PushRegister(regs.backup)
+
+	// Remove trailing zeros but keep at least one fractional digit
+	state.assembler.MoveRegisterNumber(regs.counter, 6)
+	state.assembler.AddLabel(labelTrim)
+	state.assembler.CompareRegisterNumber(regs.counter, 1)
+	state.assembler.JumpIfEqual(labelDigits)
+	state.assembler.MoveRegisterRegister(regs.backup, regs.number)
+	state.assembler.SignExtendToDX(regs.number)
+	state.assembler.DivRegister(regs.ten)
+	state.assembler.CompareRegisterNumber(regs.remainder, 0)
+	state.assembler.JumpIfNotEqual(labelTrimmed)
+	state.assembler.DecreaseRegister(regs.counter)
+	state.assembler.Jump(labelTrim)
+	state.assembler.AddLabel(labelTrimmed)
+	state.assembler.MoveRegisterRegister(regs.number, regs.backup)
+	state.assembler.AddLabel(labelDigits)
+	state.writeDigit(regs, "")
+	state.assembler.DecreaseRegister(regs.counter)
+	state.assembler.CompareRegisterNumber(regs.counter, 0)
+	state.assembler.JumpIfNotEqual(labelDigits)
+	state.assembler.DecreaseRegister(regs.buffer)
+	state.assembler.StoreNumber(regs.buffer, 0, 1, '.')
+
+	// Integer part
+	state.assembler.PopRegister(regs.number)
+	state.assembler.AddLabel(labelInteger)
+	state.writeDigit(regs, "")
+	state.assembler.CompareRegisterNumber(regs.number, 0)
+	state.assembler.JumpIfNotEqual(labelInteger)
+	state.assembler.AddLabel(labelSign)
+	state.writeSign(regs, labelWrite)
+	state.endPrint(regs)
+}
+
+// beginPrint saves the registers in use and reserves
+// a buffer on the stack that optionally ends with a newline.
+// The characters are written backwards into the buffer.
+func (state *State) beginPrint(newline bool) *printRegisters {
+	regs := &printRegisters{
+		number:    state.registers.All.ByName("rax"),
+		remainder: state.registers.All.ByName("rdx"),
+		buffer:    state.registers.All.ByName("rsi"),
+		counter:   state.registers.All.ByName("rdi"),
+		sign:      state.registers.All.ByName("r8"),
+		backup:    state.registers.All.ByName("r9"),
+		ten:       state.registers.All.ByName("r10"),
+	}
+
+	// The syscall modifies rcx and r11
+	rcx := state.registers.All.ByName("rcx")
+	r11 := state.registers.All.ByName("r11")
+	regs.saved = register.List{regs.number, rcx, regs.remainder, regs.buffer, regs.counter, regs.sign, regs.backup, regs.ten, r11}.InUse()
+
+	for _, reg := range regs.saved {
+		state.assembler.PushRegister(reg)
+	}
+
+	rsp := state.registers.Stack
+	state.assembler.SubRegisterNumber(rsp, printBufferSize)
+	state.assembler.MoveRegisterRegister(regs.buffer, rsp)
+	state.assembler.AddRegisterNumber(regs.buffer, printBufferSize)
+
+	if newline {
+		state.assembler.DecreaseRegister(regs.buffer)
+		state.assembler.StoreNumber(regs.buffer, 0, 1, '\n')
+	}
+
+	state.assembler.MoveRegisterNumber(regs.ten, 10)
+	return regs
+}
+
+// endPrint writes the characters between the buffer position and
+// the end of the buffer to the standard output and restores the registers.
+func (state *State) endPrint(regs *printRegisters) {
+	rsp := state.registers.Stack
+	state.assembler.MoveRegisterRegister(regs.remainder, rsp)
+	state.assembler.AddRegisterNumber(regs.remainder, printBufferSize)
+	state.assembler.SubRegisterRegister(regs.remainder, regs.buffer)
+	state.assembler.MoveRegisterNumber(regs.number, state.environment.Target.SyscallWrite)
+	state.assembler.MoveRegisterNumber(regs.counter, 1)
+	state.assembler.Syscall()
+	state.assembler.AddRegisterNumber(rsp, printBufferSize)
+
+	for i := len(regs.saved) - 1; i >= 0; i-- {
+		state.assembler.PopRegister(regs.saved[i])
+	}
+}
+
+// writeDigit divides the number by 10 and writes the remainder
+// as an ASCII digit in front of the buffer position.
+// Negative numbers need a label to turn the negative remainder into a digit.
+func (state *State) writeDigit(regs *printRegisters, labelPositive string) {
+	state.assembler.SignExtendToDX(regs.number)
+	state.assembler.DivRegister(regs.ten)
+
+	if labelPositive != "" {
+		state.assembler.CompareRegisterNumber(regs.remainder, 0)
+		state.assembler.JumpIfGreaterOrEqual(labelPositive)
+		state.assembler.NegateRegister(regs.remainder)
+		state.assembler.AddLabel(labelPositive)
+	}
+
+	state.assembler.AddRegisterNumber(regs.remainder, '0')
+	state.assembler.DecreaseRegister(regs.buffer)
+	state.assembler.StoreRegister(regs.buffer, 0, 1, regs.remainder)
+}
+
+// writeUnsignedDigit divides the unsigned number by 10 and writes
+// the remainder as an ASCII digit in front of the buffer position.
+func (state *State) writeUnsignedDigit(regs *printRegisters) {
+	state.assembler.XorRegisterRegister(regs.remainder, regs.remainder)
+	state.assembler.UnsignedDivRegister(regs.ten)
+	state.assembler.AddRegisterNumber(regs.remainder, '0')
+	state.assembler.DecreaseRegister(regs.buffer)
+	state.assembler.StoreRegister(regs.buffer, 0, 1, regs.remainder)
+}
+
+// writeText writes the characters of the text in front of the buffer position.
+func (state *State) writeText(regs *printRegisters, text string) {
+	for i := len(text) - 1; i >= 0; i-- {
+		state.assembler.DecreaseRegister(regs.buffer)
+		state.assembler.StoreNumber(regs.buffer, 0, 1, uint64(text[i]))
+	}
+}
+
+// writeSign writes a minus sign in front of the buffer position if the sign register is negative.
+func (state *State) writeSign(regs *printRegisters, labelPositive string) {
+	state.assembler.CompareRegisterNumber(regs.sign, 0)
+	state.assembler.JumpIfGreaterOrEqual(labelPositive)
+	state.assembler.DecreaseRegister(regs.buffer)
+	state.assembler.StoreNumber(regs.buffer, 0, 1, '-')
+	state.assembler.AddLabel(labelPositive)
+}
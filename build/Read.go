@@ -0,0 +1,47 @@
+package build
+
+import (
+	"strconv"
+
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// StandardInput is the file descriptor of the standard input.
+const StandardInput = 0
+
+// readSyscall describes the parameters of the read syscall used by the read builtin.
+var readSyscall = &Function{
+	Name: BuiltinSyscall,
+	Parameters: []*Parameter{
+		{Name: "syscall number", Type: types.Int},
+		{Name: "fd", Type: types.Int},
+		{Name: "buffer", Type: types.Pointer},
+		{Name: "length", Type: types.Int},
+	},
+	ReturnTypes: []*types.Type{types.Int},
+	IsBuiltin:   true,
+	SideEffects: 1,
+}
+
+// ReadParameters turns the parameters of the read builtin into the parameters of a read syscall
+// that reads from the standard input into the buffer.
+func (state *State) ReadParameters(expr *expression.Expression) []*expression.Expression {
+	number := numberExpression(state.environment.Target.SyscallRead, expr)
+	fd := numberExpression(StandardInput, expr)
+	expr.Children = append([]*expression.Expression{number, fd}, expr.Children...)
+	return expr.Children
+}
+
+// numberExpression creates a leaf containing the number as a child of the parent.
+func numberExpression(number uint64, parent *expression.Expression) *expression.Expression {
+	leaf := expression.FromToken(token.Token{
+		Kind:     token.Number,
+		Position: parent.Token.Position,
+		Bytes:    strconv.AppendUint(nil, number, 10),
+	})
+
+	leaf.Parent = parent
+	return leaf
+}
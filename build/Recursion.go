@@ -0,0 +1,83 @@
+package build
+
+// markRecursion assigns a cycle number to every function that can call itself, directly or indirectly.
+// Functions calling each other share the same number.
+// The strongly connected components of the call graph are found via Tarjan's algorithm.
+func (env *Environment) markRecursion(functions map[*Function]bool) {
+	var (
+		byName  = env.functionsByName()
+		index   = map[*Function]int{}
+		lowLink = map[*Function]int{}
+		onStack = map[*Function]bool{}
+		stack   []*Function
+		cycles  int
+		visit   func(*Function)
+	)
+
+	visit = func(function *Function) {
+		index[function] = len(index) + 1
+		lowLink[function] = index[function]
+		stack = append(stack, function)
+		onStack[function] = true
+		callsItself := false
+
+		for _, callee := range function.callees(byName) {
+			if !functions[callee] {
+				continue
+			}
+
+			if callee == function {
+				callsItself = true
+			}
+
+			if index[callee] == 0 {
+				visit(callee)
+
+				if lowLink[callee] < lowLink[function] {
+					lowLink[function] = lowLink[callee]
+				}
+			} else if onStack[callee] && index[callee] < lowLink[function] {
+				lowLink[function] = index[callee]
+			}
+		}
+
+		if lowLink[function] != index[function] {
+			return
+		}
+
+		start := len(stack) - 1
+
+		for stack[start] != function {
+			start--
+		}
+
+		component := stack[start:]
+		stack = stack[:start]
+
+		for _, member := range component {
+			onStack[member] = false
+		}
+
+		if len(component) == 1 && !callsItself {
+			return
+		}
+
+		cycles++
+
+		for _, member := range component {
+			member.cycle = cycles
+		}
+	}
+
+	for function := range functions {
+		if index[function] == 0 {
+			visit(function)
+		}
+	}
+}
+
+// isRecursiveCall tells you whether the function can call the current function again.
+// The compilation of these functions can't be awaited because they're waiting for us.
+func (state *State) isRecursiveCall(function *Function) bool {
+	return function == state.function || (function.cycle != 0 && function.cycle == state.function.cycle)
+}
@@ -2,9 +2,15 @@ package build
 
 import (
 	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/instruction"
 	"github.com/akyoto/q/build/token"
 )
 
+// ReturnState handles the state of return statements.
+type ReturnState struct {
+	epilogue bool
+}
+
 // Return handles return statements.
 func (state *State) Return(tokens []token.Token) error {
 	state.Skip(token.Keyword)
@@ -21,13 +27,29 @@ func (state *State) Return(tokens []token.Token) error {
 			return err
 		}
 
-		if typ != state.function.ReturnTypes[0] {
+		if literalType(expression, typ, state.function.ReturnTypes[0]) != state.function.ReturnTypes[0] {
 			return errors.New(&errors.InvalidType{Name: typ.String(), Expected: state.function.ReturnTypes[0].String()})
 		}
 	} else if len(state.function.ReturnTypes) > 0 {
 		return errors.New(&errors.MissingReturnValue{ReturnType: state.function.ReturnTypes[0].Name})
 	}
 
+	// The shared epilogue runs the deferred calls.
+	// The last instruction doesn't need a jump because the epilogue follows it.
+	if state.returnState.epilogue {
+		if state.instrCursor < len(state.instructions)-1 {
+			state.assembler.Jump("return")
+		}
+
+		return nil
+	}
+
+	err := state.RunDeferred()
+
+	if err != nil {
+		return err
+	}
+
 	if state.ensureState.counter == 0 {
 		state.assembler.Return()
 		return nil
@@ -36,3 +58,34 @@ func (state *State) Return(tokens []token.Token) error {
 	state.assembler.Jump("return")
 	return nil
 }
+
+// usesEpilogue tells you whether the returns of a function jump to a shared epilogue
+// that releases the stack memory of arrays, runs the deferred calls and returns.
+// Functions without cleanup code or with a single exit return directly.
+// Calls deferred after a return must not run on that return,
+// therefore these functions keep the cleanup code at each return.
+func usesEpilogue(instructions []instruction.Instruction, allocatesArrays bool) bool {
+	exits := 0
+	cleanup := allocatesArrays
+
+	for _, instr := range instructions {
+		switch instr.Kind {
+		case instruction.Return:
+			exits++
+
+		case instruction.Defer:
+			if exits > 0 {
+				return false
+			}
+
+			cleanup = true
+		}
+	}
+
+	// Reaching the end of the function is another exit
+	if len(instructions) == 0 || instructions[len(instructions)-1].Kind != instruction.Return {
+		exits++
+	}
+
+	return cleanup && exits > 1
+}
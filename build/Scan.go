@@ -9,10 +9,11 @@ import (
 // Scan scans the input file.
 func (file *File) Scan(imports chan<- *Import, structs chan<- *types.Type, functions chan<- *Function) error {
 	var (
-		tokens                  = file.tokens
-		newlines                = 0
-		index    token.Position = 0
-		t        token.Token
+		tokens                    = file.tokens
+		newlines                  = 0
+		index      token.Position = 0
+		annotation token.Position = -1
+		t          token.Token
 	)
 
 begin:
@@ -33,9 +34,19 @@ begin:
 				return err
 			}
 
+			if annotation != -1 {
+				function.Inline = annotations[tokens[annotation].Text()]
+				function.annotationPosition = annotation
+				annotation = -1
+			}
+
 			functions <- function
 
 		case token.Keyword:
+			if annotation != -1 {
+				return NewError(errors.New(errors.MissingAnnotatedFunction), file.path, tokens[:annotation+1], nil)
+			}
+
 			if t.Text() == "import" {
 				var imp *Import
 				var err error
@@ -51,6 +62,17 @@ begin:
 				goto begin
 			}
 
+			if t.Text() == "const" {
+				var err error
+				index, err = file.scanConstant(tokens, index)
+
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
 			if t.Text() == "struct" {
 				var typ *types.Type
 				var err error
@@ -77,10 +99,27 @@ begin:
 		case token.Comment:
 			// OK.
 
+		case token.Annotation:
+			_, exists := annotations[t.Text()]
+
+			if !exists {
+				return NewError(errors.New(&errors.UnknownAnnotation{Name: t.Text()}), file.path, tokens[:index+1], nil)
+			}
+
+			if annotation != -1 {
+				return NewError(errors.New(errors.MissingAnnotatedFunction), file.path, tokens[:annotation+1], nil)
+			}
+
+			annotation = index
+
 		default:
 			return NewError(errors.New(errors.TopLevel), file.path, tokens[:index+1], nil)
 		}
 	}
 
+	if annotation != -1 {
+		return NewError(errors.New(errors.MissingAnnotatedFunction), file.path, tokens[:annotation+1], nil)
+	}
+
 	return nil
 }
@@ -57,6 +57,11 @@ func (stack *ScopeStack) Push() {
 	stack.scopes = append(stack.scopes, Scope{})
 }
 
+// Depth returns the number of scopes on the stack.
+func (stack *ScopeStack) Depth() int {
+	return len(stack.scopes)
+}
+
 // Pop removes the scope at the top of the stack.
 func (stack *ScopeStack) Pop() {
 	stack.scopes = stack.scopes[:len(stack.scopes)-1]
@@ -68,6 +73,11 @@ func (stack *ScopeStack) Errors(isLoop bool) []*ScopeError {
 	scope := stack.scopes[len(stack.scopes)-1]
 
 	for _, variable := range scope {
+		// Unused parameters are reported as warnings
+		if variable.IsParameter && !variable.Used {
+			continue
+		}
+
 		if !variable.Used {
 			scopeErrors = append(scopeErrors, &ScopeError{
 				Position: variable.Position,
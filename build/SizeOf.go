@@ -0,0 +1,64 @@
+package build
+
+import (
+	"strconv"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// ResolveSizeOf replaces all `sizeof(Type)` calls in the expression tree with the size of the type in bytes.
+// The size is known at compile time, therefore the result can be folded like any other number.
+func (state *State) ResolveSizeOf(expr *expression.Expression) error {
+	for _, child := range expr.Children {
+		err := state.ResolveSizeOf(child)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if !expr.IsFunctionCall || expr.Token.Text() != BuiltinSizeOf {
+		return nil
+	}
+
+	// User-defined functions take precedence over builtins
+	if state.environment.Functions[PolymorphName(BuiltinSizeOf, len(expr.Children))] != nil {
+		return nil
+	}
+
+	if len(expr.Children) != 1 {
+		return errors.New(&errors.ParameterCount{
+			FunctionName:  BuiltinSizeOf,
+			CountGiven:    len(expr.Children),
+			CountRequired: 1,
+		})
+	}
+
+	parameter := expr.Children[0]
+
+	if !parameter.IsLeaf() || parameter.Token.Kind != token.Identifier {
+		return errors.New(errors.ExpectedTypeName)
+	}
+
+	typeName := parameter.Token.Text()
+	typ := state.function.File.Type(typeName)
+
+	if typ == nil {
+		return errors.New(state.environment.UnknownTypeError(typeName))
+	}
+
+	parameter.Close()
+	expr.Children = expr.Children[:0]
+	expr.IsFunctionCall = false
+	expr.Type = types.Int
+	expr.Token = token.Token{
+		Kind:     token.Number,
+		Position: expr.Token.Position,
+		Bytes:    strconv.AppendUint(nil, uint64(typ.Size), 10),
+	}
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package build
+
+import (
+	"math"
+	"sort"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/instruction"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+)
+
+// spillSlotSize is the number of bytes reserved on the stack for a spilled variable.
+const spillSlotSize = 8
+
+// SpillState handles the state of variables that were moved to the stack
+// because all registers were in use.
+//
+// Variables are only spilled and reloaded at statement boundaries.
+// A block statement like a loop or an if-else chain counts as a single statement
+// and the statements inside of its blocks can only spill the variables declared in the block,
+// therefore every control flow path sees the same variable locations.
+type SpillState struct {
+	slots      map[*Variable]byte
+	free       []byte
+	released   []byte
+	statements []spillStatement
+	active     bool
+	exceeded   bool
+}
+
+// spillStatement is a statement whose start receives the stores of spilled variables.
+type spillStatement struct {
+	position   int
+	depth      int
+	end        instruction.Position
+	blockStart token.Position
+	from       token.Position
+	until      token.Position
+}
+
+// BeginStatement starts a new statement at the given instruction index
+// and reloads the spilled variables that are needed by it.
+// Statements inside of blocks are nested in the statement of the block.
+func (state *State) BeginStatement(index instruction.Position) error {
+	statements := state.spillState.statements
+
+	for len(statements) > 0 && index >= statements[len(statements)-1].end {
+		statements = statements[:len(statements)-1]
+	}
+
+	state.spillState.statements = statements
+	instr := state.instructions[index]
+	depth := state.scopes.Depth()
+	blockStart := 0
+
+	if len(statements) > 0 {
+		if depth <= statements[len(statements)-1].depth || isBlockEnd(instr.Kind) {
+			return nil
+		}
+
+		blockStart = state.instructions[blockIndex(state.instructions, index)].Position
+	} else {
+		// Slots released by the previous top-level statement can be reused now
+		// because new stores are inserted at the start of a statement inside of it.
+		state.spillState.free = append(state.spillState.free, state.spillState.released...)
+		state.spillState.released = state.spillState.released[:0]
+	}
+
+	state.tokenCursor = instr.Position
+	end := statementEnd(state.instructions, index)
+	last := state.instructions[end-1]
+
+	state.spillState.statements = append(statements, spillStatement{
+		position:   len(state.assembler.Instructions),
+		depth:      depth,
+		end:        end,
+		blockStart: blockStart,
+		from:       instr.Position,
+		until:      last.Position + len(last.Tokens),
+	})
+
+	state.spillState.active = true
+
+	for _, variable := range state.spilledVariables() {
+		offset := state.spillState.slots[variable]
+
+		// Variables of blocks that have ended don't need their slots anymore
+		if state.scopes.Get(variable.Name) != variable {
+			delete(state.spillState.slots, variable)
+			state.spillState.released = append(state.spillState.released, offset)
+			continue
+		}
+
+		if !state.isNeeded(variable, state.currentStatement()) {
+			continue
+		}
+
+		reg := state.FindFreeRegister()
+
+		if reg == nil {
+			return errors.New(errors.ExceededMaxVariables)
+		}
+
+		state.assembler.LoadRegister(reg, state.registers.Stack, offset, spillSlotSize)
+		variable.ForceSetRegister(reg)
+		delete(state.spillState.slots, variable)
+		state.spillState.released = append(state.spillState.released, offset)
+	}
+
+	return nil
+}
+
+// EndStatements stops spilling because the end of the function has been reached.
+func (state *State) EndStatements() {
+	state.spillState.active = false
+}
+
+// FindFreeRegister returns a free general purpose register.
+// If all registers are in use, the variable whose next use is the farthest away
+// and that isn't needed by the current statement is moved to the stack.
+// Variables of the innermost statement are preferred over those of the enclosing statements.
+func (state *State) FindFreeRegister() *register.Register {
+	free := state.registers.General.FindFree()
+
+	if free != nil || !state.spillState.active {
+		return free
+	}
+
+	statements := state.spillState.statements
+
+	for i := len(statements) - 1; i >= 0; i-- {
+		victim := state.spillCandidate(&statements[i])
+
+		if victim == nil {
+			continue
+		}
+
+		offset, ok := state.spillSlot()
+
+		if !ok {
+			state.spillState.exceeded = true
+			return nil
+		}
+
+		// The store is placed at the start of the statement because
+		// the register didn't change since then and the variable isn't needed afterwards.
+		reg := victim.Register()
+		state.assembler.StoreRegisterAt(statements[i].position, state.registers.Stack, offset, spillSlotSize, reg)
+		reg.Free()
+		victim.register = nil
+
+		// The store moved the instructions of the nested statements
+		for j := i + 1; j < len(statements); j++ {
+			statements[j].position++
+		}
+
+		if state.spillState.slots == nil {
+			state.spillState.slots = map[*Variable]byte{}
+		}
+
+		state.spillState.slots[victim] = offset
+		return reg
+	}
+
+	return nil
+}
+
+// spillError replaces the error of a failed register request
+// if spilling failed because the stack slots ran out.
+func (state *State) spillError(err error) error {
+	if state.spillState.exceeded && errors.Unwrap(err) == errors.ExceededMaxVariables {
+		return errors.New(errors.ExceededMaxSpillSlots)
+	}
+
+	return err
+}
+
+// currentStatement returns the innermost statement.
+func (state *State) currentStatement() *spillStatement {
+	return &state.spillState.statements[len(state.spillState.statements)-1]
+}
+
+// spilledVariables returns the variables on the stack sorted by their offset
+// so that the generated code doesn't depend on the map iteration order.
+func (state *State) spilledVariables() []*Variable {
+	variables := make([]*Variable, 0, len(state.spillState.slots))
+
+	for variable := range state.spillState.slots {
+		variables = append(variables, variable)
+	}
+
+	sort.Slice(variables, func(a, b int) bool {
+		return state.spillState.slots[variables[a]] < state.spillState.slots[variables[b]]
+	})
+
+	return variables
+}
+
+// spillCandidate returns the variable that should be moved to the stack at the start of the statement.
+func (state *State) spillCandidate(statement *spillStatement) *Variable {
+	var victim *Variable
+	victimNextUse := 0
+
+	for _, reg := range state.registers.General {
+		variable, isVariable := reg.User().(*Variable)
+
+		if !isVariable || variable.Register() != reg || variable.KeepAlive > 0 || variable.IsConstant {
+			continue
+		}
+
+		if variable.Position < statement.blockStart || state.scopes.Get(variable.Name) != variable {
+			continue
+		}
+
+		if variable.AliveUntil < statement.until || state.isNeeded(variable, statement) {
+			continue
+		}
+
+		nextUse := state.nextUse(variable.Name, statement.until)
+
+		if victim == nil || nextUse > victimNextUse {
+			victim = variable
+			victimNextUse = nextUse
+		}
+	}
+
+	return victim
+}
+
+// spillSlot returns the stack offset for a spilled variable.
+// Slots share the stack frame with arrays and are addressed with an 8-bit displacement.
+func (state *State) spillSlot() (byte, bool) {
+	free := state.spillState.free
+
+	if len(free) > 0 {
+		offset := free[len(free)-1]
+		state.spillState.free = free[:len(free)-1]
+		return offset, true
+	}
+
+	offset := state.arrayState.stackSize
+
+	if offset+spillSlotSize > math.MaxInt8+1 {
+		return 0, false
+	}
+
+	state.arrayState.stackSize += spillSlotSize
+	return byte(offset), true
+}
+
+// isNeeded tells you whether the variable is referenced in the statement.
+func (state *State) isNeeded(variable *Variable, statement *spillStatement) bool {
+	for _, t := range state.tokens[statement.from:statement.until] {
+		if t.Kind == token.Identifier && t.Text() == variable.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextUse returns the position of the next reference to the identifier.
+func (state *State) nextUse(name string, from token.Position) token.Position {
+	for i := from; i < len(state.tokens); i++ {
+		t := state.tokens[i]
+
+		if t.Kind == token.Identifier && t.Text() == name {
+			return i
+		}
+	}
+
+	return len(state.tokens)
+}
+
+// statementEnd returns the index after the last instruction of the statement at the given index.
+// Blocks end with their matching end instruction and if blocks include all of their else branches.
+func statementEnd(instructions []instruction.Instruction, index instruction.Position) instruction.Position {
+	depth := 0
+
+	for i := index; i < len(instructions); i++ {
+		switch {
+		case isBlockStart(instructions[i].Kind):
+			depth++
+
+		case isBlockEnd(instructions[i].Kind):
+			depth--
+		}
+
+		if depth > 0 {
+			continue
+		}
+
+		if i+1 < len(instructions) && instructions[i+1].Kind == instruction.ElseStart {
+			continue
+		}
+
+		return i + 1
+	}
+
+	return len(instructions)
+}
+
+// blockIndex returns the index of the first instruction inside the block that contains the given index.
+func blockIndex(instructions []instruction.Instruction, index instruction.Position) instruction.Position {
+	depth := 0
+
+	for i := index - 1; i >= 0; i-- {
+		switch {
+		case isBlockEnd(instructions[i].Kind):
+			depth++
+
+		case isBlockStart(instructions[i].Kind):
+			if depth == 0 {
+				return i + 1
+			}
+
+			depth--
+		}
+	}
+
+	return 0
+}
+
+// isBlockStart tells you whether the instruction kind starts a block.
+func isBlockStart(kind instruction.Kind) bool {
+	switch kind {
+	case instruction.IfStart, instruction.ElseStart, instruction.ForStart, instruction.LoopStart, instruction.WhileStart, instruction.SwitchStart, instruction.CaseStart, instruction.BlockStart, instruction.AssemblyStart:
+		return true
+	}
+
+	return false
+}
+
+// isBlockEnd tells you whether the instruction kind ends a block.
+func isBlockEnd(kind instruction.Kind) bool {
+	switch kind {
+	case instruction.IfEnd, instruction.ElseEnd, instruction.ForEnd, instruction.LoopEnd, instruction.WhileEnd, instruction.SwitchEnd, instruction.CaseEnd, instruction.BlockEnd, instruction.AssemblyEnd:
+		return true
+	}
+
+	return false
+}
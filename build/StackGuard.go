@@ -0,0 +1,76 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/syscall"
+)
+
+const (
+	// StackGuardLabel is the label of the stack overflow handler shared by all functions.
+	StackGuardLabel = "stack.trap"
+
+	// StackGuardExitCode is the exit code of a program that exceeded its stack limit.
+	StackGuardExitCode = 102
+
+	// StackGuardSize is the number of stack bytes that can be used before the program exits.
+	// Linux limits the arguments and environment to a quarter of the default 8 MiB stack,
+	// therefore the guard always triggers before the stack is exhausted.
+	StackGuardSize = 6 << 20
+
+	// threadBlockSize is the number of bytes that the thread block occupies on the initial stack.
+	threadBlockSize = 16
+
+	// syscallArchPrctl and archSetFS are used to store the address of the stack limit in the fs register.
+	syscallArchPrctl = 158
+	archSetFS        = 0x1002
+)
+
+// GuardStack adds a stack limit check to the start of functions that call other functions.
+// Leaf functions only use a few bytes of stack and don't need the check.
+func (state *State) GuardStack() {
+	if !state.stackGuard || !state.assembler.HasCalls() {
+		return
+	}
+
+	state.assembler.InsertStackCheck(StackGuardLabel)
+}
+
+// addThreadBlock stores the stack limit and the initial stack pointer in the _start frame
+// and points the fs register to it. The limit is at fs:[0] and the initial stack pointer at fs:[8].
+// Two values are pushed to keep the stack aligned to 16 bytes.
+func (build *Build) addThreadBlock(code *asm.Assembler) {
+	code.MoveRegisterRegister("rax", "rsp")
+	code.PushRegister("rax")
+
+	if build.StackGuard {
+		code.MoveRegisterNumber("rcx", StackGuardSize)
+		code.SubRegisterRegister("rax", "rcx")
+	}
+
+	code.PushRegister("rax")
+	code.MoveRegisterNumber(syscall.Registers[0], syscallArchPrctl)
+	code.MoveRegisterNumber(syscall.Registers[1], archSetFS)
+	code.MoveRegisterRegister(syscall.Registers[2], "rsp")
+	code.Syscall()
+}
+
+// threadBlockAssembly returns the code of addThreadBlock in Intel syntax.
+func (build *Build) threadBlockAssembly() string {
+	limit := ""
+
+	if build.StackGuard {
+		limit = fmt.Sprintf("\tmov rcx, %d\n\tsub rax, rcx\n", StackGuardSize)
+	}
+
+	return fmt.Sprintf("\tmov rax, rsp\n\tpush rax\n%s\tpush rax\n\tmov %s, %d\n\tmov %s, %d\n\tmov %s, rsp\n\tsyscall\n", limit, syscall.Registers[0], syscallArchPrctl, syscall.Registers[1], archSetFS, syscall.Registers[2])
+}
+
+// addStackGuardHandler adds the stack overflow handler which exits the program.
+func (build *Build) addStackGuardHandler(code *asm.Assembler) {
+	code.AddLabel(StackGuardLabel)
+	code.MoveRegisterNumber(syscall.Registers[0], build.Target.SyscallExit)
+	code.MoveRegisterNumber(syscall.Registers[1], StackGuardExitCode)
+	code.Syscall()
+}
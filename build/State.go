@@ -3,10 +3,12 @@ package build
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/akyoto/q/build/assembler"
 	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/instruction"
+	"github.com/akyoto/q/build/log"
 	"github.com/akyoto/q/build/register"
 	"github.com/akyoto/q/build/token"
 	"github.com/akyoto/q/build/types"
@@ -30,11 +32,31 @@ type State struct {
 	forState    ForState
 	ifState     IfState
 	loopState   LoopState
+	whileState  WhileState
 	expectState ExpectState
+	assertState AssertState
 	ensureState EnsureState
+	breakState  BreakState
+	deferState  DeferState
+	arrayState  ArrayState
+	returnState ReturnState
+	switchState SwitchState
+	spillState  SpillState
+
+	// Counters
+	printCounter   int
+	logicalCounter int
+	envCounter     int
 
 	// Optimization flags
 	ignoreContracts bool
+	foldConstants   bool
+	reduceStrength  bool
+
+	// Debug flags
+	checkOverflow bool
+	stackGuard    bool
+	debug         bool
 }
 
 // CompileInstructions compiles all instructions.
@@ -44,18 +66,36 @@ func (state *State) CompileInstructions() error {
 	for index, instr := range state.instructions {
 		state.KillVariables(lastKillPos, instr.Position)
 		lastKillPos = instr.Position
+		state.CountLiveVariables(instr.Position)
+		err := state.BeginStatement(index)
+
+		if err != nil {
+			return state.spillError(err)
+		}
 
 		if state.assembler.Verbose {
 			state.assembler.AddComment(instr.String())
 		}
 
-		err := state.Instruction(instr, index)
+		if state.debug {
+			line, column := state.function.SourcePosition(instr.Position)
+			state.assembler.AddSourceLine(state.function.File.path, line, column)
+		}
+
+		start := len(state.assembler.Instructions)
+		err = state.Instruction(instr, index)
 
 		if err != nil {
-			return err
+			return state.spillError(err)
+		}
+
+		if state.assembler.Verbose {
+			line, _ := state.function.SourcePosition(instr.Position)
+			state.assembler.SetSourceLine(start, line)
 		}
 	}
 
+	state.EndStatements()
 	return nil
 }
 
@@ -63,6 +103,7 @@ func (state *State) CompileInstructions() error {
 func (state *State) Instruction(instr instruction.Instruction, index instruction.Position) error {
 	state.tokenCursor = instr.Position
 	state.instrCursor = index
+	state.ResolveConstants(instr.Tokens)
 
 	switch instr.Kind {
 	case instruction.Assignment:
@@ -77,6 +118,12 @@ func (state *State) Instruction(instr instruction.Instruction, index instruction
 	case instruction.IfEnd:
 		return state.IfEnd()
 
+	case instruction.ElseStart:
+		return state.ElseStart(instr.Tokens)
+
+	case instruction.ElseEnd:
+		return state.IfEnd()
+
 	case instruction.ForStart:
 		return state.ForStart(instr.Tokens)
 
@@ -89,6 +136,39 @@ func (state *State) Instruction(instr instruction.Instruction, index instruction
 	case instruction.LoopEnd:
 		return state.LoopEnd()
 
+	case instruction.WhileStart:
+		return state.WhileStart(instr.Tokens)
+
+	case instruction.WhileEnd:
+		return state.WhileEnd()
+
+	case instruction.SwitchStart:
+		return state.SwitchStart(instr.Tokens)
+
+	case instruction.SwitchEnd:
+		return state.SwitchEnd()
+
+	case instruction.CaseStart:
+		return state.CaseStart(instr.Tokens)
+
+	case instruction.CaseEnd:
+		return state.CaseEnd()
+
+	case instruction.BlockStart:
+		return state.BlockStart()
+
+	case instruction.BlockEnd:
+		return state.BlockEnd()
+
+	case instruction.AssemblyStart:
+		return state.AssemblyStart(instr.Tokens)
+
+	case instruction.AssemblyEnd:
+		return nil
+
+	case instruction.Assembly:
+		return state.Assembly(instr.Tokens)
+
 	case instruction.Return:
 		return state.Return(instr.Tokens)
 
@@ -98,6 +178,15 @@ func (state *State) Instruction(instr instruction.Instruction, index instruction
 	case instruction.Ensure:
 		return state.Ensure(instr.Tokens)
 
+	case instruction.Break:
+		return state.Break(instr.Tokens)
+
+	case instruction.Continue:
+		return state.Continue(instr.Tokens)
+
+	case instruction.Defer:
+		return state.Defer(instr.Tokens)
+
 	case instruction.Invalid:
 		return state.Invalid(instr.Tokens)
 
@@ -116,6 +205,13 @@ func (state *State) CompareRegisterExpression(register *register.Register, expre
 
 		switch expression[0].Kind {
 		case token.Identifier:
+			value, isBool := BoolLiteral(expression[0])
+
+			if isBool {
+				state.assembler.CompareRegisterNumber(register, value)
+				return nil, types.Bool, nil
+			}
+
 			variableName := expression[0].Text()
 			variable := state.scopes.Get(variableName)
 
@@ -143,7 +239,7 @@ func (state *State) CompareRegisterExpression(register *register.Register, expre
 		}
 	}
 
-	temporary := state.registers.General.FindFree()
+	temporary := state.FindFreeRegister()
 
 	if temporary == nil {
 		return nil, nil, errors.New(errors.ExceededMaxVariables)
@@ -174,6 +270,23 @@ func (state *State) PopScope(isLoop bool) error {
 	return nil
 }
 
+// WarnUnusedParameters prints a warning for every parameter that has never been used.
+// Unused parameters are not treated as errors because they can be intentional.
+func (state *State) WarnUnusedParameters() {
+	file := state.function.File
+
+	for _, parameter := range state.function.Parameters {
+		variable := state.scopes.Get(parameter.Name)
+
+		if variable == nil || variable.Used {
+			continue
+		}
+
+		warning := NewError(&errors.UnusedParameter{Name: parameter.Name}, file.path, file.tokens[:parameter.Position+1], state.function)
+		log.Error.Println(warning)
+	}
+}
+
 // UseVariable marks the variable as used and should always
 // be called when the variable value is required.
 func (state *State) UseVariable(variable *Variable) {
@@ -183,7 +296,7 @@ func (state *State) UseVariable(variable *Variable) {
 
 // InLoop returns true if we're currently in a loop body.
 func (state *State) InLoop() bool {
-	return len(state.forState.stack) > 0 || len(state.loopState.labels) > 0
+	return len(state.forState.stack) > 0 || len(state.loopState.labels) > 0 || len(state.whileState.stack) > 0
 }
 
 // Invalid handles invalid instructions.
@@ -204,7 +317,20 @@ func (state *State) Invalid(tokens []token.Token) error {
 
 // ParseInt parses an integer number.
 func (state *State) ParseInt(numberString string) (int64, error) {
-	number, err := strconv.ParseInt(numberString, 10, 64)
+	number, err := token.ParseInt(numberString)
+
+	if err != nil {
+		return 0, errors.New(&errors.NotANumber{
+			Expression: numberString,
+		})
+	}
+
+	return number, nil
+}
+
+// ParseFloat converts a floating-point literal to a float64.
+func (state *State) ParseFloat(numberString string) (float64, error) {
+	number, err := strconv.ParseFloat(strings.ReplaceAll(numberString, "_", ""), 64)
 
 	if err != nil {
 		return 0, errors.New(&errors.NotANumber{
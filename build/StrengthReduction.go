@@ -0,0 +1,204 @@
+package build
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/register"
+)
+
+// ReduceStrength replaces an operation with a number by cheaper instructions.
+// It returns false if no cheaper alternative exists.
+func (state *State) ReduceStrength(operation string, register *register.Register, number int64) bool {
+	if number <= 0 || number&(number-1) != 0 {
+		return false
+	}
+
+	exponent := uint64(bits.TrailingZeros64(uint64(number)))
+
+	switch operation {
+	case "*":
+		// Shifts don't report overflows
+		if state.checkOverflow {
+			return false
+		}
+
+		if exponent > 0 {
+			state.assembler.ShiftLeftRegisterNumber(register, exponent)
+		}
+
+		return true
+
+	case "/":
+		if exponent == 0 {
+			return true
+		}
+
+		return state.divideByPowerOfTwo(register, exponent, false)
+
+	case "%":
+		if exponent == 0 {
+			state.assembler.MoveRegisterNumber(register, 0)
+			return true
+		}
+
+		// The mask needs to fit into a 32-bit immediate value
+		if exponent > 31 {
+			return false
+		}
+
+		return state.divideByPowerOfTwo(register, exponent, true)
+	}
+
+	return false
+}
+
+// divideByPowerOfTwo divides a signed integer by 2^exponent using shifts.
+// An arithmetic shift alone would round towards negative infinity,
+// therefore negative numbers are biased by 2^exponent-1 first
+// so that the result is rounded towards zero like the division instruction does.
+func (state *State) divideByPowerOfTwo(register *register.Register, exponent uint64, remainder bool) bool {
+	bias := state.registers.General.FindFree()
+
+	if bias == nil {
+		return false
+	}
+
+	bias.ForceUse(register)
+	state.assembler.MoveRegisterRegister(bias, register)
+	state.assembler.ShiftRightRegisterNumber(bias, 63)
+	state.assembler.ShiftRightLogicalRegisterNumber(bias, 64-exponent)
+
+	if remainder {
+		// x % 2^n == x - ((x + bias) & -2^n)
+		state.assembler.AddRegisterRegister(bias, register)
+		state.assembler.AndRegisterNumber(bias, uint64(-(int64(1) << exponent)))
+		state.assembler.SubRegisterRegister(register, bias)
+	} else {
+		// x / 2^n == (x + bias) >> n
+		state.assembler.AddRegisterRegister(register, bias)
+		state.assembler.ShiftRightRegisterNumber(register, exponent)
+	}
+
+	bias.Free()
+	return true
+}
+
+// DivideByConstant divides a signed integer by a constant divisor
+// using a multiplication with the reciprocal instead of a division.
+// It returns false if the divisor is not supported.
+func (state *State) DivideByConstant(operation string, register *register.Register, divisor int64) (bool, error) {
+	if divisor < 3 || divisor > math.MaxInt32 || divisor&(divisor-1) == 0 {
+		return false, nil
+	}
+
+	if operation != "/" && operation != "%" {
+		return false, nil
+	}
+
+	rax := state.registers.All.ByName("rax")
+	rdx := state.registers.All.ByName("rdx")
+
+	saved, err := state.SaveRegisters(register, rax, rdx)
+	defer state.RestoreRegisters(saved)
+
+	if err != nil {
+		return false, err
+	}
+
+	// The dividend needs to stay in a register that isn't
+	// modified by the multiplication.
+	dividend := register
+
+	if register == rax || register == rdx {
+		dividend = state.FindFreeRegister()
+
+		if dividend == nil {
+			return false, errors.New(errors.ExceededMaxVariables)
+		}
+
+		dividend.ForceUse(register)
+		state.assembler.MoveRegisterRegister(dividend, register)
+		defer dividend.Free()
+	}
+
+	magic, shift := magicNumber(divisor)
+
+	// quotient = (dividend * magic) >> (64 + shift)
+	state.assembler.MoveRegisterNumber(rax, uint64(magic))
+	state.assembler.MulRegister(dividend)
+
+	if magic < 0 {
+		state.assembler.AddRegisterRegister(rdx, dividend)
+	}
+
+	if shift > 0 {
+		state.assembler.ShiftRightRegisterNumber(rdx, shift)
+	}
+
+	// Negative quotients are rounded towards zero by adding 1
+	state.assembler.MoveRegisterRegister(rax, dividend)
+	state.assembler.ShiftRightLogicalRegisterNumber(rax, 63)
+	state.assembler.AddRegisterRegister(rdx, rax)
+
+	if operation == "/" {
+		if register != rdx {
+			state.assembler.MoveRegisterRegister(register, rdx)
+		}
+
+		return true, nil
+	}
+
+	// remainder = dividend - quotient * divisor
+	state.assembler.MulRegisterNumber(rdx, uint64(divisor))
+	state.assembler.SubRegisterRegister(dividend, rdx)
+
+	if register != dividend {
+		state.assembler.MoveRegisterRegister(register, dividend)
+	}
+
+	return true, nil
+}
+
+// magicNumber calculates the multiplier and the shift for a signed division
+// by a constant divisor greater than or equal to 2.
+// The algorithm is described in "Hacker's Delight" by Henry S. Warren.
+func magicNumber(divisor int64) (int64, uint64) {
+	const twoPow63 = uint64(1) << 63
+
+	d := uint64(divisor)
+	anc := twoPow63 - 1 - twoPow63%d
+	q1 := twoPow63 / anc
+	r1 := twoPow63 - q1*anc
+	q2 := twoPow63 / d
+	r2 := twoPow63 - q2*d
+	p := uint64(63)
+
+	for {
+		p++
+		q1 *= 2
+		r1 *= 2
+
+		if r1 >= anc {
+			q1++
+			r1 -= anc
+		}
+
+		q2 *= 2
+		r2 *= 2
+
+		if r2 >= d {
+			q2++
+			r2 -= d
+		}
+
+		delta := d - r2
+
+		if q1 > delta || (q1 == delta && r1 != 0) {
+			break
+		}
+	}
+
+	return int64(q2 + 1), p - 64
+}
@@ -0,0 +1,194 @@
+package build
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/instruction"
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// SwitchState handles the state of switch compilation.
+type SwitchState struct {
+	counter int
+	stack   []Switch
+}
+
+// Switch represents a switch block.
+// The value is compared with the values of each case in order
+// and the first matching case is executed.
+type Switch struct {
+	labelEnd  string
+	labelNext string
+	value     *register.Register
+	variable  *Variable
+	cases     int
+	hasElse   bool
+	values    map[int64]bool
+}
+
+// SwitchStart handles the start of switch blocks.
+func (state *State) SwitchStart(tokens []token.Token) error {
+	state.Skip(token.Keyword)
+	expression := tokens[1:]
+
+	if len(expression) == 0 {
+		return errors.New(errors.MissingSwitchValue)
+	}
+
+	value, typ, err := state.EvaluateTokens(expression)
+
+	if err != nil {
+		return err
+	}
+
+	if typ == types.Float64 || typ == types.Bool {
+		return errors.New(&errors.InvalidType{Name: typ.String(), Expected: types.Int.String()})
+	}
+
+	state.switchState.counter++
+
+	block := Switch{
+		labelEnd: fmt.Sprintf("switch_%d_end", state.switchState.counter),
+		value:    value,
+		values:   map[int64]bool{},
+	}
+
+	// The value is compared at the start of every case,
+	// therefore a variable needs to stay alive until the end of the switch.
+	variable, isVariable := value.User().(*Variable)
+
+	if isVariable {
+		variable.KeepAlive++
+		block.variable = variable
+	}
+
+	state.switchState.stack = append(state.switchState.stack, block)
+	return nil
+}
+
+// CaseStart handles the start of a case inside a switch block.
+// A case lists one or more constant values separated by commas or it is the 'else' case.
+func (state *State) CaseStart(tokens []token.Token) error {
+	block := &state.switchState.stack[len(state.switchState.stack)-1]
+
+	if block.hasElse {
+		return errors.New(errors.CaseAfterElse)
+	}
+
+	block.cases++
+	state.scopes.Push()
+
+	if tokens[0].Kind == token.Keyword && tokens[0].Text() == "else" {
+		if len(tokens) > 1 {
+			return errors.New(errors.InvalidExpression)
+		}
+
+		state.Skip(token.Keyword)
+		block.hasElse = true
+		block.labelNext = ""
+		return nil
+	}
+
+	block.labelNext = fmt.Sprintf("switch_%d_case_%d_end", state.switchState.counter, block.cases)
+	labelBody := fmt.Sprintf("switch_%d_case_%d_body", state.switchState.counter, block.cases)
+	values := token.Split(tokens, token.Separator)
+
+	for i, valueTokens := range values {
+		value, isConstant := state.ConstantInt(valueTokens)
+
+		if !isConstant {
+			return errors.New(errors.NotConstant)
+		}
+
+		if block.values[value] {
+			return errors.New(&errors.DuplicateCase{Value: value})
+		}
+
+		block.values[value] = true
+		err := state.compareSwitchValue(block.value, value)
+
+		if err != nil {
+			return err
+		}
+
+		if i == len(values)-1 {
+			state.assembler.JumpIfNotEqual(block.labelNext)
+			break
+		}
+
+		state.assembler.JumpIfEqual(labelBody)
+	}
+
+	if len(values) > 1 {
+		state.assembler.AddLabel(labelBody)
+	}
+
+	return nil
+}
+
+// CaseEnd handles the end of a case inside a switch block.
+func (state *State) CaseEnd() error {
+	err := state.PopScope(false)
+
+	if err != nil {
+		return err
+	}
+
+	block := &state.switchState.stack[len(state.switchState.stack)-1]
+	nextIndex := state.instrCursor + 1
+
+	// Only the last case can fall through to the end of the switch
+	if nextIndex < len(state.instructions) && state.instructions[nextIndex].Kind == instruction.CaseStart && !state.assembler.IsUnreachable() {
+		state.assembler.Jump(block.labelEnd)
+	}
+
+	if block.labelNext != "" {
+		state.assembler.AddLabel(block.labelNext)
+	}
+
+	return nil
+}
+
+// SwitchEnd handles the end of switch blocks.
+func (state *State) SwitchEnd() error {
+	block := state.switchState.stack[len(state.switchState.stack)-1]
+	state.switchState.stack = state.switchState.stack[:len(state.switchState.stack)-1]
+	state.assembler.AddLabel(block.labelEnd)
+
+	if block.variable == nil {
+		block.value.Free()
+		return nil
+	}
+
+	block.variable.KeepAlive--
+
+	if block.variable.AliveUntil < state.tokenCursor {
+		block.variable.AliveUntil = state.tokenCursor
+	}
+
+	return nil
+}
+
+// compareSwitchValue compares the register with a case value.
+// Values that don't fit into a 32-bit immediate are moved into a temporary register first.
+func (state *State) compareSwitchValue(value *register.Register, number int64) error {
+	if number >= math.MinInt32 && number <= math.MaxInt32 {
+		state.assembler.CompareRegisterNumber(value, uint64(number))
+		return nil
+	}
+
+	temporary := state.FindFreeRegister()
+
+	if temporary == nil {
+		return errors.New(errors.ExceededMaxVariables)
+	}
+
+	state.assembler.MoveRegisterNumber(temporary, uint64(number))
+	state.assembler.CompareRegisterRegister(value, temporary)
+	temporary.Free()
+	return nil
+}
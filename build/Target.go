@@ -0,0 +1,65 @@
+package build
+
+import (
+	"io"
+	"strings"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/q/build/macho"
+)
+
+// Target describes the operating system an executable is built for.
+type Target struct {
+	Name         string
+	SyscallRead  uint64
+	SyscallWrite uint64
+	SyscallExit  uint64
+}
+
+var (
+	// Linux produces ELF executables.
+	Linux = &Target{Name: "linux", SyscallRead: 0, SyscallWrite: 1, SyscallExit: 60}
+
+	// Darwin produces Mach-O executables for macOS.
+	Darwin = &Target{Name: "darwin", SyscallRead: 0x2000003, SyscallWrite: 0x2000004, SyscallExit: 0x2000001}
+)
+
+// Targets defines the supported targets by name.
+var Targets = map[string]*Target{
+	Linux.Name:  Linux,
+	Darwin.Name: Darwin,
+}
+
+// Executable is a binary that can be written to disk or to memory.
+type Executable interface {
+	io.WriterTo
+	WriteToFile(fileName string) error
+}
+
+// Executable creates the binary file format used by the target.
+// The relative pointers are data references that are relative to the instruction pointer.
+func (target *Target) Executable(code *asm.Assembler, relativePointers []asm.Pointer) Executable {
+	if target == Darwin {
+		return macho.New(code, relativePointers)
+	}
+
+	return newELF(code, relativePointers)
+}
+
+// IncludesFile tells you whether the source file is compiled for this target.
+// Files named after a different target like "linux.q" or "io_linux.q" are excluded.
+func (target *Target) IncludesFile(fileName string) bool {
+	name := strings.TrimSuffix(fileName, ".q")
+
+	for targetName := range Targets {
+		if targetName == target.Name {
+			continue
+		}
+
+		if name == targetName || strings.HasSuffix(name, "_"+targetName) {
+			return false
+		}
+	}
+
+	return true
+}
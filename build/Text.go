@@ -0,0 +1,72 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/expression"
+	"github.com/akyoto/q/build/token"
+)
+
+// textTypeName is the name of the type that texts are declared with.
+const textTypeName = "Text"
+
+// isText tells you whether the tokens evaluate to a text with a length prefix.
+// Texts share their type with integers and pointers,
+// therefore the value needs to be checked instead of the type.
+// The values returned by 'env' are null-terminated and don't count as texts.
+func (state *State) isText(tokens []token.Token) bool {
+	if len(tokens) == 1 {
+		return tokens[0].Kind == token.Text || state.isTextVariable(tokens[0])
+	}
+
+	if !containsText(tokens) && (len(tokens) < 2 || tokens[0].Kind != token.Identifier || tokens[1].Kind != token.GroupStart) {
+		return false
+	}
+
+	expr, err := expression.FromTokens(tokens)
+
+	if err != nil {
+		return false
+	}
+
+	defer expr.Close()
+	return state.isTextExpression(expr)
+}
+
+// isTextExpression tells you whether the expression evaluates to a text with a length prefix.
+func (state *State) isTextExpression(expr *expression.Expression) bool {
+	if expr.IsLeaf() {
+		return expr.Token.Kind == token.Text || state.isTextVariable(expr.Token)
+	}
+
+	// Text literals can be concatenated at compile time
+	if expr.Token.Kind == token.Operator && expr.Token.Text() == "+" && len(expr.Children) == 2 {
+		return state.isTextExpression(expr.Children[0]) && state.isTextExpression(expr.Children[1])
+	}
+
+	if !expr.IsFunctionCall {
+		return false
+	}
+
+	function := state.environment.Functions[PolymorphName(expr.Token.Text(), len(expr.Children))]
+	return function != nil && TypeNameFromTokens(function.ReturnTypeTokens) == textTypeName
+}
+
+// isTextVariable tells you whether the token refers to a variable that holds a text.
+func (state *State) isTextVariable(t token.Token) bool {
+	if t.Kind != token.Identifier {
+		return false
+	}
+
+	variable := state.scopes.Get(t.Text())
+	return variable != nil && variable.IsText
+}
+
+// containsText tells you whether one of the tokens is a text literal.
+func containsText(tokens []token.Token) bool {
+	for _, t := range tokens {
+		if t.Kind == token.Text {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,41 +5,25 @@ import (
 
 	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/types"
-	"github.com/akyoto/stringutils/similarity"
 )
 
 // UnknownPackageError produces an unknown package error
 // and tries to guess which package the user was trying to type.
 func (state *State) UnknownPackageError(pkgName string) error {
-	if len(state.function.File.imports) == 0 {
-		return &errors.UnknownPackage{Name: pkgName}
-	}
-
 	knownPackages := make([]string, 0, len(state.function.File.imports))
 
 	for imp := range state.function.File.imports {
 		knownPackages = append(knownPackages, imp)
 	}
 
-	// Suggest a package name based on the similarity to known functions
-	sort.Slice(knownPackages, func(a, b int) bool {
-		aSimilarity := similarity.JaroWinkler(pkgName, knownPackages[a])
-		bSimilarity := similarity.JaroWinkler(pkgName, knownPackages[b])
-		return aSimilarity > bSimilarity
-	})
-
-	if similarity.JaroWinkler(pkgName, knownPackages[0]) < 0.8 {
-		return &errors.UnknownPackage{Name: pkgName}
-	}
-
 	return &errors.UnknownPackage{
 		Name:        pkgName,
-		CorrectName: knownPackages[0],
+		CorrectName: closestName(pkgName, knownPackages),
 	}
 }
 
 // UnknownVariableError produces an unknown variable error
-// and tries to guess which variable the user was trying to type.
+// and tries to guess which variable or constant the user was trying to type.
 func (state *State) UnknownVariableError(variableName string) error {
 	knownVariables := []string{}
 
@@ -47,24 +31,13 @@ func (state *State) UnknownVariableError(variableName string) error {
 		knownVariables = append(knownVariables, variable.Name)
 	})
 
-	if len(knownVariables) == 0 {
-		return &errors.UnknownVariable{Name: variableName}
-	}
-
-	// Suggest a variable name based on the similarity to known variables
-	sort.Slice(knownVariables, func(a, b int) bool {
-		aSimilarity := similarity.JaroWinkler(variableName, knownVariables[a])
-		bSimilarity := similarity.JaroWinkler(variableName, knownVariables[b])
-		return aSimilarity > bSimilarity
-	})
-
-	if similarity.JaroWinkler(variableName, knownVariables[0]) < 0.8 {
-		return &errors.UnknownVariable{Name: variableName}
+	for constant := range state.function.File.constants {
+		knownVariables = append(knownVariables, constant)
 	}
 
 	return &errors.UnknownVariable{
 		Name:        variableName,
-		CorrectName: knownVariables[0],
+		CorrectName: closestName(variableName, knownVariables),
 	}
 }
 
@@ -78,54 +51,32 @@ func (env *Environment) UnknownFunctionError(functionName string) error {
 	}
 
 	for function := range env.Functions {
-		knownFunctions = append(knownFunctions, function)
-	}
-
-	// Suggest a function name based on the similarity to known functions
-	sort.Slice(knownFunctions, func(a, b int) bool {
-		aSimilarity := similarity.JaroWinkler(functionName, knownFunctions[a])
-		bSimilarity := similarity.JaroWinkler(functionName, knownFunctions[b])
-		return aSimilarity > bSimilarity
-	})
-
-	if similarity.JaroWinkler(functionName, knownFunctions[0]) < 0.8 {
-		return &errors.UnknownFunction{Name: functionName}
+		knownFunctions = append(knownFunctions, UnpolymorphName(function))
 	}
 
 	return &errors.UnknownFunction{
 		Name:        functionName,
-		CorrectName: knownFunctions[0],
+		CorrectName: closestName(UnpolymorphName(functionName), knownFunctions),
 	}
 }
 
 // UnknownTypeError produces an unknown type error
 // and tries to guess which type the user was trying to type.
-func (env *Environment) UnknownTypeError(pkgName string) error {
+func (env *Environment) UnknownTypeError(typeName string) error {
 	knownTypes := make([]string, 0, len(env.Types))
 
-	for typeName := range env.Types {
-		knownTypes = append(knownTypes, typeName)
-	}
-
-	// Suggest a type name based on the similarity to known functions
-	sort.Slice(knownTypes, func(a, b int) bool {
-		aSimilarity := similarity.JaroWinkler(pkgName, knownTypes[a])
-		bSimilarity := similarity.JaroWinkler(pkgName, knownTypes[b])
-		return aSimilarity > bSimilarity
-	})
-
-	if similarity.JaroWinkler(pkgName, knownTypes[0]) < 0.8 {
-		return &errors.UnknownType{Name: pkgName}
+	for name := range env.Types {
+		knownTypes = append(knownTypes, name)
 	}
 
 	return &errors.UnknownType{
-		Name:        pkgName,
-		CorrectName: knownTypes[0],
+		Name:        typeName,
+		CorrectName: closestName(typeName, knownTypes),
 	}
 }
 
 // UnknownFieldError produces an unknown field error
-// and tries to guess which type the user was trying to type.
+// and tries to guess which field the user was trying to type.
 func UnknownFieldError(field string, typ *types.Type) error {
 	knownFields := make([]string, 0, len(typ.Fields))
 
@@ -133,24 +84,72 @@ func UnknownFieldError(field string, typ *types.Type) error {
 		knownFields = append(knownFields, field.Name)
 	}
 
-	if len(knownFields) == 0 {
-		return &errors.UnknownField{TypeName: typ.Name, Name: field}
+	return &errors.UnknownField{
+		TypeName:    typ.Name,
+		Name:        field,
+		CorrectName: closestName(field, knownFields),
 	}
+}
 
-	// Suggest a type name based on the similarity to known functions
-	sort.Slice(knownFields, func(a, b int) bool {
-		aSimilarity := similarity.JaroWinkler(field, knownFields[a])
-		bSimilarity := similarity.JaroWinkler(field, knownFields[b])
-		return aSimilarity > bSimilarity
-	})
+// closestName returns the known name with the smallest edit distance to the given name.
+// Only names that differ in up to a third of the characters are considered to be typos,
+// otherwise the result is an empty string.
+// Names with the same distance are compared alphabetically.
+func closestName(name string, knownNames []string) string {
+	sort.Strings(knownNames)
+	maxDistance := (len([]rune(name)) + 1) / 3
+	closest := ""
+	closestDistance := maxDistance + 1
+
+	for _, knownName := range knownNames {
+		distance := levenshtein(name, knownName)
 
-	if similarity.JaroWinkler(field, knownFields[0]) < 0.8 {
-		return &errors.UnknownField{TypeName: typ.Name, Name: field}
+		if distance != 0 && distance < closestDistance {
+			closest = knownName
+			closestDistance = distance
+		}
 	}
 
-	return &errors.UnknownField{
-		TypeName:    typ.Name,
-		Name:        field,
-		CorrectName: knownFields[0],
+	return closest
+}
+
+// levenshtein returns the number of single character insertions, deletions
+// and substitutions that are needed to change a into b.
+func levenshtein(a string, b string) int {
+	runesA := []rune(a)
+	runesB := []rune(b)
+	previous := make([]int, len(runesB)+1)
+	current := make([]int, len(runesB)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(runesA); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(runesB); j++ {
+			deletion := previous[j] + 1
+			insertion := current[j-1] + 1
+			substitution := previous[j-1]
+
+			if runesA[i-1] != runesB[j-1] {
+				substitution++
+			}
+
+			current[j] = deletion
+
+			if insertion < current[j] {
+				current[j] = insertion
+			}
+
+			if substitution < current[j] {
+				current[j] = substitution
+			}
+		}
+
+		previous, current = current, previous
 	}
+
+	return previous[len(runesB)]
 }
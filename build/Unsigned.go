@@ -0,0 +1,25 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// isUnsignedOperation tells you whether one of the operand types is an unsigned integer.
+func isUnsignedOperation(left *types.Type, right *types.Type) bool {
+	return (left != nil && left.Unsigned) || (right != nil && right.Unsigned)
+}
+
+// literalType returns the type of the tokens when they are used together with a value of the other type.
+// Integer literals adapt to unsigned integer types, all other types remain unchanged.
+func literalType(tokens []token.Token, typ *types.Type, other *types.Type) *types.Type {
+	if typ != types.Int || other == nil || !other.Unsigned {
+		return typ
+	}
+
+	if len(tokens) != 1 || tokens[0].Kind != token.Number || IsFloatLiteral(tokens[0]) {
+		return typ
+	}
+
+	return other
+}
@@ -17,6 +17,11 @@ type Variable struct {
 	LastAssignUsed bool
 	Used           bool
 	Mutable        bool
+	IsParameter    bool
+	IsConstant     bool
+	IsLoopCounter  bool
+	IsText         bool
+	Value          int64
 	register       *register.Register
 }
 
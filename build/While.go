@@ -0,0 +1,85 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/akyoto/q/build/token"
+)
+
+// WhileState handles the state of while loop compilation.
+type WhileState struct {
+	counter int
+	stack   []WhileLoop
+}
+
+// WhileLoop represents a while loop.
+type WhileLoop struct {
+	labelStart string
+	labelEnd   string
+	variables  []*Variable
+}
+
+// WhileStart handles the start of while loops.
+func (state *State) WhileStart(tokens []token.Token) error {
+	state.Skip(token.Keyword)
+	state.scopes.Push()
+	condition := tokens[1:]
+
+	state.whileState.counter++
+	labelStart := fmt.Sprintf("while_%d", state.whileState.counter)
+	labelEnd := fmt.Sprintf("while_%d_end", state.whileState.counter)
+
+	whileLoop := WhileLoop{
+		labelStart: labelStart,
+		labelEnd:   labelEnd,
+	}
+
+	// The condition is re-evaluated on every iteration,
+	// therefore the variables used in the condition
+	// need to stay alive until the end of the loop.
+	for _, t := range condition {
+		if t.Kind != token.Identifier {
+			continue
+		}
+
+		variable := state.scopes.Get(t.Text())
+
+		if variable == nil {
+			continue
+		}
+
+		variable.KeepAlive++
+		whileLoop.variables = append(whileLoop.variables, variable)
+	}
+
+	state.assembler.AddLabel(labelStart)
+	state.whileState.stack = append(state.whileState.stack, whileLoop)
+	state.pushBreakLabels(labelEnd, labelStart)
+	return state.Condition(condition, labelEnd)
+}
+
+// WhileEnd handles the end of while loops.
+func (state *State) WhileEnd() error {
+	err := state.PopScope(true)
+
+	if err != nil {
+		return err
+	}
+
+	loop := state.whileState.stack[len(state.whileState.stack)-1]
+	state.whileState.stack = state.whileState.stack[:len(state.whileState.stack)-1]
+
+	state.assembler.Jump(loop.labelStart)
+	state.assembler.AddLabel(loop.labelEnd)
+	state.popBreakLabels()
+
+	for _, variable := range loop.variables {
+		variable.KeepAlive--
+
+		if variable.AliveUntil < state.tokenCursor {
+			variable.AliveUntil = state.tokenCursor
+		}
+	}
+
+	return nil
+}
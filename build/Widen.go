@@ -0,0 +1,40 @@
+package build
+
+import (
+	"github.com/akyoto/q/build/register"
+	"github.com/akyoto/q/build/types"
+)
+
+// widens tells you whether every value of the type can be represented by the other type.
+// Integers can be converted to larger integers of the same signedness
+// and unsigned integers can also be converted to larger signed integers.
+func widens(typ *types.Type, other *types.Type) bool {
+	if !isInteger(typ) || !isInteger(other) || typ.Size >= other.Size {
+		return false
+	}
+
+	return typ.Unsigned || !other.Unsigned
+}
+
+// isInteger tells you whether the type is a signed or unsigned integer.
+func isInteger(typ *types.Type) bool {
+	switch typ {
+	case types.Int64, types.Int32, types.Int16, types.Int8,
+		types.UInt64, types.UInt32, types.UInt16, types.UInt8:
+		return true
+	}
+
+	return false
+}
+
+// Widen extends the value of the given type in the register to 64 bits.
+// Loads of values smaller than 4 bytes leave the upper bits of the register unchanged,
+// therefore the extension always uses the lower bytes of the register.
+func (state *State) Widen(reg *register.Register, typ *types.Type) {
+	if typ.Unsigned {
+		state.assembler.ZeroExtendRegister(reg, reg, byte(typ.Size))
+		return
+	}
+
+	state.assembler.SignExtendRegister(reg, reg, byte(typ.Size))
+}
@@ -1,19 +1,43 @@
 package assembler
 
 import (
+	"encoding/binary"
+	"fmt"
+	"io"
 	"log"
+	"strings"
 
 	"github.com/akyoto/asm"
 	"github.com/akyoto/q/build/assembler/instructions"
+	"github.com/akyoto/q/build/assembler/mnemonics"
 	"github.com/akyoto/q/build/register"
 )
 
+// StringLengthSize is the number of bytes used to store the length in front of a string.
+const StringLengthSize = 8
+
 // Assembler produces machine code.
 type Assembler struct {
-	Instructions    []instruction
-	Verbose         bool
-	usedRegisterIDs []register.ID
-	final           *asm.Assembler
+	Instructions     []instruction
+	Verbose          bool
+	TailCalls        bool
+	TestZero         bool
+	usedRegisterIDs  []register.ID
+	savedRegisters   []register.ID
+	tailCalls        []instruction
+	relativePointers []asm.Pointer
+	stringAddresses  []uint32
+	lines            []Line
+	sourceLines      map[instruction]int
+	final            *asm.Assembler
+}
+
+// Line maps the address of the machine code for a source line to its position.
+type Line struct {
+	Address uint32
+	File    string
+	Line    int
+	Column  int
 }
 
 // New creates a new assembler.
@@ -25,6 +49,19 @@ func New(verbose bool) *Assembler {
 	}
 }
 
+// JumpsTo tells you whether one of the instructions jumps to the given label.
+func (a *Assembler) JumpsTo(label string) bool {
+	for _, instr := range a.Instructions {
+		jump, isJump := instr.(*instructions.Jump)
+
+		if isJump && jump.Label == label {
+			return true
+		}
+	}
+
+	return false
+}
+
 // AddLabel adds an instruction that adds a label.
 func (a *Assembler) AddLabel(labelName string) {
 	jump, isJump := a.lastInstruction().(*instructions.Jump)
@@ -41,22 +78,247 @@ func (a *Assembler) AddComment(message string) {
 	a.Instructions = append(a.Instructions, &instructions.AddComment{Comment: message})
 }
 
-// AddString adds a string.
+// AddSourceLine adds an instruction that marks the start of a source line.
+func (a *Assembler) AddSourceLine(file string, line int, column int) {
+	a.Instructions = append(a.Instructions, &instructions.SourceLine{File: file, Line: line, Column: column})
+}
+
+// SetSourceLine assigns the source line to the instructions starting at the given index
+// that don't have a source line yet. The lines are only shown in the verbose output.
+func (a *Assembler) SetSourceLine(start int, line int) {
+	if a.sourceLines == nil {
+		a.sourceLines = make(map[instruction]int)
+	}
+
+	for _, instr := range a.Instructions[start:] {
+		_, exists := a.sourceLines[instr]
+
+		if !exists {
+			a.sourceLines[instr] = line
+		}
+	}
+}
+
+// AddString adds a string that is prefixed by its 64-bit length
+// and returns the address of the first character.
 func (a *Assembler) AddString(text string) uint32 {
-	return a.final.AddData([]byte(text))
+	length := make([]byte, StringLengthSize)
+	binary.LittleEndian.PutUint64(length, uint64(len(text)))
+	a.final.AddData(length)
+	address := a.final.AddData([]byte(text))
+	a.stringAddresses = append(a.stringAddresses, address)
+	return address
 }
 
 // Finalize generates the final assembly code.
 func (a *Assembler) Finalize() *asm.Assembler {
+	prefix, local := a.localLabels()
+
 	for _, instr := range a.Instructions {
-		instr.Exec(a.final)
+		sourceLine, isSourceLine := instr.(*instructions.SourceLine)
+
+		if isSourceLine {
+			a.lines = append(a.lines, Line{
+				Address: uint32(a.final.Position()),
+				File:    sourceLine.File,
+				Line:    sourceLine.Line,
+				Column:  sourceLine.Column,
+			})
+		}
+
+		scope(instr, prefix, local).Exec(a.final)
+		address, isAddress := instr.(*instructions.RegisterAddress)
+
+		if isAddress && address.Mnemonic == mnemonics.LEA {
+			a.relativePointers = append(a.relativePointers, asm.Pointer{
+				Address:  address.Address,
+				Position: a.final.Position() - 4,
+			})
+		}
 	}
 
 	return a.final
 }
 
+// Fingerprint returns a key that is equal for functions with identical machine code.
+// The machine code alone isn't enough because the addresses of labels are only
+// resolved after merging, therefore the label references are included as well.
+// It is only available after the code has been finalized.
+func (a *Assembler) Fingerprint() string {
+	if len(a.Instructions) == 0 {
+		return ""
+	}
+
+	_, local := a.localLabels()
+	fingerprint := strings.Builder{}
+	fingerprint.Write(a.final.Code())
+	fingerprint.WriteByte(0)
+	fingerprint.Write(a.final.Data())
+
+	// The function label is skipped because it's the only difference between identical functions
+	for _, instr := range a.Instructions[1:] {
+		switch instr.(type) {
+		case *instructions.AddLabel, *instructions.Jump, *instructions.RegisterLabel, *instructions.RegisterAddress:
+			fingerprint.WriteByte(0)
+			fingerprint.WriteString(scope(instr, "", local).Assembly())
+		}
+	}
+
+	return fingerprint.String()
+}
+
+// RelativePointers returns the data references in the code that are relative to the instruction pointer.
+// The displacements still contain the data address and need to be patched by the linker.
+// It is only available after the code has been finalized.
+func (a *Assembler) RelativePointers() []asm.Pointer {
+	return a.relativePointers
+}
+
+// Lines returns the source lines with their addresses relative to the start of the function.
+// It is only available after the code has been finalized.
+func (a *Assembler) Lines() []Line {
+	return a.lines
+}
+
+// WriteAssembly writes the instructions in Intel syntax
+// followed by the data that is referenced by the instructions.
+func (a *Assembler) WriteAssembly(writer io.Writer) error {
+	prefix, local := a.localLabels()
+
+	for _, instr := range a.Instructions {
+		indent := "\t"
+
+		if instr.Name() == "LABEL" {
+			indent = ""
+		}
+
+		for _, line := range strings.Split(scope(instr, prefix, local).Assembly(), "\n") {
+			_, err := fmt.Fprintf(writer, "%s%s\n", indent, line)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteData writes the data section of the function.
+// Each string is preceded by its length and starts with a label.
+func (a *Assembler) WriteData(writer io.Writer) error {
+	prefix, _ := a.localLabels()
+	data := a.final.Data()
+	start := 0
+
+	for i, address := range append(a.stringAddresses, uint32(len(data))) {
+		end := int(address)
+
+		for position := start; position < end; position += 16 {
+			chunkEnd := position + 16
+
+			if chunkEnd > end {
+				chunkEnd = end
+			}
+
+			chunk := data[position:chunkEnd]
+			values := make([]string, len(chunk))
+
+			for i, value := range chunk {
+				values[i] = fmt.Sprintf("0x%02x", value)
+			}
+
+			_, err := fmt.Fprintf(writer, "\t.byte %s\n", strings.Join(values, ", "))
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// The last address marks the end of the data
+		if i < len(a.stringAddresses) {
+			_, err := fmt.Fprintf(writer, "%s:\n", instructions.Symbol(dataLabel(prefix, address)))
+
+			if err != nil {
+				return err
+			}
+		}
+
+		start = end
+	}
+
+	return nil
+}
+
+// scope returns a copy of the instruction that refers to function-scoped labels.
+// Local labels and data labels are prefixed with the function name.
+func scope(instr instruction, prefix string, local map[string]bool) instruction {
+	switch instr := instr.(type) {
+	case *instructions.AddLabel:
+		if local[instr.Label] {
+			scoped := *instr
+			scoped.Label = prefix + instr.Label
+			return &scoped
+		}
+
+	case *instructions.Jump:
+		if local[instr.Label] {
+			scoped := *instr
+			scoped.Label = prefix + instr.Label
+			return &scoped
+		}
+
+	case *instructions.RegisterAddress:
+		scoped := *instr
+		scoped.Label = dataLabel(prefix, instr.Address)
+		return &scoped
+	}
+
+	return instr
+}
+
+// dataLabel returns the label of the data at the given address.
+func dataLabel(prefix string, address uint32) string {
+	return fmt.Sprintf("%sdata_%d", prefix, address)
+}
+
+// localLabels returns the labels defined after the function label.
+// The labels of all functions share a single namespace after merging,
+// therefore local labels need to be prefixed with the function name.
+func (a *Assembler) localLabels() (string, map[string]bool) {
+	if len(a.Instructions) == 0 {
+		return "", nil
+	}
+
+	functionLabel, isLabel := a.Instructions[0].(*instructions.AddLabel)
+
+	if !isLabel {
+		return "", nil
+	}
+
+	local := map[string]bool{}
+
+	for _, instr := range a.Instructions[1:] {
+		label, isLabel := instr.(*instructions.AddLabel)
+
+		if isLabel {
+			local[label.Label] = true
+		}
+	}
+
+	return functionLabel.Label + ".", local
+}
+
 // UseRegisterID marks the given register ID as used.
+// Registers that are saved on the stack are restored later,
+// therefore modifying them doesn't count as a use.
 func (a *Assembler) UseRegisterID(newID register.ID) {
+	for _, id := range a.savedRegisters {
+		if id == newID {
+			return
+		}
+	}
+
 	for _, id := range a.usedRegisterIDs {
 		if id == newID {
 			return
@@ -72,25 +334,110 @@ func (a *Assembler) UsedRegisterIDs() []register.ID {
 }
 
 // WriteTo generates the final assembly code.
+// Instructions are followed by the line of the source code they were generated for.
 func (a *Assembler) WriteTo(logger *log.Logger) {
 	for _, instr := range a.Instructions {
 		switch instr.Name() {
 		case "LABEL":
 			logger.SetPrefix("")
+			logger.Println(instr.String())
+			continue
 
-		case "COMMENT":
+		case "COMMENT", "LINE":
 			logger.SetPrefix("  ")
+			logger.Println(instr.String())
+			continue
+		}
+
+		logger.SetPrefix("    ")
+		line := a.sourceLines[instr]
 
-		default:
-			logger.SetPrefix("    ")
+		if line == 0 {
+			logger.Println(instr.String())
+			continue
 		}
 
-		logger.Println(instr.String())
+		logger.Printf("%s  # line %d\n", instr.String(), line)
 	}
 
 	logger.SetPrefix("")
 }
 
+// IsUnreachable tells you whether the next instruction would never be executed
+// because it follows a return or an unconditional jump.
+func (a *Assembler) IsUnreachable() bool {
+	lastInstr := a.lastInstruction()
+
+	if lastInstr == nil {
+		return false
+	}
+
+	return lastInstr.Name() == mnemonics.RET || lastInstr.Name() == mnemonics.JMP
+}
+
+// LoadsStackStart tells you whether the code loads the stack pointer at program entry.
+func (a *Assembler) LoadsStackStart() bool {
+	for _, instr := range a.Instructions {
+		if instr.Name() == mnemonics.STACKSTART {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasCalls tells you whether the code contains a call to another function.
+func (a *Assembler) HasCalls() bool {
+	for _, instr := range a.Instructions {
+		if instr.Name() == mnemonics.CALL {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InsertStackCheck inserts a check after the function label
+// that jumps to the given label if the stack pointer is below the limit stored at fs:[0].
+func (a *Assembler) InsertStackCheck(label string) {
+	check := &instructions.Base{Mnemonic: mnemonics.STACKCHECK}
+	jump := &instructions.Jump{Label: label}
+	jump.SetName(mnemonics.JB)
+
+	a.Instructions = append(a.Instructions[:1], append([]instruction{check, jump}, a.Instructions[1:]...)...)
+}
+
+// ReserveStack subtracts the size from the stack pointer after the function label
+// and adds it back before each return and tail call.
+func (a *Assembler) ReserveStack(stack *register.Register, size uint64) {
+	reserve := &instructions.RegisterNumber{Destination: stack, Number: size}
+	reserve.SetName(mnemonics.SUB)
+	code := append(make([]instruction, 0, len(a.Instructions)+2), a.Instructions[0], reserve)
+
+	for _, instr := range a.Instructions[1:] {
+		if instr.Name() == mnemonics.RET || a.isTailCall(instr) {
+			release := &instructions.RegisterNumber{Destination: stack, Number: size}
+			release.SetName(mnemonics.ADD)
+			code = append(code, release)
+		}
+
+		code = append(code, instr)
+	}
+
+	a.Instructions = code
+}
+
+// isTailCall tells you whether the instruction is a call that was turned into a jump.
+func (a *Assembler) isTailCall(instr instruction) bool {
+	for _, tailCall := range a.tailCalls {
+		if tailCall == instr {
+			return true
+		}
+	}
+
+	return false
+}
+
 // lastInstruction returns the last added instruction.
 func (a *Assembler) lastInstruction() instruction {
 	if len(a.Instructions) == 0 {
@@ -116,6 +463,13 @@ func (a *Assembler) do(mnemonic string) {
 
 // doRegister adds an instruction with a single register operand.
 func (a *Assembler) doRegister(mnemonic string, destination *register.Register) {
+	a.addRegister(mnemonic, destination)
+	a.UseRegisterID(destination.ID)
+}
+
+// addRegister adds an instruction with a single register operand
+// without marking the register as used.
+func (a *Assembler) addRegister(mnemonic string, destination *register.Register) {
 	instr := &instructions.Register{
 		Destination: destination,
 	}
@@ -127,7 +481,32 @@ func (a *Assembler) doRegister(mnemonic string, destination *register.Register)
 	}
 
 	a.Instructions = append(a.Instructions, instr)
-	a.UseRegisterID(destination.ID)
+}
+
+// saveRegister pushes the register on the stack.
+// Pushing doesn't modify the register, therefore it's not marked as used.
+func (a *Assembler) saveRegister(destination *register.Register) {
+	a.addRegister(mnemonics.PUSH, destination)
+	a.savedRegisters = append(a.savedRegisters, destination.ID)
+}
+
+// restoreRegister pops the register from the stack.
+// Popping the last saved register restores its previous value,
+// only popping into a different register counts as a use.
+func (a *Assembler) restoreRegister(destination *register.Register) {
+	last := len(a.savedRegisters) - 1
+
+	if last >= 0 && a.savedRegisters[last] == destination.ID {
+		a.savedRegisters = a.savedRegisters[:last]
+		a.addRegister(mnemonics.POP, destination)
+		return
+	}
+
+	if last >= 0 {
+		a.savedRegisters = a.savedRegisters[:last]
+	}
+
+	a.doRegister(mnemonics.POP, destination)
 }
 
 // doRegisterRegister adds an instruction using 2 registers.
@@ -182,6 +561,23 @@ func (a *Assembler) doRegisterAddress(mnemonic string, destination *register.Reg
 	a.UseRegisterID(destination.ID)
 }
 
+// doRegisterLabel adds an instruction using a register and the address of a label.
+func (a *Assembler) doRegisterLabel(mnemonic string, destination *register.Register, label string) {
+	instr := &instructions.RegisterLabel{
+		Destination: destination,
+		Label:       label,
+	}
+
+	instr.SetName(mnemonic)
+
+	if a.Verbose {
+		instr.UsedBy = destination.UserString()
+	}
+
+	a.Instructions = append(a.Instructions, instr)
+	a.UseRegisterID(destination.ID)
+}
+
 // doMemoryNumber adds an instruction using a memory address and a number.
 func (a *Assembler) doMemoryNumber(mnemonic string, destination *register.Register, offset byte, byteCount byte, number uint64) {
 	instr := &instructions.MemoryNumber{
@@ -241,6 +637,26 @@ func (a *Assembler) doRegisterMemory(mnemonic string, destination *register.Regi
 	a.UseRegisterID(destination.ID)
 }
 
+// doRegisterEffectiveAddress adds an instruction using a register and an address calculation.
+func (a *Assembler) doRegisterEffectiveAddress(mnemonic string, destination *register.Register, base *register.Register, index *register.Register, scale byte, displacement int32) {
+	instr := &instructions.RegisterEffectiveAddress{
+		Destination:  destination,
+		Source:       base,
+		Index:        index,
+		Scale:        scale,
+		Displacement: displacement,
+	}
+
+	instr.SetName(mnemonic)
+
+	if a.Verbose {
+		instr.UsedBy = destination.UserString()
+	}
+
+	a.Instructions = append(a.Instructions, instr)
+	a.UseRegisterID(destination.ID)
+}
+
 // doJump adds a jump instruction with a label operand.
 func (a *Assembler) doJump(mnemonic string, labelName string) {
 	instr := &instructions.Jump{Label: labelName}
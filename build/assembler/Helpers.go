@@ -1,25 +1,26 @@
 package assembler
 
 import (
+	"github.com/akyoto/q/build/assembler/instructions"
 	"github.com/akyoto/q/build/assembler/mnemonics"
 	"github.com/akyoto/q/build/register"
 )
 
 func (a *Assembler) Return() {
+	// Avoid double return and unreachable returns after a jump
+	if a.IsUnreachable() {
+		return
+	}
+
 	lastInstr := a.lastInstruction()
 
-	if lastInstr != nil {
-		// Avoid double return
-		if lastInstr.Name() == mnemonics.RET {
-			return
-		}
-
-		// If the previous instruction was a call,
-		// change it to a jump.
-		// if lastInstr.Name() == CALL {
-		// 	lastInstr.SetName(JMP)
-		// 	return
-		// }
+	// If the previous instruction was a call,
+	// change it to a jump so that the callee
+	// returns directly to our caller.
+	if a.TailCalls && lastInstr != nil && lastInstr.Name() == mnemonics.CALL {
+		lastInstr.SetName(mnemonics.JMP)
+		a.tailCalls = append(a.tailCalls, lastInstr)
+		return
 	}
 
 	a.do(mnemonics.RET)
@@ -29,10 +30,46 @@ func (a *Assembler) Syscall() {
 	a.do(mnemonics.SYSCALL)
 }
 
+func (a *Assembler) CPUID() {
+	a.do(mnemonics.CPUID)
+}
+
+func (a *Assembler) Inline(mnemonic string) {
+	a.do(mnemonic)
+}
+
+func (a *Assembler) SizedRegister(mnemonic string, destination *register.Register, byteCount byte) {
+	a.doRegister(mnemonic, destination)
+
+	if byteCount != 8 {
+		a.lastInstruction().(*instructions.Register).ByteCount = byteCount
+	}
+}
+
+func (a *Assembler) SizedRegisterRegister(mnemonic string, destination *register.Register, source *register.Register, byteCount byte) {
+	a.doRegisterRegister(mnemonic, destination, source)
+
+	if byteCount != 8 {
+		a.lastInstruction().(*instructions.RegisterRegister).ByteCount = byteCount
+	}
+}
+
+func (a *Assembler) SizedRegisterNumber(mnemonic string, destination *register.Register, number uint64, byteCount byte) {
+	a.doRegisterNumber(mnemonic, destination, number)
+
+	if byteCount != 8 {
+		a.lastInstruction().(*instructions.RegisterNumber).ByteCount = byteCount
+	}
+}
+
 func (a *Assembler) Call(label string) {
 	a.doJump(mnemonics.CALL, label)
 }
 
+func (a *Assembler) CallRegister(destination *register.Register) {
+	a.addRegister(mnemonics.CALL, destination)
+}
+
 func (a *Assembler) Jump(label string) {
 	a.doJump(mnemonics.JMP, label)
 }
@@ -61,6 +98,66 @@ func (a *Assembler) JumpIfGreaterOrEqual(label string) {
 	a.doJump(mnemonics.JGE, label)
 }
 
+func (a *Assembler) JumpIfBelow(label string) {
+	a.doJump(mnemonics.JB, label)
+}
+
+func (a *Assembler) JumpIfBelowOrEqual(label string) {
+	a.doJump(mnemonics.JBE, label)
+}
+
+func (a *Assembler) JumpIfAbove(label string) {
+	a.doJump(mnemonics.JA, label)
+}
+
+func (a *Assembler) JumpIfAboveOrEqual(label string) {
+	a.doJump(mnemonics.JAE, label)
+}
+
+func (a *Assembler) JumpIfOverflow(label string) {
+	a.doJump(mnemonics.JO, label)
+}
+
+func (a *Assembler) SetRegisterIfEqual(destination *register.Register) {
+	a.doRegister(mnemonics.SETE, destination)
+}
+
+func (a *Assembler) SetRegisterIfNotEqual(destination *register.Register) {
+	a.doRegister(mnemonics.SETNE, destination)
+}
+
+func (a *Assembler) SetRegisterIfLess(destination *register.Register) {
+	a.doRegister(mnemonics.SETL, destination)
+}
+
+func (a *Assembler) SetRegisterIfLessOrEqual(destination *register.Register) {
+	a.doRegister(mnemonics.SETLE, destination)
+}
+
+func (a *Assembler) SetRegisterIfGreater(destination *register.Register) {
+	a.doRegister(mnemonics.SETG, destination)
+}
+
+func (a *Assembler) SetRegisterIfGreaterOrEqual(destination *register.Register) {
+	a.doRegister(mnemonics.SETGE, destination)
+}
+
+func (a *Assembler) SetRegisterIfBelow(destination *register.Register) {
+	a.doRegister(mnemonics.SETB, destination)
+}
+
+func (a *Assembler) SetRegisterIfBelowOrEqual(destination *register.Register) {
+	a.doRegister(mnemonics.SETBE, destination)
+}
+
+func (a *Assembler) SetRegisterIfAbove(destination *register.Register) {
+	a.doRegister(mnemonics.SETA, destination)
+}
+
+func (a *Assembler) SetRegisterIfAboveOrEqual(destination *register.Register) {
+	a.doRegister(mnemonics.SETAE, destination)
+}
+
 func (a *Assembler) IncreaseRegister(destination *register.Register) {
 	a.doRegister(mnemonics.INC, destination)
 }
@@ -69,12 +166,25 @@ func (a *Assembler) DecreaseRegister(destination *register.Register) {
 	a.doRegister(mnemonics.DEC, destination)
 }
 
+func (a *Assembler) NegateRegister(destination *register.Register) {
+	a.doRegister(mnemonics.NEG, destination)
+}
+
+func (a *Assembler) NotRegister(destination *register.Register) {
+	a.doRegister(mnemonics.NOT, destination)
+}
+
 func (a *Assembler) PushRegister(destination *register.Register) {
-	a.doRegister(mnemonics.PUSH, destination)
+	a.saveRegister(destination)
 }
 
 func (a *Assembler) PopRegister(destination *register.Register) {
-	a.doRegister(mnemonics.POP, destination)
+	a.restoreRegister(destination)
+	destination.Assign()
+}
+
+func (a *Assembler) LoadStackStart(destination *register.Register) {
+	a.doRegister(mnemonics.STACKSTART, destination)
 	destination.Assign()
 }
 
@@ -82,6 +192,14 @@ func (a *Assembler) DivRegister(destination *register.Register) {
 	a.doRegister(mnemonics.DIV, destination)
 }
 
+func (a *Assembler) UnsignedDivRegister(destination *register.Register) {
+	a.doRegister(mnemonics.UDIV, destination)
+}
+
+func (a *Assembler) MulRegister(destination *register.Register) {
+	a.doRegister(mnemonics.MUL, destination)
+}
+
 func (a *Assembler) SignExtendToDX(destination *register.Register) {
 	a.doRegister(mnemonics.CDQ, destination)
 }
@@ -91,11 +209,28 @@ func (a *Assembler) MoveRegisterRegister(destination *register.Register, source
 	destination.Assign()
 }
 
+func (a *Assembler) ZeroExtendRegister(destination *register.Register, source *register.Register, byteCount byte) {
+	a.doRegisterRegister(mnemonics.MOVZX, destination, source)
+	a.lastInstruction().(*instructions.RegisterRegister).ByteCount = byteCount
+	destination.Assign()
+}
+
+func (a *Assembler) SignExtendRegister(destination *register.Register, source *register.Register, byteCount byte) {
+	a.doRegisterRegister(mnemonics.MOVSX, destination, source)
+	a.lastInstruction().(*instructions.RegisterRegister).ByteCount = byteCount
+	destination.Assign()
+}
+
 func (a *Assembler) MoveRegisterNumber(destination *register.Register, number uint64) {
 	a.doRegisterNumber(mnemonics.MOV, destination, number)
 	destination.Assign()
 }
 
+func (a *Assembler) LoadAddress(destination *register.Register, base *register.Register, index *register.Register, scale byte, displacement int32) {
+	a.doRegisterEffectiveAddress(mnemonics.LEA, destination, base, index, scale, displacement)
+	destination.Assign()
+}
+
 func (a *Assembler) StoreNumber(destination *register.Register, offset byte, byteCount byte, number uint64) {
 	a.doMemoryNumber(mnemonics.STORE, destination, offset, byteCount, number)
 }
@@ -104,24 +239,70 @@ func (a *Assembler) StoreRegister(destination *register.Register, offset byte, b
 	a.doMemoryRegister(mnemonics.STORE, destination, offset, byteCount, source)
 }
 
+func (a *Assembler) StoreRegisterAt(position int, destination *register.Register, offset byte, byteCount byte, source *register.Register) {
+	a.doMemoryRegister(mnemonics.STORE, destination, offset, byteCount, source)
+	store := a.lastInstruction()
+	copy(a.Instructions[position+1:], a.Instructions[position:len(a.Instructions)-1])
+	a.Instructions[position] = store
+}
+
 func (a *Assembler) LoadRegister(destination *register.Register, source *register.Register, offset byte, byteCount byte) {
 	a.doRegisterMemory(mnemonics.LOAD, destination, source, offset, byteCount)
 	destination.Assign()
 }
 
+func (a *Assembler) LoadZeroExtend(destination *register.Register, source *register.Register, offset byte, byteCount byte) {
+	a.doRegisterMemory(mnemonics.LOADZX, destination, source, offset, byteCount)
+	destination.Assign()
+}
+
+func (a *Assembler) LoadSignExtend(destination *register.Register, source *register.Register, offset byte, byteCount byte) {
+	a.doRegisterMemory(mnemonics.LOADSX, destination, source, offset, byteCount)
+	destination.Assign()
+}
+
 func (a *Assembler) MoveRegisterAddress(destination *register.Register, address uint32) {
 	a.doRegisterAddress(mnemonics.MOV, destination, address)
 	destination.Assign()
 }
 
+func (a *Assembler) LoadStringAddress(destination *register.Register, address uint32) {
+	a.doRegisterAddress(mnemonics.LEA, destination, address)
+	destination.Assign()
+}
+
+func (a *Assembler) MoveRegisterFunctionAddress(destination *register.Register, label string) {
+	a.doRegisterLabel(mnemonics.LEA, destination, label)
+	destination.Assign()
+}
+
 func (a *Assembler) CompareRegisterRegister(destination *register.Register, source *register.Register) {
 	a.doRegisterRegister(mnemonics.CMP, destination, source)
 }
 
+func (a *Assembler) MoveIfLess(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.CMOVL, destination, source)
+}
+
+func (a *Assembler) MoveIfGreater(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.CMOVG, destination, source)
+}
+
 func (a *Assembler) CompareRegisterNumber(destination *register.Register, number uint64) {
+	// A test of the register with itself is shorter
+	// and sets the same flags as a comparison with zero.
+	if a.TestZero && number == 0 {
+		a.TestRegisterRegister(destination, destination)
+		return
+	}
+
 	a.doRegisterNumber(mnemonics.CMP, destination, number)
 }
 
+func (a *Assembler) TestRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.TEST, destination, source)
+}
+
 func (a *Assembler) AddRegisterRegister(destination *register.Register, source *register.Register) {
 	a.doRegisterRegister(mnemonics.ADD, destination, source)
 }
@@ -145,3 +326,91 @@ func (a *Assembler) MulRegisterRegister(destination *register.Register, source *
 func (a *Assembler) MulRegisterNumber(destination *register.Register, number uint64) {
 	a.doRegisterNumber(mnemonics.MUL, destination, number)
 }
+
+func (a *Assembler) AndRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.AND, destination, source)
+}
+
+func (a *Assembler) AndRegisterNumber(destination *register.Register, number uint64) {
+	a.doRegisterNumber(mnemonics.AND, destination, number)
+}
+
+func (a *Assembler) OrRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.OR, destination, source)
+}
+
+func (a *Assembler) OrRegisterNumber(destination *register.Register, number uint64) {
+	a.doRegisterNumber(mnemonics.OR, destination, number)
+}
+
+func (a *Assembler) XorRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.XOR, destination, source)
+}
+
+func (a *Assembler) XorRegisterNumber(destination *register.Register, number uint64) {
+	a.doRegisterNumber(mnemonics.XOR, destination, number)
+}
+
+func (a *Assembler) ShiftLeftRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.SHL, destination, source)
+}
+
+func (a *Assembler) ShiftLeftRegisterNumber(destination *register.Register, number uint64) {
+	a.doRegisterNumber(mnemonics.SHL, destination, number)
+}
+
+func (a *Assembler) ShiftRightRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.SAR, destination, source)
+}
+
+func (a *Assembler) ShiftLeftRegisterRegisterBMI2(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.SHLX, destination, source)
+}
+
+func (a *Assembler) ShiftRightRegisterRegisterBMI2(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.SARX, destination, source)
+}
+
+func (a *Assembler) ShiftRightRegisterNumber(destination *register.Register, number uint64) {
+	a.doRegisterNumber(mnemonics.SAR, destination, number)
+}
+
+func (a *Assembler) ShiftRightLogicalRegisterNumber(destination *register.Register, number uint64) {
+	a.doRegisterNumber(mnemonics.SHR, destination, number)
+}
+
+func (a *Assembler) MoveFloatRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.MOVQ, destination, source)
+	destination.Assign()
+}
+
+func (a *Assembler) AddFloatRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.ADDSD, destination, source)
+}
+
+func (a *Assembler) SubFloatRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.SUBSD, destination, source)
+}
+
+func (a *Assembler) MulFloatRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.MULSD, destination, source)
+}
+
+func (a *Assembler) DivFloatRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.DIVSD, destination, source)
+}
+
+func (a *Assembler) IntToFloatRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.CVTSI2SD, destination, source)
+	destination.Assign()
+}
+
+func (a *Assembler) FloatToIntRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.CVTSD2SI, destination, source)
+	destination.Assign()
+}
+
+func (a *Assembler) TruncateFloatToIntRegisterRegister(destination *register.Register, source *register.Register) {
+	a.doRegisterRegister(mnemonics.CVTTSD2SI, destination, source)
+	destination.Assign()
+}
@@ -7,5 +7,6 @@ type instruction interface {
 	Name() string
 	SetName(string)
 	String() string
+	Assembly() string
 	Size() byte
 }
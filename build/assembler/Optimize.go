@@ -3,6 +3,7 @@ package assembler
 import (
 	"github.com/akyoto/q/build/assembler/instructions"
 	"github.com/akyoto/q/build/assembler/mnemonics"
+	"github.com/akyoto/q/build/register"
 )
 
 // Optimize optimizes the assembler instructions for improved performance.
@@ -28,7 +29,7 @@ func (a *Assembler) Optimize() {
 				continue
 			}
 
-			if nextInstr.Mnemonic != mnemonics.MOV {
+			if nextInstr.Mnemonic != mnemonics.MOV || nextInstr.ByteCount != 0 {
 				continue
 			}
 
@@ -63,7 +64,7 @@ func (a *Assembler) Optimize() {
 
 					if reg2.Destination == source {
 						// Overwriting the register means it's safe to optimize
-						if reg2.Mnemonic == mnemonics.MOV {
+						if reg2.Mnemonic == mnemonics.MOV && !partialWrite(reg2.ByteCount) {
 							break
 						}
 
@@ -95,3 +96,318 @@ func (a *Assembler) Optimize() {
 		}
 	}
 }
+
+// RemoveUnreachable removes the instructions after a return or an unconditional jump
+// until the next label that is the target of a jump.
+// --------------------------------------------
+// jmp label
+// mov reg, 1
+// label:
+// --------------------------------------------
+// label:
+// --------------------------------------------
+// The jump is removed as well because it only skipped the unreachable code.
+// This mostly affects the branches of conditions that are known at compile time.
+// --------------------------------------------
+func (a *Assembler) RemoveUnreachable() {
+	targets := map[string]bool{}
+
+	for _, instr := range a.Instructions {
+		switch instr := instr.(type) {
+		case *instructions.Jump:
+			targets[instr.Label] = true
+
+		case *instructions.RegisterLabel:
+			targets[instr.Label] = true
+		}
+	}
+
+	code := a.Instructions[:0]
+	unreachable := false
+
+	for _, instr := range a.Instructions {
+		label, isLabel := instr.(*instructions.AddLabel)
+
+		if isLabel && targets[label.Label] {
+			unreachable = false
+
+			if len(code) > 0 {
+				jump, isJump := code[len(code)-1].(*instructions.Jump)
+
+				if isJump && jump.Mnemonic == mnemonics.JMP && jump.Label == label.Label {
+					code = code[:len(code)-1]
+				}
+			}
+		}
+
+		if unreachable {
+			continue
+		}
+
+		code = append(code, instr)
+
+		if instr.Name() == mnemonics.RET || instr.Name() == mnemonics.JMP {
+			unreachable = true
+		}
+	}
+
+	a.Instructions = code
+}
+
+// RemoveRedundantInstructions removes instructions that have no effect.
+// --------------------------------------------
+// mov reg, reg
+// --------------------------------------------
+// push reg
+// pop reg
+// --------------------------------------------
+// Both patterns leave all registers and the stack unchanged.
+// Push and pop are only removed if they are directly adjacent
+// so that no label or other instruction depends on the pushed value.
+// --------------------------------------------
+func (a *Assembler) RemoveRedundantInstructions() {
+	code := a.Instructions[:0]
+
+	for _, instr := range a.Instructions {
+		move, ok := instr.(*instructions.RegisterRegister)
+
+		if ok && move.Mnemonic == mnemonics.MOV && move.Destination == move.Source && move.ByteCount == 0 {
+			continue
+		}
+
+		pop, ok := instr.(*instructions.Register)
+
+		if ok && pop.Mnemonic == mnemonics.POP && len(code) > 0 {
+			push, ok := code[len(code)-1].(*instructions.Register)
+
+			if ok && push.Mnemonic == mnemonics.PUSH && push.Destination == pop.Destination {
+				code = code[:len(code)-1]
+				continue
+			}
+		}
+
+		code = append(code, instr)
+	}
+
+	a.Instructions = code
+}
+
+// ZeroWithXor replaces moves of zero by the shorter xor of a register with itself.
+// --------------------------------------------
+// mov reg, 0
+// --------------------------------------------
+// xor reg, reg
+// --------------------------------------------
+// The xor modifies the flags, therefore the move is only replaced
+// if the following code doesn't read the flags before they're overwritten.
+// --------------------------------------------
+func (a *Assembler) ZeroWithXor() {
+	for index, instr := range a.Instructions {
+		move, ok := instr.(*instructions.RegisterNumber)
+
+		if !ok || move.Mnemonic != mnemonics.MOV || move.Number != 0 || move.ByteCount != 0 {
+			continue
+		}
+
+		if flagsLive(a.Instructions[index+1:]) {
+			continue
+		}
+
+		xor := &instructions.RegisterRegister{
+			Destination: move.Destination,
+			Source:      move.Destination,
+			UsedBy1:     move.UsedBy,
+			UsedBy2:     move.UsedBy,
+		}
+
+		xor.SetName(mnemonics.XOR)
+		a.Instructions[index] = xor
+	}
+}
+
+// flagsLive tells you whether the code reads the flags before they're overwritten.
+// Jumps are followed by unknown code, therefore the flags are considered to be live.
+func flagsLive(code []instruction) bool {
+	for _, instr := range code {
+		switch instr.Name() {
+		case mnemonics.JMP,
+			mnemonics.JE, mnemonics.JNE, mnemonics.JL, mnemonics.JLE, mnemonics.JG, mnemonics.JGE,
+			mnemonics.JB, mnemonics.JBE, mnemonics.JA, mnemonics.JAE, mnemonics.JO,
+			mnemonics.CMOVL, mnemonics.CMOVG,
+			mnemonics.SETE, mnemonics.SETNE, mnemonics.SETL, mnemonics.SETLE, mnemonics.SETG, mnemonics.SETGE,
+			mnemonics.SETB, mnemonics.SETBE, mnemonics.SETA, mnemonics.SETAE:
+			return true
+
+		case mnemonics.CMP, mnemonics.TEST, mnemonics.ADD, mnemonics.SUB, mnemonics.AND, mnemonics.OR, mnemonics.XOR:
+			return false
+
+		case mnemonics.CALL, mnemonics.RET:
+			return false
+		}
+	}
+
+	return false
+}
+
+// ForwardMoves removes moves whose destination is only read once by the next instruction.
+// --------------------------------------------
+// mov reg1, reg2
+// add reg3, reg1
+// --------------------------------------------
+// add reg3, reg2
+// --------------------------------------------
+// This mostly affects return values that are stored in a variable
+// and immediately passed on, therefore reg2 is often the return value register.
+// reg1 can't be a register that is read implicitly by other instructions
+// and it must not be used in the remaining code before it is overwritten.
+// --------------------------------------------
+func (a *Assembler) ForwardMoves() {
+	for index := 0; index+1 < len(a.Instructions); index++ {
+		move, ok := a.Instructions[index].(*instructions.RegisterRegister)
+
+		if !ok || move.Mnemonic != mnemonics.MOV || move.ByteCount != 0 || move.Destination == move.Source || implicitRegisters[move.Destination.Name] {
+			continue
+		}
+
+		next, ok := a.Instructions[index+1].(*instructions.RegisterRegister)
+
+		if !ok || next.Source != move.Destination || next.Destination == move.Destination {
+			continue
+		}
+
+		// The shift count needs to be in cl
+		if next.Mnemonic == mnemonics.SHL || next.Mnemonic == mnemonics.SAR {
+			continue
+		}
+
+		if a.registerLive(a.Instructions[index+2:], move.Destination) {
+			continue
+		}
+
+		next.Source = move.Source
+		next.UsedBy2 = move.UsedBy2
+		a.Instructions = append(a.Instructions[:index], a.Instructions[index+1:]...)
+	}
+}
+
+// partialWrite tells you whether a move with the given byte count keeps the upper bits of the register.
+// Moves to the lower 32 bits clear the upper 32 bits.
+func partialWrite(byteCount byte) bool {
+	return byteCount == 1 || byteCount == 2
+}
+
+// implicitRegisters contains the registers that are read by instructions without being an operand,
+// like the parameters of calls and system calls or the dividend of a division.
+var implicitRegisters = map[string]bool{
+	"rax": true,
+	"rcx": true,
+	"rdx": true,
+	"rdi": true,
+	"rsi": true,
+	"r8":  true,
+	"r9":  true,
+	"r10": true,
+	"r11": true,
+	"rsp": true,
+}
+
+// registerLive tells you whether the code reads the register before it is overwritten.
+// Jumps inside the function are followed by unknown code, therefore the register is considered to be live.
+// Calls and tail calls only read the parameters which are passed in implicit registers.
+func (a *Assembler) registerLive(code []instruction, reg *register.Register) bool {
+	for _, instr := range code {
+		switch instr := instr.(type) {
+		case *instructions.Register:
+			if instr.Destination != reg {
+				continue
+			}
+
+			switch instr.Mnemonic {
+			case mnemonics.POP, mnemonics.STACKSTART,
+				mnemonics.SETE, mnemonics.SETNE, mnemonics.SETL, mnemonics.SETLE, mnemonics.SETG, mnemonics.SETGE,
+				mnemonics.SETB, mnemonics.SETBE, mnemonics.SETA, mnemonics.SETAE:
+				return false
+			}
+
+			return true
+
+		case *instructions.RegisterRegister:
+			if instr.Source == reg {
+				return true
+			}
+
+			if instr.Destination != reg {
+				continue
+			}
+
+			switch instr.Mnemonic {
+			case mnemonics.MOV:
+				return partialWrite(instr.ByteCount)
+
+			case mnemonics.MOVZX, mnemonics.MOVSX, mnemonics.MOVQ, mnemonics.CVTSD2SI, mnemonics.CVTTSD2SI:
+				return false
+			}
+
+			return true
+
+		case *instructions.RegisterNumber:
+			if instr.Destination == reg {
+				return instr.Mnemonic != mnemonics.MOV || partialWrite(instr.ByteCount)
+			}
+
+		case *instructions.RegisterMemory:
+			if instr.Source == reg {
+				return true
+			}
+
+			if instr.Destination == reg {
+				return false
+			}
+
+		case *instructions.RegisterEffectiveAddress:
+			if instr.Source == reg || instr.Index == reg {
+				return true
+			}
+
+			if instr.Destination == reg {
+				return false
+			}
+
+		case *instructions.RegisterAddress:
+			if instr.Destination == reg {
+				return false
+			}
+
+		case *instructions.RegisterLabel:
+			if instr.Destination == reg {
+				return false
+			}
+
+		case *instructions.MemoryRegister:
+			if instr.Destination == reg || instr.Source == reg {
+				return true
+			}
+
+		case *instructions.MemoryNumber:
+			if instr.Destination == reg {
+				return true
+			}
+
+		case *instructions.Jump:
+			if a.isTailCall(instr) {
+				return false
+			}
+
+			if instr.Mnemonic != mnemonics.CALL {
+				return true
+			}
+
+		case *instructions.Base:
+			if instr.Mnemonic == mnemonics.RET {
+				return false
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,249 @@
+package assembler
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/akyoto/q/build/assembler/instructions"
+	"github.com/akyoto/q/build/register"
+)
+
+// Snapshot is the serializable form of an assembler.
+// Registers are stored by their ID and strings by their contents.
+type Snapshot struct {
+	Instructions    []SnapshotInstruction
+	UsedRegisterIDs []register.ID
+	Strings         []string
+	Verbose         bool
+	TailCalls       bool
+	TestZero        bool
+}
+
+// SnapshotInstruction is the serializable form of a single instruction.
+// The kind determines which of the other fields are used.
+type SnapshotInstruction struct {
+	Kind        string
+	Mnemonic    string
+	Text        string
+	Destination register.ID
+	Source      register.ID
+	Index       register.ID
+	Number      uint64
+	Address     uint32
+	Offset      byte
+	ByteCount   byte
+	UsedBy1     string
+	UsedBy2     string
+	Line        int
+}
+
+// Snapshot returns the serializable form of the assembler.
+func (a *Assembler) Snapshot() (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Instructions:    make([]SnapshotInstruction, 0, len(a.Instructions)),
+		UsedRegisterIDs: a.usedRegisterIDs,
+		Strings:         make([]string, 0, len(a.stringAddresses)),
+		Verbose:         a.Verbose,
+		TailCalls:       a.TailCalls,
+		TestZero:        a.TestZero,
+	}
+
+	data := a.final.Data()
+
+	for _, address := range a.stringAddresses {
+		length := binary.LittleEndian.Uint64(data[address-StringLengthSize : address])
+		snapshot.Strings = append(snapshot.Strings, string(data[address:address+uint32(length)]))
+	}
+
+	for _, instr := range a.Instructions {
+		snap := SnapshotInstruction{Mnemonic: instr.Name(), Line: a.sourceLines[instr]}
+
+		switch instr := instr.(type) {
+		case *instructions.AddComment:
+			snap.Kind = "AddComment"
+			snap.Text = instr.Comment
+
+		case *instructions.AddLabel:
+			snap.Kind = "AddLabel"
+			snap.Text = instr.Label
+
+		case *instructions.Base:
+			snap.Kind = "Base"
+
+		case *instructions.SourceLine:
+			snap.Kind = "SourceLine"
+			snap.Text = instr.File
+			snap.Number = uint64(instr.Line)
+			snap.Address = uint32(instr.Column)
+
+		case *instructions.Jump:
+			snap.Kind = "Jump"
+			snap.Text = instr.Label
+
+		case *instructions.MemoryNumber:
+			snap.Kind = "MemoryNumber"
+			snap.Destination = instr.Destination.ID
+			snap.Number = instr.Number
+			snap.Offset = instr.Offset
+			snap.ByteCount = instr.ByteCount
+			snap.UsedBy1 = instr.UsedBy
+
+		case *instructions.MemoryRegister:
+			snap.Kind = "MemoryRegister"
+			snap.Destination = instr.Destination.ID
+			snap.Source = instr.Source.ID
+			snap.Offset = instr.Offset
+			snap.ByteCount = instr.ByteCount
+			snap.UsedBy1 = instr.UsedBy1
+			snap.UsedBy2 = instr.UsedBy2
+
+		case *instructions.Register:
+			snap.Kind = "Register"
+			snap.Destination = instr.Destination.ID
+			snap.ByteCount = instr.ByteCount
+			snap.UsedBy1 = instr.UsedBy
+
+		case *instructions.RegisterAddress:
+			snap.Kind = "RegisterAddress"
+			snap.Destination = instr.Destination.ID
+			snap.Address = instr.Address
+			snap.UsedBy1 = instr.UsedBy
+
+		case *instructions.RegisterLabel:
+			snap.Kind = "RegisterLabel"
+			snap.Destination = instr.Destination.ID
+			snap.Text = instr.Label
+			snap.UsedBy1 = instr.UsedBy
+
+		case *instructions.RegisterMemory:
+			snap.Kind = "RegisterMemory"
+			snap.Destination = instr.Destination.ID
+			snap.Source = instr.Source.ID
+			snap.Offset = instr.Offset
+			snap.ByteCount = instr.ByteCount
+			snap.UsedBy1 = instr.UsedBy1
+			snap.UsedBy2 = instr.UsedBy2
+
+		case *instructions.RegisterEffectiveAddress:
+			snap.Kind = "RegisterEffectiveAddress"
+			snap.Destination = instr.Destination.ID
+			snap.Source = instr.Source.ID
+			snap.ByteCount = instr.Scale
+			snap.Address = uint32(instr.Displacement)
+			snap.UsedBy1 = instr.UsedBy
+
+			if instr.Scale != 0 {
+				snap.Index = instr.Index.ID
+			}
+
+		case *instructions.RegisterNumber:
+			snap.Kind = "RegisterNumber"
+			snap.Destination = instr.Destination.ID
+			snap.Number = instr.Number
+			snap.ByteCount = instr.ByteCount
+			snap.UsedBy1 = instr.UsedBy
+
+		case *instructions.RegisterRegister:
+			snap.Kind = "RegisterRegister"
+			snap.Destination = instr.Destination.ID
+			snap.Source = instr.Source.ID
+			snap.ByteCount = instr.ByteCount
+			snap.UsedBy1 = instr.UsedBy1
+			snap.UsedBy2 = instr.UsedBy2
+
+		default:
+			return nil, fmt.Errorf("Unknown instruction type %T", instr)
+		}
+
+		snapshot.Instructions = append(snapshot.Instructions, snap)
+	}
+
+	return snapshot, nil
+}
+
+// FromSnapshot recreates an assembler from its serializable form.
+// The instructions refer to the registers of a new register manager.
+func FromSnapshot(snapshot *Snapshot) (*Assembler, error) {
+	a := New(snapshot.Verbose)
+	a.TailCalls = snapshot.TailCalls
+	a.TestZero = snapshot.TestZero
+	a.usedRegisterIDs = snapshot.UsedRegisterIDs
+	registers := register.NewManager()
+
+	// The strings are added in the original order
+	// so that the data addresses remain the same.
+	for _, text := range snapshot.Strings {
+		a.AddString(text)
+	}
+
+	for _, snap := range snapshot.Instructions {
+		var instr instruction
+
+		if int(snap.Destination) >= len(registers.All) || int(snap.Source) >= len(registers.All) {
+			return nil, fmt.Errorf("Invalid register ID in instruction %s", snap.Mnemonic)
+		}
+
+		destination := registers.ByID(snap.Destination)
+		source := registers.ByID(snap.Source)
+
+		switch snap.Kind {
+		case "AddComment":
+			instr = &instructions.AddComment{Comment: snap.Text}
+
+		case "AddLabel":
+			instr = &instructions.AddLabel{Label: snap.Text}
+
+		case "Base":
+			instr = &instructions.Base{}
+
+		case "Jump":
+			instr = &instructions.Jump{Label: snap.Text}
+
+		case "SourceLine":
+			instr = &instructions.SourceLine{File: snap.Text, Line: int(snap.Number), Column: int(snap.Address)}
+
+		case "MemoryNumber":
+			instr = &instructions.MemoryNumber{Destination: destination, Number: snap.Number, Offset: snap.Offset, ByteCount: snap.ByteCount, UsedBy: snap.UsedBy1}
+
+		case "MemoryRegister":
+			instr = &instructions.MemoryRegister{Destination: destination, Source: source, Offset: snap.Offset, ByteCount: snap.ByteCount, UsedBy1: snap.UsedBy1, UsedBy2: snap.UsedBy2}
+
+		case "Register":
+			instr = &instructions.Register{Destination: destination, UsedBy: snap.UsedBy1, ByteCount: snap.ByteCount}
+
+		case "RegisterAddress":
+			instr = &instructions.RegisterAddress{Destination: destination, Address: snap.Address, UsedBy: snap.UsedBy1}
+
+		case "RegisterLabel":
+			instr = &instructions.RegisterLabel{Destination: destination, Label: snap.Text, UsedBy: snap.UsedBy1}
+
+		case "RegisterMemory":
+			instr = &instructions.RegisterMemory{Destination: destination, Source: source, Offset: snap.Offset, ByteCount: snap.ByteCount, UsedBy1: snap.UsedBy1, UsedBy2: snap.UsedBy2}
+
+		case "RegisterEffectiveAddress":
+			if int(snap.Index) >= len(registers.All) {
+				return nil, fmt.Errorf("Invalid register ID in instruction %s", snap.Mnemonic)
+			}
+
+			instr = &instructions.RegisterEffectiveAddress{Destination: destination, Source: source, Index: registers.ByID(snap.Index), Scale: snap.ByteCount, Displacement: int32(snap.Address), UsedBy: snap.UsedBy1}
+
+		case "RegisterNumber":
+			instr = &instructions.RegisterNumber{Destination: destination, Number: snap.Number, UsedBy: snap.UsedBy1, ByteCount: snap.ByteCount}
+
+		case "RegisterRegister":
+			instr = &instructions.RegisterRegister{Destination: destination, Source: source, UsedBy1: snap.UsedBy1, UsedBy2: snap.UsedBy2, ByteCount: snap.ByteCount}
+
+		default:
+			return nil, fmt.Errorf("Unknown instruction kind %s", snap.Kind)
+		}
+
+		instr.SetName(snap.Mnemonic)
+		a.Instructions = append(a.Instructions, instr)
+
+		if snap.Line != 0 {
+			a.SetSourceLine(len(a.Instructions)-1, snap.Line)
+		}
+	}
+
+	return a, nil
+}
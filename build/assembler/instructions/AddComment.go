@@ -34,3 +34,8 @@ func (instr *AddComment) Size() byte {
 func (instr *AddComment) String() string {
 	return log.CommentColor.Sprint(instr.Comment)
 }
+
+// Assembly returns the comment.
+func (instr *AddComment) Assembly() string {
+	return "# " + instr.Comment
+}
@@ -36,3 +36,8 @@ func (instr *AddLabel) Size() byte {
 func (instr *AddLabel) String() string {
 	return fmt.Sprintf("%s:", log.FaintColor.Sprint(instr.Label))
 }
+
+// Assembly returns the label definition.
+func (instr *AddLabel) Assembly() string {
+	return Symbol(instr.Label) + ":"
+}
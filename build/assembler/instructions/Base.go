@@ -39,6 +39,10 @@ func (instr *Base) Exec(a *asm.Assembler) {
 
 	case mnemonics.CPUID:
 		a.CPUID()
+
+	// cmp rsp, qword ptr fs:[0]
+	case mnemonics.STACKCHECK:
+		a.WriteBytes(0x64, 0x48, 0x3b, 0x24, 0x25, 0, 0, 0, 0)
 	}
 
 	instr.size = byte(a.Position() - start)
@@ -48,3 +52,12 @@ func (instr *Base) Exec(a *asm.Assembler) {
 func (instr *Base) String() string {
 	return mnemonicColor.Sprint(instr.Mnemonic)
 }
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *Base) Assembly() string {
+	if instr.Mnemonic == mnemonics.STACKCHECK {
+		return "cmp rsp, qword ptr fs:[0]"
+	}
+
+	return instr.Mnemonic
+}
@@ -41,6 +41,10 @@ func (instr *Jump) Exec(a *asm.Assembler) {
 
 	case mnemonics.JMP:
 		a.Jump(instr.Label)
+
+	// Unsigned and overflow jumps skip an unconditional jump if the inverted condition is true
+	case mnemonics.JB, mnemonics.JBE, mnemonics.JA, mnemonics.JAE, mnemonics.JO:
+		encodeInvertedJump(a, instr.Mnemonic, instr.Label)
 	}
 
 	instr.size = byte(a.Position() - start)
@@ -50,3 +54,8 @@ func (instr *Jump) Exec(a *asm.Assembler) {
 func (instr *Jump) String() string {
 	return fmt.Sprintf("%s %s", mnemonicColor.Sprint(instr.Mnemonic), instr.Label)
 }
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *Jump) Assembly() string {
+	return fmt.Sprintf("%s %s", instr.Mnemonic, Symbol(instr.Label))
+}
@@ -37,3 +37,8 @@ func (instr *MemoryNumber) Exec(a *asm.Assembler) {
 func (instr *MemoryNumber) String() string {
 	return fmt.Sprintf("%s %dB [%v+%d], %d", mnemonicColor.Sprint(instr.Mnemonic), instr.ByteCount, instr.Destination.StringWithUser(instr.UsedBy), instr.Offset, instr.Number)
 }
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *MemoryNumber) Assembly() string {
+	return fmt.Sprintf("mov %s, %d", memoryOperand(instr.Destination.Name, instr.Offset, instr.ByteCount), int64(instr.Number))
+}
@@ -25,7 +25,7 @@ func (instr *MemoryRegister) Exec(a *asm.Assembler) {
 
 	switch instr.Mnemonic {
 	case mnemonics.STORE:
-		a.StoreRegister(instr.Destination.Name, instr.Offset, instr.ByteCount, instr.Source.Name)
+		encodeStoreRegister(a, instr.Destination.Name, instr.Offset, instr.ByteCount, instr.Source.Name)
 
 	default:
 		panic("This should never happen!")
@@ -38,3 +38,8 @@ func (instr *MemoryRegister) Exec(a *asm.Assembler) {
 func (instr *MemoryRegister) String() string {
 	return fmt.Sprintf("%s %dB [%v+%d], %s", mnemonicColor.Sprint(instr.Mnemonic), instr.ByteCount, instr.Destination.StringWithUser(instr.UsedBy1), instr.Offset, instr.Source.StringWithUser(instr.UsedBy2))
 }
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *MemoryRegister) Assembly() string {
+	return fmt.Sprintf("mov %s, %s", memoryOperand(instr.Destination.Name, instr.Offset, instr.ByteCount), instr.Source.Sized(instr.ByteCount))
+}
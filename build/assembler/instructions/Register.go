@@ -4,21 +4,31 @@ import (
 	"fmt"
 
 	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/opcode"
 	"github.com/akyoto/q/build/assembler/mnemonics"
 	"github.com/akyoto/q/build/register"
 )
 
 // Register is used for instructions requiring 1 register operand.
+// A non-zero byte count limits the operation to the lower bytes of the register.
 type Register struct {
 	Base
 	Destination *register.Register
 	UsedBy      string
+	ByteCount   byte
 }
 
 // Exec writes the instruction to the final assembler.
 func (instr *Register) Exec(a *asm.Assembler) {
 	start := a.Position()
 
+	if instr.ByteCount != 0 {
+		extension := singleExtensions[instr.Mnemonic]
+		encodeSizedRegister(a, extension[0], extension[1], instr.Destination.Name, instr.ByteCount)
+		instr.size = byte(a.Position() - start)
+		return
+	}
+
 	switch instr.Mnemonic {
 	case mnemonics.INC:
 		a.IncreaseRegister(instr.Destination.Name)
@@ -26,17 +36,47 @@ func (instr *Register) Exec(a *asm.Assembler) {
 	case mnemonics.DEC:
 		a.DecreaseRegister(instr.Destination.Name)
 
+	case mnemonics.NEG:
+		encodeRegister(a, 0xf7, 3, instr.Destination.Name)
+
+	case mnemonics.NOT:
+		encodeRegister(a, 0xf7, 2, instr.Destination.Name)
+
+	// Signed multiplication of rax with the 128-bit result in rdx:rax
+	case mnemonics.MUL:
+		encodeRegister(a, 0xf7, 5, instr.Destination.Name)
+
+	// Set the lowest byte to the comparison result and zero-extend it
+	case mnemonics.SETE, mnemonics.SETNE, mnemonics.SETL, mnemonics.SETLE, mnemonics.SETG, mnemonics.SETGE, mnemonics.SETB, mnemonics.SETBE, mnemonics.SETA, mnemonics.SETAE:
+		encodeSetRegister(a, setCodes[instr.Mnemonic], instr.Destination.Name)
+
 	case mnemonics.DIV:
 		a.DivRegister(instr.Destination.Name)
 
+	// Unsigned division of rdx:rax
+	case mnemonics.UDIV:
+		encodeRegister(a, 0xf7, 6, instr.Destination.Name)
+
 	case mnemonics.CDQ:
 		a.SignExtendToDX(instr.Destination.Name)
 
+	// Indirect calls and jumps to the address in the register
+	case mnemonics.CALL:
+		encodeIndirect(a, 2, instr.Destination.Name)
+
+	case mnemonics.JMP:
+		encodeIndirect(a, 4, instr.Destination.Name)
+
 	case mnemonics.PUSH:
 		a.PushRegister(instr.Destination.Name)
 
 	case mnemonics.POP:
 		a.PopRegister(instr.Destination.Name)
+
+	// mov reg, qword ptr fs:[8]
+	case mnemonics.STACKSTART:
+		to := registerCodes[instr.Destination.Name]
+		a.WriteBytes(0x64, opcode.REX(1, to>>3, 0, 0), 0x8b, opcode.ModRM(0b00, to&0b111, 0b100), 0x25, 8, 0, 0, 0)
 	}
 
 	instr.size = byte(a.Position() - start)
@@ -44,5 +84,28 @@ func (instr *Register) Exec(a *asm.Assembler) {
 
 // String implements the string serialization.
 func (instr *Register) String() string {
+	if instr.ByteCount != 0 {
+		return fmt.Sprintf("%s %dB %v", mnemonicColor.Sprint(instr.Mnemonic), instr.ByteCount, instr.Destination.StringWithUser(instr.UsedBy))
+	}
+
 	return fmt.Sprintf("%s %v", mnemonicColor.Sprint(instr.Mnemonic), instr.Destination.StringWithUser(instr.UsedBy))
 }
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *Register) Assembly() string {
+	name := instr.Destination.Name
+
+	switch instr.Mnemonic {
+	case mnemonics.CDQ:
+		return "cqo"
+
+	case mnemonics.STACKSTART:
+		return fmt.Sprintf("mov %s, qword ptr fs:[8]", name)
+
+	case mnemonics.SETE, mnemonics.SETNE, mnemonics.SETL, mnemonics.SETLE, mnemonics.SETG, mnemonics.SETGE, mnemonics.SETB, mnemonics.SETBE, mnemonics.SETA, mnemonics.SETAE:
+		low := instr.Destination.Sized(1)
+		return lines(fmt.Sprintf("%s %s", instr.Mnemonic, low), fmt.Sprintf("movzx %s, %s", name, low))
+	}
+
+	return fmt.Sprintf("%s %s", instr.Mnemonic, instr.Destination.Sized(instr.ByteCount))
+}
@@ -14,16 +14,21 @@ type RegisterAddress struct {
 	Destination *register.Register
 	UsedBy      string
 	Address     uint32
+	Label       string
 }
 
 // Exec writes the instruction to the final assembler.
 func (instr *RegisterAddress) Exec(a *asm.Assembler) {
 	start := a.Position()
 
-	//nolint:gocritic
 	switch instr.Mnemonic {
 	case mnemonics.MOV:
 		a.MoveRegisterAddress(instr.Destination.Name, instr.Address)
+
+	// The displacement is relative to the end of the instruction,
+	// therefore it is patched once the distance from the code to the data is known.
+	case mnemonics.LEA:
+		encodeLoadDataAddress(a, instr.Destination.Name, instr.Address)
 	}
 
 	instr.size = byte(a.Position() - start)
@@ -33,3 +38,13 @@ func (instr *RegisterAddress) Exec(a *asm.Assembler) {
 func (instr *RegisterAddress) String() string {
 	return fmt.Sprintf("%s %v, <%v>", mnemonicColor.Sprint(instr.Mnemonic), instr.Destination.StringWithUser(instr.UsedBy), instr.Address)
 }
+
+// Assembly returns the instruction in Intel syntax.
+// The address is replaced by the label of the data.
+func (instr *RegisterAddress) Assembly() string {
+	if instr.Mnemonic == mnemonics.LEA {
+		return fmt.Sprintf("%s %s, [rip+%s]", instr.Mnemonic, instr.Destination.Name, Symbol(instr.Label))
+	}
+
+	return fmt.Sprintf("%s %s, offset %s", instr.Mnemonic, instr.Destination.Name, Symbol(instr.Label))
+}
@@ -0,0 +1,59 @@
+package instructions
+
+import (
+	"fmt"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/q/build/register"
+)
+
+// RegisterEffectiveAddress is used for instructions that calculate
+// the address base + index * scale + displacement in a register.
+// The index is optional and only used if the scale is not zero.
+type RegisterEffectiveAddress struct {
+	Base
+	Destination  *register.Register
+	Source       *register.Register
+	Index        *register.Register
+	Scale        byte
+	Displacement int32
+	UsedBy       string
+}
+
+// Exec writes the instruction to the final assembler.
+func (instr *RegisterEffectiveAddress) Exec(a *asm.Assembler) {
+	start := a.Position()
+	index := ""
+
+	if instr.Scale != 0 {
+		index = instr.Index.Name
+	}
+
+	encodeLoadAddress(a, instr.Destination.Name, instr.Source.Name, index, instr.Scale, instr.Displacement)
+	instr.size = byte(a.Position() - start)
+}
+
+// String implements the string serialization.
+func (instr *RegisterEffectiveAddress) String() string {
+	return fmt.Sprintf("%s %v, [%s]", mnemonicColor.Sprint(instr.Mnemonic), instr.Destination.StringWithUser(instr.UsedBy), instr.address())
+}
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *RegisterEffectiveAddress) Assembly() string {
+	return fmt.Sprintf("%s %s, [%s]", instr.Mnemonic, instr.Destination.Name, instr.address())
+}
+
+// address returns the address calculation in Intel syntax.
+func (instr *RegisterEffectiveAddress) address() string {
+	address := instr.Source.Name
+
+	if instr.Scale != 0 {
+		address = fmt.Sprintf("%s+%s*%d", address, instr.Index.Name, instr.Scale)
+	}
+
+	if instr.Displacement != 0 {
+		address = fmt.Sprintf("%s%+d", address, instr.Displacement)
+	}
+
+	return address
+}
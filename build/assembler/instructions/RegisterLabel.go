@@ -0,0 +1,40 @@
+package instructions
+
+import (
+	"fmt"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/q/build/assembler/mnemonics"
+	"github.com/akyoto/q/build/register"
+)
+
+// RegisterLabel is used for instructions that load the address of a label into a register.
+type RegisterLabel struct {
+	Base
+	Destination *register.Register
+	UsedBy      string
+	Label       string
+}
+
+// Exec writes the instruction to the final assembler.
+func (instr *RegisterLabel) Exec(a *asm.Assembler) {
+	start := a.Position()
+
+	//nolint:gocritic
+	switch instr.Mnemonic {
+	case mnemonics.LEA:
+		encodeLoadLabelAddress(a, instr.Destination.Name, instr.Label)
+	}
+
+	instr.size = byte(a.Position() - start)
+}
+
+// String implements the string serialization.
+func (instr *RegisterLabel) String() string {
+	return fmt.Sprintf("%s %v, %s", mnemonicColor.Sprint(instr.Mnemonic), instr.Destination.StringWithUser(instr.UsedBy), instr.Label)
+}
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *RegisterLabel) Assembly() string {
+	return fmt.Sprintf("%s %s, [rip+%s]", instr.Mnemonic, instr.Destination.Name, Symbol(instr.Label))
+}
@@ -25,7 +25,13 @@ func (instr *RegisterMemory) Exec(a *asm.Assembler) {
 
 	switch instr.Mnemonic {
 	case mnemonics.LOAD:
-		a.LoadRegister(instr.Destination.Name, instr.Source.Name, instr.Offset, instr.ByteCount)
+		encodeLoadRegister(a, instr.Destination.Name, instr.Source.Name, instr.Offset, instr.ByteCount)
+
+	case mnemonics.LOADZX:
+		encodeLoadZeroExtend(a, instr.Destination.Name, instr.Source.Name, instr.Offset, instr.ByteCount)
+
+	case mnemonics.LOADSX:
+		encodeLoadSignExtend(a, instr.Destination.Name, instr.Source.Name, instr.Offset, instr.ByteCount)
 
 	default:
 		panic("This should never happen!")
@@ -36,5 +42,21 @@ func (instr *RegisterMemory) Exec(a *asm.Assembler) {
 
 // String implements the string serialization.
 func (instr *RegisterMemory) String() string {
-	return fmt.Sprintf("%s %dB %v, [%v+%d]", mnemonicColor.Sprint(instr.Mnemonic), instr.ByteCount, instr.Destination.StringWithUser(instr.UsedBy1), instr.Source.StringWithUser(instr.UsedBy2), instr.Offset)
+	return fmt.Sprintf("%s %dB %v, [%v%+d]", mnemonicColor.Sprint(instr.Mnemonic), instr.ByteCount, instr.Destination.StringWithUser(instr.UsedBy1), instr.Source.StringWithUser(instr.UsedBy2), int8(instr.Offset))
+}
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *RegisterMemory) Assembly() string {
+	switch {
+	case instr.Mnemonic == mnemonics.LOADZX:
+		return fmt.Sprintf("movzx %s, %s", instr.Destination.Name, memoryOperand(instr.Source.Name, instr.Offset, instr.ByteCount))
+
+	case instr.Mnemonic == mnemonics.LOADSX && instr.ByteCount == 4:
+		return fmt.Sprintf("movsxd %s, %s", instr.Destination.Name, memoryOperand(instr.Source.Name, instr.Offset, instr.ByteCount))
+
+	case instr.Mnemonic == mnemonics.LOADSX:
+		return fmt.Sprintf("movsx %s, %s", instr.Destination.Name, memoryOperand(instr.Source.Name, instr.Offset, instr.ByteCount))
+	}
+
+	return fmt.Sprintf("mov %s, %s", instr.Destination.Sized(instr.ByteCount), memoryOperand(instr.Source.Name, instr.Offset, instr.ByteCount))
 }
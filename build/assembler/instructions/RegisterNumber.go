@@ -2,6 +2,7 @@ package instructions
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/akyoto/asm"
 	"github.com/akyoto/q/build/assembler/mnemonics"
@@ -9,32 +10,72 @@ import (
 )
 
 // RegisterNumber is used for instructions requiring a register and a number operand.
+// A non-zero byte count limits the operation to the lower bytes of the register.
 type RegisterNumber struct {
 	Base
 	Destination *register.Register
 	Number      uint64
 	UsedBy      string
+	ByteCount   byte
 }
 
 // Exec writes the instruction to the final assembler.
 func (instr *RegisterNumber) Exec(a *asm.Assembler) {
 	start := a.Position()
 
+	if instr.ByteCount != 0 {
+		if instr.Mnemonic == mnemonics.MOV {
+			encodeSizedMoveRegisterNumber(a, instr.Destination.Name, instr.Number, instr.ByteCount)
+		} else {
+			encodeSizedRegisterNumber(a, immediateExtensions[instr.Mnemonic], instr.Destination.Name, instr.Number, instr.ByteCount)
+		}
+
+		instr.size = byte(a.Position() - start)
+		return
+	}
+
 	switch instr.Mnemonic {
 	case mnemonics.MOV:
+		// Negative numbers need to be sign-extended to 64 bits
+		if int64(instr.Number) < 0 && int64(instr.Number) >= math.MinInt32 {
+			encodeMoveRegisterNegativeNumber(a, instr.Destination.Name, instr.Number)
+			break
+		}
+
 		a.MoveRegisterNumber(instr.Destination.Name, instr.Number)
 
 	case mnemonics.CMP:
 		a.CompareRegisterNumber(instr.Destination.Name, instr.Number)
 
 	case mnemonics.ADD:
-		a.AddRegisterNumber(instr.Destination.Name, instr.Number)
+		encodeRegisterNumber(a, 0, instr.Destination.Name, instr.Number)
 
 	case mnemonics.MUL:
-		a.MulRegisterNumber(instr.Destination.Name, instr.Number)
+		encodeMulRegisterNumber(a, instr.Destination.Name, instr.Number)
 
 	case mnemonics.SUB:
-		a.SubRegisterNumber(instr.Destination.Name, instr.Number)
+		encodeRegisterNumber(a, 5, instr.Destination.Name, instr.Number)
+
+	case mnemonics.AND:
+		encodeRegisterNumber(a, 4, instr.Destination.Name, instr.Number)
+
+	case mnemonics.OR:
+		encodeRegisterNumber(a, 1, instr.Destination.Name, instr.Number)
+
+	case mnemonics.XOR:
+		encodeRegisterNumber(a, 6, instr.Destination.Name, instr.Number)
+
+	case mnemonics.SHL:
+		encodeRegister(a, 0xc1, 4, instr.Destination.Name)
+		a.WriteBytes(byte(instr.Number))
+
+	case mnemonics.SAR:
+		encodeRegister(a, 0xc1, 7, instr.Destination.Name)
+		a.WriteBytes(byte(instr.Number))
+
+	case mnemonics.SHR:
+		encodeRegister(a, 0xc1, 5, instr.Destination.Name)
+		a.WriteBytes(byte(instr.Number))
 	}
 
 	instr.size = byte(a.Position() - start)
@@ -42,5 +83,14 @@ func (instr *RegisterNumber) Exec(a *asm.Assembler) {
 
 // String implements the string serialization.
 func (instr *RegisterNumber) String() string {
-	return fmt.Sprintf("%s %v, %d", mnemonicColor.Sprint(instr.Mnemonic), instr.Destination.StringWithUser(instr.UsedBy), instr.Number)
+	if instr.ByteCount != 0 {
+		return fmt.Sprintf("%s %dB %v, %d", mnemonicColor.Sprint(instr.Mnemonic), instr.ByteCount, instr.Destination.StringWithUser(instr.UsedBy), int64(instr.Number))
+	}
+
+	return fmt.Sprintf("%s %v, %d", mnemonicColor.Sprint(instr.Mnemonic), instr.Destination.StringWithUser(instr.UsedBy), int64(instr.Number))
+}
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *RegisterNumber) Assembly() string {
+	return fmt.Sprintf("%s %s, %d", instr.Mnemonic, instr.Destination.Sized(instr.ByteCount), int64(instr.Number))
 }
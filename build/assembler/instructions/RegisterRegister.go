@@ -9,33 +9,111 @@ import (
 )
 
 // RegisterRegister is used for instructions requiring 2 register operands.
+// A non-zero byte count limits the operation to the lower bytes of both registers.
+// Extensions use it as the size of the source instead.
 type RegisterRegister struct {
 	Base
 	Destination *register.Register
 	Source      *register.Register
 	UsedBy1     string
 	UsedBy2     string
+	ByteCount   byte
 }
 
 // Exec writes the instruction to the final assembler.
 func (instr *RegisterRegister) Exec(a *asm.Assembler) {
 	start := a.Position()
 
+	if instr.ByteCount != 0 && instr.Mnemonic != mnemonics.MOVZX && instr.Mnemonic != mnemonics.MOVSX {
+		encodeSizedRegisterRegister(a, sizedCodes[instr.Mnemonic], instr.Destination.Name, instr.Source.Name, instr.ByteCount)
+		instr.size = byte(a.Position() - start)
+		return
+	}
+
 	switch instr.Mnemonic {
 	case mnemonics.MOV:
 		a.MoveRegisterRegister(instr.Destination.Name, instr.Source.Name)
 
+	case mnemonics.MOVZX:
+		encodeExtendRegister(a, false, instr.Destination.Name, instr.Source.Name, instr.ByteCount)
+
+	case mnemonics.MOVSX:
+		encodeExtendRegister(a, true, instr.Destination.Name, instr.Source.Name, instr.ByteCount)
+
 	case mnemonics.CMP:
 		a.CompareRegisterRegister(instr.Destination.Name, instr.Source.Name)
 
+	case mnemonics.TEST:
+		encodeRegisterRegister(a, []byte{0x85}, instr.Destination.Name, instr.Source.Name)
+
 	case mnemonics.ADD:
 		a.AddRegisterRegister(instr.Destination.Name, instr.Source.Name)
 
 	case mnemonics.SUB:
 		a.SubRegisterRegister(instr.Destination.Name, instr.Source.Name)
 
+	// IMUL stores the destination in the reg field of ModRM
 	case mnemonics.MUL:
-		a.MulRegisterRegister(instr.Destination.Name, instr.Source.Name)
+		encodeRegisterRegister(a, []byte{0x0f, 0xaf}, instr.Source.Name, instr.Destination.Name)
+
+	// Conditional moves also store the destination in the reg field of ModRM
+	case mnemonics.CMOVL:
+		encodeRegisterRegister(a, []byte{0x0f, 0x4c}, instr.Source.Name, instr.Destination.Name)
+
+	case mnemonics.CMOVG:
+		encodeRegisterRegister(a, []byte{0x0f, 0x4f}, instr.Source.Name, instr.Destination.Name)
+
+	case mnemonics.AND:
+		encodeRegisterRegister(a, []byte{0x21}, instr.Destination.Name, instr.Source.Name)
+
+	case mnemonics.OR:
+		encodeRegisterRegister(a, []byte{0x09}, instr.Destination.Name, instr.Source.Name)
+
+	case mnemonics.XOR:
+		encodeRegisterRegister(a, []byte{0x31}, instr.Destination.Name, instr.Source.Name)
+
+	// Shifts by a register always use CL as the source.
+	case mnemonics.SHL:
+		encodeRegister(a, 0xd3, 4, instr.Destination.Name)
+
+	case mnemonics.SAR:
+		encodeRegister(a, 0xd3, 7, instr.Destination.Name)
+
+	// BMI2 shifts can use any register as the source.
+	case mnemonics.SHLX:
+		encodeShiftBMI2(a, 0b01, instr.Destination.Name, instr.Source.Name)
+
+	case mnemonics.SARX:
+		encodeShiftBMI2(a, 0b10, instr.Destination.Name, instr.Source.Name)
+
+	// Moves between general purpose and SSE registers
+	case mnemonics.MOVQ:
+		if isFloatRegister(instr.Destination.Name) {
+			encodeSSE(a, 0x66, 0x6e, instr.Destination.Name, instr.Source.Name, true)
+		} else {
+			encodeSSE(a, 0x66, 0x7e, instr.Source.Name, instr.Destination.Name, true)
+		}
+
+	case mnemonics.ADDSD:
+		encodeSSE(a, 0xf2, 0x58, instr.Destination.Name, instr.Source.Name, false)
+
+	case mnemonics.MULSD:
+		encodeSSE(a, 0xf2, 0x59, instr.Destination.Name, instr.Source.Name, false)
+
+	case mnemonics.SUBSD:
+		encodeSSE(a, 0xf2, 0x5c, instr.Destination.Name, instr.Source.Name, false)
+
+	case mnemonics.DIVSD:
+		encodeSSE(a, 0xf2, 0x5e, instr.Destination.Name, instr.Source.Name, false)
+
+	case mnemonics.CVTSI2SD:
+		encodeSSE(a, 0xf2, 0x2a, instr.Destination.Name, instr.Source.Name, true)
+
+	case mnemonics.CVTSD2SI:
+		encodeSSE(a, 0xf2, 0x2d, instr.Destination.Name, instr.Source.Name, true)
+
+	case mnemonics.CVTTSD2SI:
+		encodeSSE(a, 0xf2, 0x2c, instr.Destination.Name, instr.Source.Name, true)
 	}
 
 	instr.size = byte(a.Position() - start)
@@ -43,5 +121,41 @@ func (instr *RegisterRegister) Exec(a *asm.Assembler) {
 
 // String implements the string serialization.
 func (instr *RegisterRegister) String() string {
+	if instr.ByteCount != 0 {
+		return fmt.Sprintf("%s %dB %v, %v", mnemonicColor.Sprint(instr.Mnemonic), instr.ByteCount, instr.Destination.StringWithUser(instr.UsedBy1), instr.Source.StringWithUser(instr.UsedBy2))
+	}
+
 	return fmt.Sprintf("%s %v, %v", mnemonicColor.Sprint(instr.Mnemonic), instr.Destination.StringWithUser(instr.UsedBy1), instr.Source.StringWithUser(instr.UsedBy2))
 }
+
+// Assembly returns the instruction in Intel syntax.
+func (instr *RegisterRegister) Assembly() string {
+	switch instr.Mnemonic {
+	case mnemonics.SHL, mnemonics.SAR:
+		return fmt.Sprintf("%s %s, cl", instr.Mnemonic, instr.Destination.Name)
+
+	case mnemonics.SHLX, mnemonics.SARX:
+		return fmt.Sprintf("%s %s, %s, %s", instr.Mnemonic, instr.Destination.Name, instr.Destination.Name, instr.Source.Name)
+
+	// Writing the lower 32 bits clears the upper 32 bits
+	case mnemonics.MOVZX:
+		if instr.ByteCount == 4 {
+			return fmt.Sprintf("mov %s, %s", instr.Destination.Sized(4), instr.Source.Sized(4))
+		}
+
+		return fmt.Sprintf("movzx %s, %s", instr.Destination.Name, instr.Source.Sized(instr.ByteCount))
+
+	case mnemonics.MOVSX:
+		if instr.ByteCount == 4 {
+			return fmt.Sprintf("movsxd %s, %s", instr.Destination.Name, instr.Source.Sized(4))
+		}
+
+		return fmt.Sprintf("movsx %s, %s", instr.Destination.Name, instr.Source.Sized(instr.ByteCount))
+	}
+
+	if instr.ByteCount != 0 {
+		return fmt.Sprintf("%s %s, %s", instr.Mnemonic, instr.Destination.Sized(instr.ByteCount), instr.Source.Sized(instr.ByteCount))
+	}
+
+	return fmt.Sprintf("%s %s, %s", instr.Mnemonic, instr.Destination.Name, instr.Source.Name)
+}
@@ -0,0 +1,46 @@
+package instructions
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/q/build/log"
+)
+
+// SourceLine marks the start of the machine code for a line in a source file.
+type SourceLine struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// Exec writes the instruction to the final assembler.
+func (instr *SourceLine) Exec(a *asm.Assembler) {
+	// Not applicable.
+}
+
+// Name returns the pseudo mnemonic.
+func (instr *SourceLine) Name() string {
+	return "LINE"
+}
+
+// SetName sets the mnemonic.
+func (instr *SourceLine) SetName(mnemonic string) {
+	// Not applicable.
+}
+
+// Size returns the number of bytes consumed for the instruction.
+func (instr *SourceLine) Size() byte {
+	return 0
+}
+
+// String implements the string serialization.
+func (instr *SourceLine) String() string {
+	return log.CommentColor.Sprintf("%s:%d:%d", filepath.Base(instr.File), instr.Line, instr.Column)
+}
+
+// Assembly returns the source position as a comment.
+func (instr *SourceLine) Assembly() string {
+	return fmt.Sprintf("# %s:%d:%d", filepath.Base(instr.File), instr.Line, instr.Column)
+}
@@ -0,0 +1,44 @@
+package instructions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SymbolPrefix is added to the names of all labels in Intel syntax.
+// Function names like 'offset' or 'rax' would otherwise be
+// interpreted as keywords or registers by the GNU assembler.
+const SymbolPrefix = "q_"
+
+// Symbol returns the name of the label in Intel syntax.
+func Symbol(label string) string {
+	return SymbolPrefix + label
+}
+
+// memoryOperand returns the memory operand at the register address plus the offset.
+func memoryOperand(name string, offset byte, byteCount byte) string {
+	address := name
+
+	if offset != 0 {
+		address = fmt.Sprintf("%s%+d", name, int8(offset))
+	}
+
+	switch byteCount {
+	case 8:
+		return fmt.Sprintf("qword ptr [%s]", address)
+
+	case 4:
+		return fmt.Sprintf("dword ptr [%s]", address)
+
+	case 2:
+		return fmt.Sprintf("word ptr [%s]", address)
+
+	default:
+		return fmt.Sprintf("byte ptr [%s]", address)
+	}
+}
+
+// lines joins instructions that are encoded as a single instruction in this package.
+func lines(instructions ...string) string {
+	return strings.Join(instructions, "\n")
+}
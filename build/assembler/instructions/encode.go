@@ -0,0 +1,411 @@
+package instructions
+
+import (
+	"math"
+	"strings"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/opcode"
+	"github.com/akyoto/q/build/assembler/mnemonics"
+)
+
+// registerCodes maps the register names to their x86-64 encoding.
+var registerCodes = map[string]byte{
+	"rax": 0,
+	"rcx": 1,
+	"rdx": 2,
+	"rbx": 3,
+	"rsp": 4,
+	"rbp": 5,
+	"rsi": 6,
+	"rdi": 7,
+	"r8":  8,
+	"r9":  9,
+	"r10": 10,
+	"r11": 11,
+	"r12": 12,
+	"r13": 13,
+	"r14": 14,
+	"r15": 15,
+
+	"xmm0":  0,
+	"xmm1":  1,
+	"xmm2":  2,
+	"xmm3":  3,
+	"xmm4":  4,
+	"xmm5":  5,
+	"xmm6":  6,
+	"xmm7":  7,
+	"xmm8":  8,
+	"xmm9":  9,
+	"xmm10": 10,
+	"xmm11": 11,
+	"xmm12": 12,
+	"xmm13": 13,
+	"xmm14": 14,
+	"xmm15": 15,
+}
+
+// setCodes maps the set instructions to the second byte of their opcode.
+var setCodes = map[string]byte{
+	mnemonics.SETE:  0x94,
+	mnemonics.SETNE: 0x95,
+	mnemonics.SETL:  0x9c,
+	mnemonics.SETGE: 0x9d,
+	mnemonics.SETLE: 0x9e,
+	mnemonics.SETG:  0x9f,
+	mnemonics.SETB:  0x92,
+	mnemonics.SETAE: 0x93,
+	mnemonics.SETBE: 0x96,
+	mnemonics.SETA:  0x97,
+}
+
+// invertedJumps maps the jumps that are missing in the asm library
+// to the short jump with the inverted condition.
+var invertedJumps = map[string]byte{
+	mnemonics.JB:  0x73,
+	mnemonics.JAE: 0x72,
+	mnemonics.JBE: 0x77,
+	mnemonics.JA:  0x76,
+	mnemonics.JO:  0x71,
+}
+
+// encodeInvertedJump encodes a conditional jump to a label.
+// The label can only be resolved for unconditional jumps,
+// therefore the inverted condition skips the 5 bytes of the near jump.
+func encodeInvertedJump(a *asm.Assembler, mnemonic string, label string) {
+	a.WriteBytes(invertedJumps[mnemonic], 5)
+	a.Jump(label)
+}
+
+// encodeRegister encodes a 64-bit instruction with a single register operand.
+// The extension selects the operation in the reg field of ModRM.
+func encodeRegister(a *asm.Assembler, code byte, extension byte, destination string) {
+	to := registerCodes[destination]
+	a.WriteBytes(opcode.REX(1, 0, 0, to>>3), code, opcode.ModRM(0b11, extension, to&0b111))
+}
+
+// encodeIndirect encodes a call or jump to the address in the register.
+// The operand size is 64 bits by default, therefore the REX prefix is only needed for r8 to r15.
+func encodeIndirect(a *asm.Assembler, extension byte, destination string) {
+	to := registerCodes[destination]
+
+	if to >= 8 {
+		a.WriteBytes(opcode.REX(0, 0, 0, 1))
+	}
+
+	a.WriteBytes(0xff, opcode.ModRM(0b11, extension, to&0b111))
+}
+
+// encodeRegisterRegister encodes a 64-bit instruction
+// with the source in the reg field and the destination in the rm field.
+func encodeRegisterRegister(a *asm.Assembler, code []byte, destination string, source string) {
+	to := registerCodes[destination]
+	from := registerCodes[source]
+	a.WriteBytes(opcode.REX(1, from>>3, 0, to>>3))
+	_, _ = a.Write(code)
+	a.WriteBytes(opcode.ModRM(0b11, from&0b111, to&0b111))
+}
+
+// encodeSetRegister encodes a set instruction on the lowest byte of the register
+// followed by a zero extension of that byte to 64 bits.
+// The REX prefix is always needed to address the lowest byte of rsp, rbp, rsi and rdi.
+func encodeSetRegister(a *asm.Assembler, code byte, destination string) {
+	to := registerCodes[destination]
+	a.WriteBytes(opcode.REX(0, 0, 0, to>>3), 0x0f, code, opcode.ModRM(0b11, 0, to&0b111))
+	a.WriteBytes(opcode.REX(1, to>>3, 0, to>>3), 0x0f, 0xb6, opcode.ModRM(0b11, to&0b111, to&0b111))
+}
+
+// encodeLoadRegister encodes a move from memory at the source address plus the offset.
+// The offset is interpreted as a signed 8-bit displacement.
+func encodeLoadRegister(a *asm.Assembler, destination string, source string, offset byte, byteCount byte) {
+	to := registerCodes[destination]
+	from := registerCodes[source]
+	code := byte(0x8b)
+	w := byte(0)
+
+	switch byteCount {
+	case 8:
+		w = 1
+
+	case 2:
+		a.WriteBytes(0x66)
+
+	case 1:
+		code = 0x8a
+	}
+
+	// The lowest byte of rsp, rbp, rsi and rdi can only be addressed with a REX prefix
+	if w != 0 || to >= 8 || from >= 8 || (byteCount == 1 && to >= 4) {
+		a.WriteBytes(opcode.REX(w, to>>3, 0, from>>3))
+	}
+
+	a.WriteBytes(code)
+	encodeMemoryOperand(a, to, from, offset)
+}
+
+// encodeLoadZeroExtend encodes a move of 1 or 2 bytes from memory at the source address plus the offset
+// that is zero-extended to 64 bits.
+func encodeLoadZeroExtend(a *asm.Assembler, destination string, source string, offset byte, byteCount byte) {
+	to := registerCodes[destination]
+	from := registerCodes[source]
+	code := byte(0xb6)
+
+	if byteCount == 2 {
+		code = 0xb7
+	}
+
+	a.WriteBytes(opcode.REX(1, to>>3, 0, from>>3), 0x0f, code)
+	encodeMemoryOperand(a, to, from, offset)
+}
+
+// encodeLoadSignExtend encodes a move of 1, 2 or 4 bytes from memory at the source address plus the offset
+// that is sign-extended to 64 bits.
+func encodeLoadSignExtend(a *asm.Assembler, destination string, source string, offset byte, byteCount byte) {
+	to := registerCodes[destination]
+	from := registerCodes[source]
+	a.WriteBytes(opcode.REX(1, to>>3, 0, from>>3))
+
+	switch byteCount {
+	case 4:
+		a.WriteBytes(0x63)
+
+	case 2:
+		a.WriteBytes(0x0f, 0xbf)
+
+	default:
+		a.WriteBytes(0x0f, 0xbe)
+	}
+
+	encodeMemoryOperand(a, to, from, offset)
+}
+
+// encodeExtendRegister encodes a sign or zero extension of the lower 1, 2 or 4 bytes of the source to 64 bits.
+// A 32-bit move is used for the zero extension of 4 bytes because it clears the upper 32 bits.
+func encodeExtendRegister(a *asm.Assembler, signed bool, destination string, source string, byteCount byte) {
+	to := registerCodes[destination]
+	from := registerCodes[source]
+
+	if byteCount == 4 && !signed {
+		if to >= 8 || from >= 8 {
+			a.WriteBytes(opcode.REX(0, to>>3, 0, from>>3))
+		}
+
+		a.WriteBytes(0x8b, opcode.ModRM(0b11, to&0b111, from&0b111))
+		return
+	}
+
+	a.WriteBytes(opcode.REX(1, to>>3, 0, from>>3))
+
+	switch {
+	case byteCount == 4:
+		a.WriteBytes(0x63)
+
+	case signed && byteCount == 2:
+		a.WriteBytes(0x0f, 0xbf)
+
+	case signed:
+		a.WriteBytes(0x0f, 0xbe)
+
+	case byteCount == 2:
+		a.WriteBytes(0x0f, 0xb7)
+
+	default:
+		a.WriteBytes(0x0f, 0xb6)
+	}
+
+	a.WriteBytes(opcode.ModRM(0b11, to&0b111, from&0b111))
+}
+
+// encodeStoreRegister encodes a move of the source register to memory at the destination address plus the offset.
+// It replaces the store of the asm library which omits the REX prefix for r8 up to r15 in stores below 8 bytes.
+func encodeStoreRegister(a *asm.Assembler, destination string, offset byte, byteCount byte, source string) {
+	to := registerCodes[destination]
+	from := registerCodes[source]
+	code := byte(0x89)
+	w := byte(0)
+
+	switch byteCount {
+	case 8:
+		w = 1
+
+	case 2:
+		a.WriteBytes(0x66)
+
+	case 1:
+		code = 0x88
+	}
+
+	// The lowest byte of rsp, rbp, rsi and rdi can only be addressed with a REX prefix
+	if w != 0 || to >= 8 || from >= 8 || (byteCount == 1 && from >= 4) {
+		a.WriteBytes(opcode.REX(w, from>>3, 0, to>>3))
+	}
+
+	a.WriteBytes(code)
+	encodeMemoryOperand(a, from, to, offset)
+}
+
+// scaleCodes maps the scale of an index register to the scale field of the SIB byte.
+var scaleCodes = map[byte]byte{
+	1: 0b00,
+	2: 0b01,
+	4: 0b10,
+	8: 0b11,
+}
+
+// encodeLoadAddress encodes the calculation of base + index * scale + displacement.
+// An empty index name means that the address only consists of the base and the displacement.
+func encodeLoadAddress(a *asm.Assembler, destination string, base string, index string, scale byte, displacement int32) {
+	to := registerCodes[destination]
+	from := registerCodes[base]
+	indexCode := byte(0b100)
+	x := byte(0)
+
+	if index != "" {
+		indexCode = registerCodes[index]
+		x = indexCode >> 3
+	}
+
+	a.WriteBytes(opcode.REX(1, to>>3, x, from>>3), 0x8d)
+	mod := byte(0b10)
+
+	// rbp and r13 can only be encoded with a displacement
+	switch {
+	case displacement == 0 && from&0b111 != 0b101:
+		mod = 0b00
+
+	case displacement >= math.MinInt8 && displacement <= math.MaxInt8:
+		mod = 0b01
+	}
+
+	// rsp and r12 as a base require a SIB byte
+	if index != "" || from&0b111 == 0b100 {
+		a.WriteBytes(opcode.ModRM(mod, to&0b111, 0b100), opcode.SIB(scaleCodes[scale], indexCode&0b111, from&0b111))
+	} else {
+		a.WriteBytes(opcode.ModRM(mod, to&0b111, from&0b111))
+	}
+
+	switch mod {
+	case 0b01:
+		a.WriteBytes(byte(displacement))
+
+	case 0b10:
+		a.WriteUint32(uint32(displacement))
+	}
+}
+
+// encodeLoadLabelAddress encodes the calculation of a label address relative to the instruction pointer.
+// The displacement is resolved like the target of a call because both are relative to the end of the instruction,
+// therefore the call opcode is replaced by the ModRM byte after the label has been registered.
+func encodeLoadLabelAddress(a *asm.Assembler, destination string, label string) {
+	to := registerCodes[destination]
+	a.WriteBytes(opcode.REX(1, to>>3, 0, 0), 0x8d)
+	position := a.Position()
+	a.Call(label)
+	a.Code()[position] = opcode.ModRM(0b00, to&0b111, 0b101)
+}
+
+// encodeLoadDataAddress encodes the calculation of a data address relative to the instruction pointer.
+// The address within the data section is used as a placeholder for the displacement.
+func encodeLoadDataAddress(a *asm.Assembler, destination string, address uint32) {
+	to := registerCodes[destination]
+	a.WriteBytes(opcode.REX(1, to>>3, 0, 0), 0x8d, opcode.ModRM(0b00, to&0b111, 0b101))
+	a.WriteUint32(address)
+}
+
+// encodeMemoryOperand encodes the ModRM byte, the SIB byte and the displacement
+// for a register and the memory at the base address plus the offset.
+func encodeMemoryOperand(a *asm.Assembler, reg byte, base byte, offset byte) {
+	// rbp and r13 can only be encoded with a displacement
+	if offset != 0 || base&0b111 == 0b101 {
+		a.WriteBytes(opcode.ModRM(0b01, reg&0b111, base&0b111))
+	} else {
+		a.WriteBytes(opcode.ModRM(0b00, reg&0b111, base&0b111))
+	}
+
+	// rsp and r12 require a SIB byte
+	if base&0b111 == 0b100 {
+		a.WriteBytes(opcode.SIB(0b00, 0b100, 0b100))
+	}
+
+	if offset != 0 || base&0b111 == 0b101 {
+		a.WriteBytes(offset)
+	}
+}
+
+// encodeRegisterNumber encodes a 64-bit instruction of the 0x81 / 0x83 group
+// that takes a sign-extended immediate value.
+// The extension selects the operation in the reg field of ModRM.
+func encodeRegisterNumber(a *asm.Assembler, extension byte, destination string, number uint64) {
+	to := registerCodes[destination]
+	a.WriteBytes(opcode.REX(1, 0, 0, to>>3))
+
+	if int64(number) >= -128 && int64(number) <= 127 {
+		a.WriteBytes(0x83, opcode.ModRM(0b11, extension, to&0b111), byte(number))
+		return
+	}
+
+	a.WriteBytes(0x81, opcode.ModRM(0b11, extension, to&0b111))
+	a.WriteUint32(uint32(number))
+}
+
+// encodeMulRegisterNumber encodes a signed multiplication of a register with a number
+// that stores the result in the same register.
+func encodeMulRegisterNumber(a *asm.Assembler, destination string, number uint64) {
+	to := registerCodes[destination]
+	a.WriteBytes(opcode.REX(1, to>>3, 0, to>>3))
+
+	if int64(number) >= -128 && int64(number) <= 127 {
+		a.WriteBytes(0x6b, opcode.ModRM(0b11, to&0b111, to&0b111), byte(number))
+		return
+	}
+
+	a.WriteBytes(0x69, opcode.ModRM(0b11, to&0b111, to&0b111))
+	a.WriteUint32(uint32(number))
+}
+
+// encodeMoveRegisterNegativeNumber encodes a move of a 32-bit number
+// that is sign-extended to 64 bits.
+func encodeMoveRegisterNegativeNumber(a *asm.Assembler, destination string, number uint64) {
+	to := registerCodes[destination]
+	a.WriteBytes(opcode.REX(1, 0, 0, to>>3), 0xc7, opcode.ModRM(0b11, 0, to&0b111))
+	a.WriteUint32(uint32(number))
+}
+
+// encodeSSE encodes an SSE instruction with a mandatory prefix.
+// The reg operand is encoded in the reg field and the rm operand in the rm field of ModRM.
+// Wide instructions operate on 64-bit general purpose registers.
+func encodeSSE(a *asm.Assembler, prefix byte, code byte, reg string, rm string, wide bool) {
+	regCode := registerCodes[reg]
+	rmCode := registerCodes[rm]
+	w := byte(0)
+
+	if wide {
+		w = 1
+	}
+
+	a.WriteBytes(prefix)
+
+	if w != 0 || regCode >= 8 || rmCode >= 8 {
+		a.WriteBytes(opcode.REX(w, regCode>>3, 0, rmCode>>3))
+	}
+
+	a.WriteBytes(0x0f, code, opcode.ModRM(0b11, regCode&0b111, rmCode&0b111))
+}
+
+// encodeShiftBMI2 encodes a 64-bit BMI2 shift of the destination by the source
+// with the destination as both the result and the shifted value.
+// The VEX prefix stores the inverted extension bits and the inverted shift count register,
+// the operand prefix selects the kind of shift: 0b01 for shlx and 0b10 for sarx.
+func encodeShiftBMI2(a *asm.Assembler, prefix byte, destination string, source string) {
+	to := registerCodes[destination]
+	count := registerCodes[source]
+	extension := (to >> 3) ^ 1
+	a.WriteBytes(0xc4, extension<<7|1<<6|extension<<5|0b00010, 1<<7|(^count&0b1111)<<3|prefix, 0xf7)
+	a.WriteBytes(opcode.ModRM(0b11, to&0b111, to&0b111))
+}
+
+// isFloatRegister tells you whether the register is an SSE register.
+func isFloatRegister(name string) bool {
+	return strings.HasPrefix(name, "xmm")
+}
@@ -0,0 +1,153 @@
+package instructions
+
+import (
+	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/opcode"
+	"github.com/akyoto/q/build/assembler/mnemonics"
+)
+
+// sizedCodes maps the instructions with 2 register operands that support smaller operand sizes
+// to their opcode for 16, 32 and 64 bits. The opcode for 8 bits is always one less.
+var sizedCodes = map[string]byte{
+	mnemonics.MOV:  0x89,
+	mnemonics.ADD:  0x01,
+	mnemonics.SUB:  0x29,
+	mnemonics.CMP:  0x39,
+	mnemonics.TEST: 0x85,
+	mnemonics.AND:  0x21,
+	mnemonics.OR:   0x09,
+	mnemonics.XOR:  0x31,
+}
+
+// immediateExtensions maps the instructions of the 0x80 / 0x81 / 0x83 group
+// to the extension in the reg field of ModRM.
+var immediateExtensions = map[string]byte{
+	mnemonics.ADD: 0,
+	mnemonics.OR:  1,
+	mnemonics.AND: 4,
+	mnemonics.SUB: 5,
+	mnemonics.XOR: 6,
+	mnemonics.CMP: 7,
+}
+
+// singleExtensions maps the instructions with a single register operand
+// to their opcode for 16, 32 and 64 bits and the extension in the reg field of ModRM.
+// The opcode for 8 bits is always one less.
+var singleExtensions = map[string][2]byte{
+	mnemonics.INC: {0xff, 0},
+	mnemonics.DEC: {0xff, 1},
+	mnemonics.NOT: {0xf7, 2},
+	mnemonics.NEG: {0xf7, 3},
+}
+
+// SupportsByteCount tells you whether the instruction can operate on the lower part of the registers.
+func SupportsByteCount(mnemonic string, operands int) bool {
+	switch operands {
+	case 1:
+		_, exists := singleExtensions[mnemonic]
+		return exists
+
+	case 2:
+		_, exists := sizedCodes[mnemonic]
+		return exists
+
+	default:
+		return false
+	}
+}
+
+// SupportsByteCountNumber tells you whether the instruction with a number operand
+// can operate on the lower part of the register.
+func SupportsByteCountNumber(mnemonic string) bool {
+	_, exists := immediateExtensions[mnemonic]
+	return exists || mnemonic == mnemonics.MOV
+}
+
+// encodeSizePrefix writes the operand size prefix and the REX prefix for the given operand size.
+// The lowest byte of rsp, rbp, rsi and rdi can only be addressed with a REX prefix,
+// otherwise the codes would refer to ah, ch, dh and bh.
+func encodeSizePrefix(a *asm.Assembler, byteCount byte, reg byte, rm byte) {
+	if byteCount == 2 {
+		a.WriteBytes(0x66)
+	}
+
+	w := byte(0)
+
+	if byteCount == 8 {
+		w = 1
+	}
+
+	if w != 0 || reg >= 8 || rm >= 8 || (byteCount == 1 && (reg >= 4 || rm >= 4)) {
+		a.WriteBytes(opcode.REX(w, reg>>3, 0, rm>>3))
+	}
+}
+
+// encodeSizedRegisterRegister encodes an instruction on the lower bytes of 2 registers
+// with the source in the reg field and the destination in the rm field.
+func encodeSizedRegisterRegister(a *asm.Assembler, code byte, destination string, source string, byteCount byte) {
+	to := registerCodes[destination]
+	from := registerCodes[source]
+	encodeSizePrefix(a, byteCount, from, to)
+
+	if byteCount == 1 {
+		code--
+	}
+
+	a.WriteBytes(code, opcode.ModRM(0b11, from&0b111, to&0b111))
+}
+
+// encodeSizedRegister encodes an instruction on the lower bytes of a single register.
+// The extension selects the operation in the reg field of ModRM.
+func encodeSizedRegister(a *asm.Assembler, code byte, extension byte, destination string, byteCount byte) {
+	to := registerCodes[destination]
+	encodeSizePrefix(a, byteCount, 0, to)
+
+	if byteCount == 1 {
+		code--
+	}
+
+	a.WriteBytes(code, opcode.ModRM(0b11, extension, to&0b111))
+}
+
+// encodeSizedRegisterNumber encodes an instruction of the 0x80 / 0x81 / 0x83 group
+// on the lower bytes of the register. Immediate values are never larger than 32 bits.
+func encodeSizedRegisterNumber(a *asm.Assembler, extension byte, destination string, number uint64, byteCount byte) {
+	to := registerCodes[destination]
+	encodeSizePrefix(a, byteCount, 0, to)
+
+	switch {
+	case byteCount == 1:
+		a.WriteBytes(0x80, opcode.ModRM(0b11, extension, to&0b111), byte(number))
+
+	case int64(number) >= -128 && int64(number) <= 127:
+		a.WriteBytes(0x83, opcode.ModRM(0b11, extension, to&0b111), byte(number))
+
+	case byteCount == 2:
+		a.WriteBytes(0x81, opcode.ModRM(0b11, extension, to&0b111))
+		a.WriteUint16(uint16(number))
+
+	default:
+		a.WriteBytes(0x81, opcode.ModRM(0b11, extension, to&0b111))
+		a.WriteUint32(uint32(number))
+	}
+}
+
+// encodeSizedMoveRegisterNumber encodes a move of a number into the lower bytes of the register.
+// Moves to the lower 32 bits clear the upper 32 bits, the other sizes keep the remaining bits.
+func encodeSizedMoveRegisterNumber(a *asm.Assembler, destination string, number uint64, byteCount byte) {
+	to := registerCodes[destination]
+	encodeSizePrefix(a, byteCount, 0, to)
+
+	switch byteCount {
+	case 1:
+		a.WriteBytes(0xb0+to&0b111, byte(number))
+
+	case 2:
+		a.WriteBytes(0xb8 + to&0b111)
+		a.WriteUint16(uint16(number))
+
+	default:
+		a.WriteBytes(0xb8 + to&0b111)
+		a.WriteUint32(uint32(number))
+	}
+}
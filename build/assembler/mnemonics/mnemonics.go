@@ -2,12 +2,25 @@ package mnemonics
 
 const (
 	MOV     = "mov"
+	MOVZX   = "movzx"
+	MOVSX   = "movsx"
+	LEA     = "lea"
 	CMP     = "cmp"
+	TEST    = "test"
 	ADD     = "add"
 	SUB     = "sub"
 	MUL     = "imul"
 	DIV     = "idiv"
+	UDIV    = "div"
 	CDQ     = "cdq"
+	AND     = "and"
+	OR      = "or"
+	XOR     = "xor"
+	SHL     = "shl"
+	SAR     = "sar"
+	SHR     = "shr"
+	SHLX    = "shlx"
+	SARX    = "sarx"
 	RET     = "ret"
 	SYSCALL = "syscall"
 	CALL    = "call"
@@ -18,13 +31,46 @@ const (
 	JLE     = "jle"
 	JG      = "jg"
 	JGE     = "jge"
+	JB      = "jb"
+	JBE     = "jbe"
+	JA      = "ja"
+	JAE     = "jae"
+	JO      = "jo"
+	CMOVL   = "cmovl"
+	CMOVG   = "cmovg"
+	SETE    = "sete"
+	SETNE   = "setne"
+	SETL    = "setl"
+	SETLE   = "setle"
+	SETG    = "setg"
+	SETGE   = "setge"
+	SETB    = "setb"
+	SETBE   = "setbe"
+	SETA    = "seta"
+	SETAE   = "setae"
 	INC     = "inc"
 	DEC     = "dec"
+	NEG     = "neg"
+	NOT     = "not"
 	PUSH    = "push"
 	POP     = "pop"
 	CPUID   = "cpuid"
 
+	// Floating-point
+	MOVQ      = "movq"
+	ADDSD     = "addsd"
+	SUBSD     = "subsd"
+	MULSD     = "mulsd"
+	DIVSD     = "divsd"
+	CVTSI2SD  = "cvtsi2sd"
+	CVTSD2SI  = "cvtsd2si"
+	CVTTSD2SI = "cvttsd2si"
+
 	// Artificial
-	STORE = "store"
-	LOAD  = "load"
+	STORE      = "store"
+	LOAD       = "load"
+	LOADZX     = "loadzx"
+	LOADSX     = "loadsx"
+	STACKCHECK = "stackcheck"
+	STACKSTART = "stackstart"
 )
@@ -0,0 +1,38 @@
+package dwarf
+
+// Info contains the debug information of an executable.
+// Addresses are relative to the start of the machine code.
+type Info struct {
+	Producer  string
+	Name      string
+	Directory string
+	Functions []Function
+	Lines     []Line
+}
+
+// Function describes the address range of a function.
+type Function struct {
+	Name  string
+	Start uint64
+	End   uint64
+}
+
+// Line maps an address to a position in a source file.
+type Line struct {
+	Address uint64
+	File    string
+	Line    int
+	Column  int
+}
+
+// SectionNames contains the names of the debug sections in the order returned by Sections.
+var SectionNames = []string{".debug_abbrev", ".debug_info", ".debug_line"}
+
+// Sections returns the encoded debug sections for code that is loaded at the given address.
+func (info *Info) Sections(codeAddress uint64, codeSize uint64) [][]byte {
+	return [][]byte{
+		info.abbreviations(),
+		info.compilationUnit(codeAddress, codeSize),
+		info.lineProgram(codeAddress, codeSize),
+	}
+}
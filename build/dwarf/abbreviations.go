@@ -0,0 +1,43 @@
+package dwarf
+
+import "bytes"
+
+// abbreviations returns the .debug_abbrev section.
+// It describes the attributes of the compilation unit and the functions.
+func (info *Info) abbreviations() []byte {
+	buffer := bytes.Buffer{}
+
+	writeUnsigned(&buffer, abbrevCompileUnit)
+	writeUnsigned(&buffer, tagCompileUnit)
+	buffer.WriteByte(childrenYes)
+	writeAttributes(&buffer,
+		attributeProducer, formString,
+		attributeName, formString,
+		attributeCompDir, formString,
+		attributeStmtList, formSectionOffset,
+		attributeLowPC, formAddress,
+		attributeHighPC, formData8,
+	)
+
+	writeUnsigned(&buffer, abbrevSubprogram)
+	writeUnsigned(&buffer, tagSubprogram)
+	buffer.WriteByte(childrenNo)
+	writeAttributes(&buffer,
+		attributeName, formString,
+		attributeLowPC, formAddress,
+		attributeHighPC, formData8,
+	)
+
+	buffer.WriteByte(0)
+	return buffer.Bytes()
+}
+
+// writeAttributes writes pairs of attribute and form codes followed by the terminating zeros.
+func writeAttributes(buffer *bytes.Buffer, pairs ...uint64) {
+	for _, code := range pairs {
+		writeUnsigned(buffer, code)
+	}
+
+	buffer.WriteByte(0)
+	buffer.WriteByte(0)
+}
@@ -0,0 +1,30 @@
+package dwarf
+
+import "bytes"
+
+// compilationUnit returns the .debug_info section.
+// The whole program is a single compilation unit containing all functions.
+func (info *Info) compilationUnit(codeAddress uint64, codeSize uint64) []byte {
+	buffer := bytes.Buffer{}
+	writeUint16(&buffer, version)
+	writeUint32(&buffer, 0)
+	buffer.WriteByte(addressSize)
+
+	writeUnsigned(&buffer, abbrevCompileUnit)
+	writeString(&buffer, info.Producer)
+	writeString(&buffer, info.Name)
+	writeString(&buffer, info.Directory)
+	writeUint32(&buffer, 0)
+	writeUint64(&buffer, codeAddress)
+	writeUint64(&buffer, codeSize)
+
+	for _, function := range info.Functions {
+		writeUnsigned(&buffer, abbrevSubprogram)
+		writeString(&buffer, function.Name)
+		writeUint64(&buffer, codeAddress+function.Start)
+		writeUint64(&buffer, function.End-function.Start)
+	}
+
+	buffer.WriteByte(0)
+	return withLength(buffer.Bytes())
+}
@@ -0,0 +1,47 @@
+package dwarf
+
+// version is the DWARF version used for all sections.
+const version = 4
+
+const (
+	tagCompileUnit = 0x11
+	tagSubprogram  = 0x2e
+	childrenNo     = 0
+	childrenYes    = 1
+)
+
+const (
+	attributeName      = 0x03
+	attributeStmtList  = 0x10
+	attributeLowPC     = 0x11
+	attributeHighPC    = 0x12
+	attributeCompDir   = 0x1b
+	attributeProducer  = 0x25
+	formAddress        = 0x01
+	formData8          = 0x07
+	formString         = 0x08
+	formSectionOffset  = 0x17
+	abbrevCompileUnit  = 1
+	abbrevSubprogram   = 2
+	addressSize        = 8
+	minInstructionSize = 1
+)
+
+// Line number program opcodes
+const (
+	lineCopy          = 0x01
+	lineAdvancePC     = 0x02
+	lineAdvanceLine   = 0x03
+	lineSetFile       = 0x04
+	lineSetColumn     = 0x05
+	lineEndSequence   = 0x01
+	lineSetAddress    = 0x02
+	lineBase          = -5
+	lineRange         = 14
+	lineOpcodeBase    = 13
+	lineDefaultIsStmt = 1
+	lineMaxOperations = 1
+)
+
+// standardOpcodeLengths contains the number of operands of the standard opcodes 1 to 12.
+var standardOpcodeLengths = []byte{0, 1, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1}
@@ -0,0 +1,176 @@
+package dwarf
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/akyoto/asm"
+	"github.com/akyoto/asm/elf"
+)
+
+const (
+	baseAddress  = 0x400000
+	pageSize     = 0x1000
+	sectionAlign = 16
+)
+
+// Section is an additional named section that is not loaded into memory.
+type Section struct {
+	Name  string
+	Type  elf.SectionType
+	Align int64
+	Data  []byte
+}
+
+// NewELF creates a 64-bit ELF binary that includes the debug sections and the extra sections.
+// Unlike the default ELF layout, the sections are named so that debuggers can find them.
+// The debug sections are omitted if the debug information is nil.
+// The code and data are mapped by a single segment that starts at the beginning of the file.
+// The relative pointers are data references that are relative to the instruction pointer.
+func NewELF(a *asm.Assembler, info *Info, relativePointers []asm.Pointer, extra []Section) *elf.ELF64 {
+	code := a.Code()
+	data := a.Data()
+	pointers := a.Pointers()
+	names := bytes.Buffer{}
+	names.WriteByte(0)
+
+	file := &elf.ELF64{
+		Header64: elf.Header64{
+			Magic:                  [4]byte{0x7F, 'E', 'L', 'F'},
+			Class:                  2,
+			Endianness:             1, // Little endianness
+			Version:                1,
+			Type:                   0x02,
+			Architecture:           0x3E, // x86-64
+			FileVersion:            1,
+			Size:                   elf.Header64Size,
+			ProgramHeaderEntrySize: elf.ProgramHeader64Size,
+			SectionHeaderEntrySize: elf.SectionHeader64Size,
+			ProgramHeaderOffset:    elf.Header64Size,
+		},
+	}
+
+	addSection := func(name string, data []byte, typ elf.SectionType, flags elf.SectionFlags) *elf.Section {
+		section := &elf.Section{
+			Header: elf.SectionHeader64{
+				NameOffset:      int32(names.Len()),
+				Type:            typ,
+				Flags:           flags,
+				SizeInFileImage: int64(len(data)),
+				Align:           1,
+			},
+			Data: data,
+		}
+
+		writeString(&names, name)
+		file.Sections = append(file.Sections, section)
+		return section
+	}
+
+	// The code is written by the program, the text section only refers to it
+	file.Sections = append(file.Sections, &elf.Section{})
+	text := addSection(".text", nil, elf.SectionTypePROGBITS, elf.SectionFlagsAllocate|elf.SectionFlagsExecutable)
+	rodata := addSection(".rodata", data, elf.SectionTypePROGBITS, elf.SectionFlagsAllocate)
+	debug := make([]*elf.Section, 0, len(SectionNames))
+
+	if info != nil {
+		for _, name := range SectionNames {
+			debug = append(debug, addSection(name, nil, elf.SectionTypePROGBITS, 0))
+		}
+	}
+
+	others := make([]*elf.Section, 0, len(extra))
+
+	for _, section := range extra {
+		other := addSection(section.Name, section.Data, section.Type, 0)
+		other.Header.Align = section.Align
+		others = append(others, other)
+	}
+
+	stringTable := addSection(".shstrtab", nil, elf.SectionTypeSTRTAB, 0)
+	stringTable.Data = names.Bytes()
+	stringTable.Header.SizeInFileImage = int64(len(stringTable.Data))
+
+	file.Programs = []*elf.Program{
+		{
+			Header: elf.ProgramHeader64{
+				Type:            elf.ProgramTypeLOAD,
+				Flags:           elf.ProgramFlagsReadable | elf.ProgramFlagsExecutable,
+				VirtualAddress:  baseAddress,
+				PhysicalAddress: baseAddress,
+				Align:           pageSize,
+			},
+			Data: code,
+		},
+	}
+
+	file.ProgramHeaderEntryCount = int16(len(file.Programs))
+	file.SectionHeaderEntryCount = int16(len(file.Sections))
+	file.SectionNameStringTableIndex = int16(len(file.Sections) - 1)
+	file.SectionHeaderOffset = file.ProgramHeaderOffset + int64(file.ProgramHeaderEntryCount)*int64(file.ProgramHeaderEntrySize)
+	offset := file.SectionHeaderOffset + int64(file.SectionHeaderEntryCount)*int64(file.SectionHeaderEntrySize)
+
+	// Code
+	padding := calculatePadding(offset, sectionAlign)
+	offset += padding
+	file.Programs[0].Padding = make([]byte, padding)
+	codeOffset := offset
+	text.Header.Offset = codeOffset
+	text.Header.VirtualAddress = baseAddress + codeOffset
+	text.Header.SizeInFileImage = int64(len(code))
+	text.Header.Align = sectionAlign
+	offset += int64(len(code))
+
+	// Data
+	padding = calculatePadding(offset, sectionAlign)
+	offset += padding
+	rodata.Padding = make([]byte, padding)
+	dataOffset := offset
+	rodata.Header.Offset = dataOffset
+	rodata.Header.VirtualAddress = baseAddress + dataOffset
+	rodata.Header.Align = sectionAlign
+	offset += int64(len(data))
+
+	// The segment maps everything up to the end of the data
+	file.Programs[0].Header.SizeInFileImage = offset
+	file.Programs[0].Header.SizeInMemory = offset
+	file.EntryPointInMemory = baseAddress + codeOffset
+
+	// Debug sections are not loaded into memory
+	if info != nil {
+		debugSections := info.Sections(uint64(baseAddress+codeOffset), uint64(len(code)))
+
+		for i, section := range debug {
+			section.Data = debugSections[i]
+			section.Header.SizeInFileImage = int64(len(section.Data))
+		}
+	}
+
+	for _, section := range append(append(debug, others...), stringTable) {
+		padding = calculatePadding(offset, section.Header.Align)
+		offset += padding
+		section.Padding = make([]byte, padding)
+		section.Header.Offset = offset
+		offset += section.Header.SizeInFileImage
+	}
+
+	// Add section offset to all string addresses
+	for _, pointer := range pointers {
+		oldAddressSlice := code[pointer.Position : pointer.Position+4]
+		newAddress := uint32(baseAddress) + uint32(dataOffset) + pointer.Address
+		binary.LittleEndian.PutUint32(oldAddressSlice, newAddress)
+	}
+
+	// Replace the data addresses by their distance to the end of the instruction
+	for _, pointer := range relativePointers {
+		displacementSlice := code[pointer.Position : pointer.Position+4]
+		displacement := uint32(dataOffset) + pointer.Address - (uint32(codeOffset) + pointer.Position + 4)
+		binary.LittleEndian.PutUint32(displacementSlice, displacement)
+	}
+
+	return file
+}
+
+func calculatePadding(n int64, align int64) int64 {
+	return (align - (n % align)) % align
+}
@@ -0,0 +1,65 @@
+package dwarf
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// writeUnsigned writes an unsigned LEB128 number.
+func writeUnsigned(buffer *bytes.Buffer, number uint64) {
+	for {
+		b := byte(number & 0x7f)
+		number >>= 7
+
+		if number == 0 {
+			buffer.WriteByte(b)
+			return
+		}
+
+		buffer.WriteByte(b | 0x80)
+	}
+}
+
+// writeSigned writes a signed LEB128 number.
+func writeSigned(buffer *bytes.Buffer, number int64) {
+	for {
+		b := byte(number & 0x7f)
+		number >>= 7
+
+		if (number == 0 && b&0x40 == 0) || (number == -1 && b&0x40 != 0) {
+			buffer.WriteByte(b)
+			return
+		}
+
+		buffer.WriteByte(b | 0x80)
+	}
+}
+
+// writeString writes a null-terminated string.
+func writeString(buffer *bytes.Buffer, text string) {
+	buffer.WriteString(text)
+	buffer.WriteByte(0)
+}
+
+// writeUint16 writes a 16-bit number in little endian format.
+func writeUint16(buffer *bytes.Buffer, number uint16) {
+	_ = binary.Write(buffer, binary.LittleEndian, number)
+}
+
+// writeUint32 writes a 32-bit number in little endian format.
+func writeUint32(buffer *bytes.Buffer, number uint32) {
+	_ = binary.Write(buffer, binary.LittleEndian, number)
+}
+
+// writeUint64 writes a 64-bit number in little endian format.
+func writeUint64(buffer *bytes.Buffer, number uint64) {
+	_ = binary.Write(buffer, binary.LittleEndian, number)
+}
+
+// withLength prefixes the data with its 32-bit length.
+func withLength(data []byte) []byte {
+	buffer := bytes.Buffer{}
+	writeUint32(&buffer, uint32(len(data)))
+	buffer.Write(data)
+	return buffer.Bytes()
+}
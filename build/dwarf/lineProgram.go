@@ -0,0 +1,103 @@
+package dwarf
+
+import "bytes"
+
+// lineProgram returns the .debug_line section.
+// The program generates a table that maps each address to a line in a source file.
+func (info *Info) lineProgram(codeAddress uint64, codeSize uint64) []byte {
+	files := []string{}
+	fileIndex := map[string]uint64{}
+
+	for _, line := range info.Lines {
+		if fileIndex[line.File] == 0 {
+			files = append(files, line.File)
+			fileIndex[line.File] = uint64(len(files))
+		}
+	}
+
+	// Header
+	header := bytes.Buffer{}
+	header.WriteByte(minInstructionSize)
+	header.WriteByte(lineMaxOperations)
+	header.WriteByte(lineDefaultIsStmt)
+	header.WriteByte(byte(lineBase & 0xff))
+	header.WriteByte(lineRange)
+	header.WriteByte(lineOpcodeBase)
+	header.Write(standardOpcodeLengths)
+
+	// The file names are absolute, therefore no include directories are needed
+	header.WriteByte(0)
+
+	for _, file := range files {
+		writeString(&header, file)
+		writeUnsigned(&header, 0)
+		writeUnsigned(&header, 0)
+		writeUnsigned(&header, 0)
+	}
+
+	header.WriteByte(0)
+
+	// Program
+	program := bytes.Buffer{}
+	program.WriteByte(0)
+	writeUnsigned(&program, 1+addressSize)
+	program.WriteByte(lineSetAddress)
+	writeUint64(&program, codeAddress)
+
+	var (
+		address uint64
+		file    uint64 = 1
+		line           = 1
+		column         = 0
+	)
+
+	for i, row := range info.Lines {
+		// Instructions without machine code share the address with the next row
+		if i+1 < len(info.Lines) && info.Lines[i+1].Address == row.Address {
+			continue
+		}
+
+		if fileIndex[row.File] != file {
+			file = fileIndex[row.File]
+			program.WriteByte(lineSetFile)
+			writeUnsigned(&program, file)
+		}
+
+		if row.Column != column {
+			column = row.Column
+			program.WriteByte(lineSetColumn)
+			writeUnsigned(&program, uint64(column))
+		}
+
+		if row.Line != line {
+			program.WriteByte(lineAdvanceLine)
+			writeSigned(&program, int64(row.Line-line))
+			line = row.Line
+		}
+
+		if row.Address != address {
+			program.WriteByte(lineAdvancePC)
+			writeUnsigned(&program, row.Address-address)
+			address = row.Address
+		}
+
+		program.WriteByte(lineCopy)
+	}
+
+	if codeSize > address {
+		program.WriteByte(lineAdvancePC)
+		writeUnsigned(&program, codeSize-address)
+	}
+
+	program.WriteByte(0)
+	writeUnsigned(&program, 1)
+	program.WriteByte(lineEndSequence)
+
+	// Section
+	section := bytes.Buffer{}
+	writeUint16(&section, version)
+	writeUint32(&section, uint32(header.Len()))
+	section.Write(header.Bytes())
+	section.Write(program.Bytes())
+	return withLength(section.Bytes())
+}
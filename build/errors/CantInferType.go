@@ -10,3 +10,7 @@ type CantInferType struct {
 func (err *CantInferType) Error() string {
 	return fmt.Sprintf("Can't infer type of expression '%s'", err.Expression)
 }
+
+func (err *CantInferType) Code() string {
+	return "CantInferType"
+}
@@ -1,27 +1,49 @@
 package errors
 
 var (
-	ExceededMaxParameters       = &simple{"Exceeded maximum number of parameters per function", false}
-	ExceededMaxVariables        = &simple{"Exceeded maximum limit of variables per function", false}
-	ExpectedVariable            = &simple{"Expected variable on the left side of the assignment", false}
-	InvalidExpression           = &simple{"Invalid expression", false}
-	InvalidFunctionName         = &simple{"A function can not be named 'func' or 'fn'", false}
-	InvalidInstruction          = &simple{"Invalid instruction", false}
-	MissingArrayIndex           = &simple{"Missing array index", false}
-	MissingAssignmentOperator   = &simple{"Missing assignment operator", false}
-	MissingAssignmentExpression = &simple{"Missing assignment expression", false}
-	MissingEndingNewline        = &simple{"Missing newline at the end of the file", false}
-	MissingFunctionName         = &simple{"Expected function name before '('", false}
-	MissingParameter            = &simple{"Missing parameter", false}
-	MissingRange                = &simple{"Missing range expression in for loop", false}
-	MissingRangeStart           = &simple{"Missing starting value in range expression", false}
-	MissingRangeLimit           = &simple{"Missing upper limit in range expression", true}
-	MissingReturnType           = &simple{"Missing function return type", false}
-	MissingStructName           = &simple{"Missing struct name", false}
-	NotImplemented              = &simple{"Not implemented", false}
-	ParameterOpeningBracket     = &simple{"Missing opening bracket '(' after the function name", false}
-	ReturnWithoutFunctionType   = &simple{"Returning a value in a function without a return type", false}
-	EnsureWithoutFunctionType   = &simple{"Ensuring a value in a function without a return type", false}
-	TopLevel                    = &simple{"Only function definitions are allowed at the top level", false}
-	UnnecessaryNewlines         = &simple{"More than 2 successive empty lines", false}
+	AssertInExpression          = &simple{"AssertInExpression", "'assert' can only be used as a statement", false}
+	BreakOutsideLoop            = &simple{"BreakOutsideLoop", "'break' can only be used inside a loop", false}
+	CaseAfterElse               = &simple{"CaseAfterElse", "The 'else' case must be the last case of a switch", false}
+	ContinueOutsideLoop         = &simple{"ContinueOutsideLoop", "'continue' can only be used inside a loop", false}
+	DeferInsideBlock            = &simple{"DeferInsideBlock", "'defer' can only be used at the top level of a function", false}
+	DivisionByZero              = &simple{"DivisionByZero", "Division by zero", false}
+	ExceededMaxParameters       = &simple{"ExceededMaxParameters", "Exceeded maximum number of parameters per function", false}
+	ExceededMaxVariables        = &simple{"ExceededMaxVariables", "Exceeded maximum limit of variables per function", false}
+	ExceededMaxSpillSlots       = &simple{"ExceededMaxSpillSlots", "Exceeded maximum stack frame size of 128 bytes for spilled variables", false}
+	ExpectedVariable            = &simple{"ExpectedVariable", "Expected variable on the left side of the assignment", false}
+	ExpectedTypeName            = &simple{"ExpectedTypeName", "Expected a type name", false}
+	InvalidExpression           = &simple{"InvalidExpression", "Invalid expression", false}
+	InvalidFunctionName         = &simple{"InvalidFunctionName", "A function can not be named 'func' or 'fn'", false}
+	InvalidInstruction          = &simple{"InvalidInstruction", "Invalid instruction", false}
+	InvalidMainParameters       = &simple{"InvalidMainParameters", "'main' can only have the parameters 'argc Int' and 'argv Pointer'", false}
+	InvalidStep                 = &simple{"InvalidStep", "Step must be a positive number", false}
+	InvalidArraySize            = &simple{"InvalidArraySize", "Array size must be a positive number of bytes below 2 GiB", false}
+	InvalidByteCount            = &simple{"InvalidByteCount", "Byte count must be 1, 2, 4 or 8", false}
+	InvalidConcatenation        = &simple{"InvalidConcatenation", "Texts can only be concatenated with other text literals", false}
+	MissingArrayIndex           = &simple{"MissingArrayIndex", "Missing array index", false}
+	MissingAssignmentOperator   = &simple{"MissingAssignmentOperator", "Missing assignment operator", false}
+	MissingAssignmentExpression = &simple{"MissingAssignmentExpression", "Missing assignment expression", false}
+	MissingEndingNewline        = &simple{"MissingEndingNewline", "Missing newline at the end of the file", false}
+	MissingFunctionName         = &simple{"MissingFunctionName", "Expected function name before '('", false}
+	MissingAnnotatedFunction    = &simple{"MissingAnnotatedFunction", "Expected a function definition after the annotation", false}
+	MissingIf                   = &simple{"MissingIf", "Expected 'if' block before 'else'", false}
+	MissingOperand              = &simple{"MissingOperand", "Missing operand", true}
+	MissingOperator             = &simple{"MissingOperator", "Missing operator between operands", false}
+	MissingParameter            = &simple{"MissingParameter", "Missing parameter", false}
+	MissingRange                = &simple{"MissingRange", "Missing range expression in for loop", false}
+	MissingRangeStart           = &simple{"MissingRangeStart", "Missing starting value in range expression", false}
+	MissingRangeLimit           = &simple{"MissingRangeLimit", "Missing upper limit in range expression", true}
+	MissingRepeatCount          = &simple{"MissingRepeatCount", "Missing number of repetitions after 'repeat'", true}
+	MissingReturnType           = &simple{"MissingReturnType", "Missing function return type", false}
+	MissingSwitchValue          = &simple{"MissingSwitchValue", "Missing value after 'switch'", false}
+	MissingStructName           = &simple{"MissingStructName", "Missing struct name", false}
+	NotConstant                 = &simple{"NotConstant", "Expected an integer expression that can be calculated at compile time", false}
+	NotImplemented              = &simple{"NotImplemented", "Not implemented", false}
+	ParameterOpeningBracket     = &simple{"ParameterOpeningBracket", "Missing opening bracket '(' after the function name", false}
+	RecursiveInline             = &simple{"RecursiveInline", "Recursive functions can't be inlined", false}
+	ReturnWithoutFunctionType   = &simple{"ReturnWithoutFunctionType", "Returning a value in a function without a return type", false}
+	EnsureWithoutFunctionType   = &simple{"EnsureWithoutFunctionType", "Ensuring a value in a function without a return type", false}
+	TopLevel                    = &simple{"TopLevel", "Only function definitions are allowed at the top level", false}
+	UnnecessaryNewlines         = &simple{"UnnecessaryNewlines", "More than 2 successive empty lines", false}
+	UnterminatedComment         = &simple{"UnterminatedComment", "Missing '*/' at the end of the comment", false}
 )
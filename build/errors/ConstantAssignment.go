@@ -0,0 +1,18 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// ConstantAssignment represents an assignment to a constant.
+type ConstantAssignment struct {
+	Name string
+}
+
+func (err *ConstantAssignment) Error() string {
+	return fmt.Sprintf("Constant '%s' can not be modified", err.Name)
+}
+
+func (err *ConstantAssignment) Code() string {
+	return "ConstantAssignment"
+}
@@ -0,0 +1,62 @@
+package errors
+
+// Coded is implemented by all error types in this package.
+// The code is the name of the error and doesn't change when the message is reworded.
+type Coded interface {
+	error
+	Code() string
+}
+
+// Diagnostic is an error at a position in the source code.
+// Editors and other tools can process diagnostics without parsing the error message.
+type Diagnostic interface {
+	Coded
+	Message() string
+	Position() Position
+}
+
+// Position is the location of an error in a source file.
+// Lines and columns start at 1.
+type Position struct {
+	Path   string
+	Line   int
+	Column int
+}
+
+// UnknownCode is the code of errors that don't implement the Coded interface.
+const UnknownCode = "Error"
+
+// Code returns the code of the error.
+func Code(err error) string {
+	coded, ok := Unwrap(err).(Coded)
+
+	if !ok {
+		return UnknownCode
+	}
+
+	return coded.Code()
+}
+
+// Message returns the error message without the position and the stack.
+func Message(err error) string {
+	diagnostic, ok := err.(Diagnostic)
+
+	if ok {
+		return diagnostic.Message()
+	}
+
+	return Unwrap(err).Error()
+}
+
+// Unwrap returns the error without the stack information.
+func Unwrap(err error) error {
+	for {
+		withStack, ok := err.(*WithStack)
+
+		if !ok {
+			return err
+		}
+
+		err = withStack.Err
+	}
+}
@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// DuplicateCase represents a switch with the same value in multiple cases.
+type DuplicateCase struct {
+	Value int64
+}
+
+func (err *DuplicateCase) Error() string {
+	return fmt.Sprintf("Duplicate case value '%d'", err.Value)
+}
+
+func (err *DuplicateCase) Code() string {
+	return "DuplicateCase"
+}
@@ -0,0 +1,21 @@
+package errors
+
+import "fmt"
+
+// EmptyRange represents a constant range where the start is not less than the upper limit.
+type EmptyRange struct {
+	Start int64
+	Limit int64
+}
+
+func (err *EmptyRange) Error() string {
+	if err.Start > err.Limit {
+		return fmt.Sprintf("Range '%d..%d' never executes, descending ranges are not supported", err.Start, err.Limit)
+	}
+
+	return fmt.Sprintf("Range '%d..%d' never executes", err.Start, err.Limit)
+}
+
+func (err *EmptyRange) Code() string {
+	return "EmptyRange"
+}
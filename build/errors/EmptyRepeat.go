@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// EmptyRepeat represents a repeat loop with a constant count that is not positive.
+type EmptyRepeat struct {
+	Count int64
+}
+
+func (err *EmptyRepeat) Error() string {
+	return fmt.Sprintf("'repeat %d' never executes", err.Count)
+}
+
+func (err *EmptyRepeat) Code() string {
+	return "EmptyRepeat"
+}
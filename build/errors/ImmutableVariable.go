@@ -10,3 +10,7 @@ type ImmutableVariable struct {
 func (err *ImmutableVariable) Error() string {
 	return fmt.Sprintf("Variable '%s' can not be modified (make it mutable via 'mut %s')", err.Name, err.Name)
 }
+
+func (err *ImmutableVariable) Code() string {
+	return "ImmutableVariable"
+}
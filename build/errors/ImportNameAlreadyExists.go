@@ -11,3 +11,7 @@ type ImportNameAlreadyExists struct {
 func (err *ImportNameAlreadyExists) Error() string {
 	return fmt.Sprintf("Package '%s' has already been imported from '%s'", err.Name, err.ImportPath)
 }
+
+func (err *ImportNameAlreadyExists) Code() string {
+	return "ImportNameAlreadyExists"
+}
@@ -10,3 +10,7 @@ type IneffectiveAssignment struct {
 func (err *IneffectiveAssignment) Error() string {
 	return fmt.Sprintf("This value of '%s' has never been used", err.Name)
 }
+
+func (err *IneffectiveAssignment) Code() string {
+	return "IneffectiveAssignment"
+}
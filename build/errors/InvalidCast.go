@@ -0,0 +1,17 @@
+package errors
+
+import "fmt"
+
+// InvalidCast represents an error where a value can't be converted to the requested type.
+type InvalidCast struct {
+	From string
+	To   string
+}
+
+func (err *InvalidCast) Error() string {
+	return fmt.Sprintf("Can't cast '%s' to '%s'", err.From, err.To)
+}
+
+func (err *InvalidCast) Code() string {
+	return "InvalidCast"
+}
@@ -10,3 +10,7 @@ type InvalidCharacter struct {
 func (err *InvalidCharacter) Error() string {
 	return fmt.Sprintf("Invalid character '%s'", err.Character)
 }
+
+func (err *InvalidCharacter) Code() string {
+	return "InvalidCharacter"
+}
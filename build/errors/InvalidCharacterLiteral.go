@@ -0,0 +1,18 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// InvalidCharacterLiteral represents character literals that are empty or contain more than one character.
+type InvalidCharacterLiteral struct {
+	Expression string
+}
+
+func (err *InvalidCharacterLiteral) Error() string {
+	return fmt.Sprintf("Invalid character literal %s, expected a single character", err.Expression)
+}
+
+func (err *InvalidCharacterLiteral) Code() string {
+	return "InvalidCharacterLiteral"
+}
@@ -0,0 +1,18 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// InvalidEscapeSequence represents unknown escape sequences in text and character literals.
+type InvalidEscapeSequence struct {
+	Sequence string
+}
+
+func (err *InvalidEscapeSequence) Error() string {
+	return fmt.Sprintf("Unknown escape sequence '%s'", err.Sequence)
+}
+
+func (err *InvalidEscapeSequence) Code() string {
+	return "InvalidEscapeSequence"
+}
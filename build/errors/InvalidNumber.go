@@ -0,0 +1,18 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// InvalidNumber represents malformed number literals.
+type InvalidNumber struct {
+	Expression string
+}
+
+func (err *InvalidNumber) Error() string {
+	return fmt.Sprintf("Invalid number literal '%s'", err.Expression)
+}
+
+func (err *InvalidNumber) Code() string {
+	return "InvalidNumber"
+}
@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// InvalidOperand represents operands that can't be used in a calculation.
+type InvalidOperand struct {
+	Operand string
+}
+
+func (err *InvalidOperand) Error() string {
+	return fmt.Sprintf("Invalid operand %s", err.Operand)
+}
+
+func (err *InvalidOperand) Code() string {
+	return "InvalidOperand"
+}
@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// InvalidOperands represents an operand combination that the instruction doesn't support.
+type InvalidOperands struct {
+	Mnemonic string
+}
+
+func (err *InvalidOperands) Error() string {
+	return fmt.Sprintf("Invalid operands for '%s'", err.Mnemonic)
+}
+
+func (err *InvalidOperands) Code() string {
+	return "InvalidOperands"
+}
@@ -0,0 +1,17 @@
+package errors
+
+import "fmt"
+
+// InvalidPrintParameter represents print calls with a value that can't be printed.
+type InvalidPrintParameter struct {
+	FunctionName string
+	Parameter    string
+}
+
+func (err *InvalidPrintParameter) Error() string {
+	return fmt.Sprintf("'%s' only accepts texts, booleans, integers and floating-point numbers instead of '%s'", err.FunctionName, err.Parameter)
+}
+
+func (err *InvalidPrintParameter) Code() string {
+	return "InvalidPrintParameter"
+}
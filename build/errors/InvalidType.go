@@ -16,3 +16,7 @@ func (err *InvalidType) Error() string {
 
 	return fmt.Sprintf("Expected type '%s' instead of '%s'", err.Expected, err.Name)
 }
+
+func (err *InvalidType) Code() string {
+	return "InvalidType"
+}
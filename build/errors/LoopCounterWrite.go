@@ -0,0 +1,18 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// LoopCounterWrite represents an assignment to the counter of a for loop inside the loop body.
+type LoopCounterWrite struct {
+	Name string
+}
+
+func (err *LoopCounterWrite) Error() string {
+	return fmt.Sprintf("Loop counter '%s' is modified inside the loop", err.Name)
+}
+
+func (err *LoopCounterWrite) Code() string {
+	return "LoopCounterWrite"
+}
@@ -19,3 +19,7 @@ func (err *MissingCharacter) Error() string {
 		return fmt.Sprintf("Missing character '%s'", err.Character)
 	}
 }
+
+func (err *MissingCharacter) Code() string {
+	return "MissingCharacter"
+}
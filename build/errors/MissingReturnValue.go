@@ -11,3 +11,7 @@ type MissingReturnValue struct {
 func (err *MissingReturnValue) Error() string {
 	return fmt.Sprintf("Missing return value of type '%s'", err.ReturnType)
 }
+
+func (err *MissingReturnValue) Code() string {
+	return "MissingReturnValue"
+}
@@ -10,3 +10,7 @@ type MissingType struct {
 func (err *MissingType) Error() string {
 	return fmt.Sprintf("Missing type of '%s'", err.Of)
 }
+
+func (err *MissingType) Code() string {
+	return "MissingType"
+}
@@ -10,3 +10,7 @@ type NotANumber struct {
 func (err *NotANumber) Error() string {
 	return fmt.Sprintf("Not a number: %s", err.Expression)
 }
+
+func (err *NotANumber) Code() string {
+	return "NotANumber"
+}
@@ -0,0 +1,40 @@
+package errors
+
+// WithOffset describes an error at a token that is not the start of the statement.
+// The offset is the position of the token in the source file.
+type WithOffset struct {
+	Err    error
+	Offset uint16
+}
+
+func (err *WithOffset) Error() string {
+	return err.Err.Error()
+}
+
+func (err *WithOffset) Code() string {
+	return Code(err.Err)
+}
+
+func (err *WithOffset) Unwrap() error {
+	return err.Err
+}
+
+// NewAt creates a new error with stack information
+// at the token with the given offset in the source file.
+func NewAt(err error, offset uint16) *WithStack {
+	return &WithStack{
+		Err:   &WithOffset{Err: err, Offset: offset},
+		Stack: stack(),
+	}
+}
+
+// Offset returns the offset of the token the error refers to.
+func Offset(err error) (uint16, bool) {
+	withOffset, ok := Unwrap(err).(*WithOffset)
+
+	if !ok {
+		return 0, false
+	}
+
+	return withOffset.Offset, true
+}
@@ -15,3 +15,7 @@ func (err *PackageDoesntExist) Error() string {
 
 	return fmt.Sprintf("Package '%s' doesn't exist in '%s'", err.ImportPath, err.FilePath)
 }
+
+func (err *PackageDoesntExist) Code() string {
+	return "PackageDoesntExist"
+}
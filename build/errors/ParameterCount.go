@@ -20,3 +20,7 @@ func (err *ParameterCount) Error() string {
 
 	return ""
 }
+
+func (err *ParameterCount) Code() string {
+	return "ParameterCount"
+}
@@ -18,15 +18,31 @@ func (err *WithStack) Error() string {
 	return fmt.Sprintf("%v\n\n%s", err.Err, log.FaintColor.Sprint(err.Stack))
 }
 
+func (err *WithStack) Code() string {
+	return Code(err.Err)
+}
+
+func (err *WithStack) Unwrap() error {
+	return err.Err
+}
+
 // New creates a new error with stack information.
 func New(err error) *WithStack {
+	return &WithStack{
+		Err:   err,
+		Stack: stack(),
+	}
+}
+
+// stack returns the callers of the function that created the error.
+func stack() string {
 	buffer := make([]byte, 4096)
 	n := runtime.Stack(buffer, false)
 	stack := string(buffer[:n])
 	lines := strings.Split(stack, "\n")
 	var extractedLines []string
 
-	for i := 4; i < len(lines); i += 2 {
+	for i := 6; i < len(lines); i += 2 {
 		line := strings.TrimSpace(lines[i])
 		space := strings.LastIndex(line, " ")
 
@@ -37,10 +53,5 @@ func New(err error) *WithStack {
 		extractedLines = append(extractedLines, line)
 	}
 
-	stack = strings.Join(extractedLines, "\n")
-
-	return &WithStack{
-		Err:   err,
-		Stack: stack,
-	}
+	return strings.Join(extractedLines, "\n")
 }
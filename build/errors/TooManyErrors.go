@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// TooManyErrors represents the errors that have been omitted from the report.
+type TooManyErrors struct {
+	Omitted int
+}
+
+func (err *TooManyErrors) Error() string {
+	return fmt.Sprintf("Too many errors, %d more have been omitted", err.Omitted)
+}
+
+func (err *TooManyErrors) Code() string {
+	return "TooManyErrors"
+}
@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// UnknownAnnotation represents unknown function annotations.
+type UnknownAnnotation struct {
+	Name string
+}
+
+func (err *UnknownAnnotation) Error() string {
+	return fmt.Sprintf("Unknown annotation '%s'", err.Name)
+}
+
+func (err *UnknownAnnotation) Code() string {
+	return "UnknownAnnotation"
+}
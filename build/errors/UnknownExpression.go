@@ -10,3 +10,7 @@ type UnknownExpression struct {
 func (err *UnknownExpression) Error() string {
 	return fmt.Sprintf("Unknown expression '%s'", err.Expression)
 }
+
+func (err *UnknownExpression) Code() string {
+	return "UnknownExpression"
+}
@@ -16,3 +16,7 @@ func (err *UnknownField) Error() string {
 
 	return fmt.Sprintf("Type '%s' doesn't have the field '%s'", err.TypeName, err.Name)
 }
+
+func (err *UnknownField) Code() string {
+	return "UnknownField"
+}
@@ -15,3 +15,7 @@ func (err *UnknownFunction) Error() string {
 
 	return fmt.Sprintf("Unknown function '%s'", err.Name)
 }
+
+func (err *UnknownFunction) Code() string {
+	return "UnknownFunction"
+}
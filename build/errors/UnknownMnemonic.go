@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// UnknownMnemonic represents instructions in inline assembly that are not supported.
+type UnknownMnemonic struct {
+	Name string
+}
+
+func (err *UnknownMnemonic) Error() string {
+	return fmt.Sprintf("Unknown mnemonic '%s'", err.Name)
+}
+
+func (err *UnknownMnemonic) Code() string {
+	return "UnknownMnemonic"
+}
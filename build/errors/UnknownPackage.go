@@ -15,3 +15,7 @@ func (err *UnknownPackage) Error() string {
 
 	return fmt.Sprintf("Unknown package '%s'", err.Name)
 }
+
+func (err *UnknownPackage) Code() string {
+	return "UnknownPackage"
+}
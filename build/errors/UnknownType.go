@@ -15,3 +15,7 @@ func (err *UnknownType) Error() string {
 
 	return fmt.Sprintf("Unknown type '%s'", err.Name)
 }
+
+func (err *UnknownType) Code() string {
+	return "UnknownType"
+}
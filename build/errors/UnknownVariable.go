@@ -15,3 +15,7 @@ func (err *UnknownVariable) Error() string {
 
 	return fmt.Sprintf("Unknown variable '%s'", err.Name)
 }
+
+func (err *UnknownVariable) Code() string {
+	return "UnknownVariable"
+}
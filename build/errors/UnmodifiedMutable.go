@@ -10,3 +10,7 @@ type UnmodifiedMutable struct {
 func (err *UnmodifiedMutable) Error() string {
 	return fmt.Sprintf("Mutable variable '%s' has never been modified", err.Name)
 }
+
+func (err *UnmodifiedMutable) Code() string {
+	return "UnmodifiedMutable"
+}
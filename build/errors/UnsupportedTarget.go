@@ -0,0 +1,17 @@
+package errors
+
+import "fmt"
+
+// UnsupportedTarget represents builtins that can't be compiled for the target operating system.
+type UnsupportedTarget struct {
+	Name   string
+	Target string
+}
+
+func (err *UnsupportedTarget) Error() string {
+	return fmt.Sprintf("'%s' is not supported for target '%s'", err.Name, err.Target)
+}
+
+func (err *UnsupportedTarget) Code() string {
+	return "UnsupportedTarget"
+}
@@ -0,0 +1,16 @@
+package errors
+
+import "fmt"
+
+// UnusedImport represents imports that have never been used.
+type UnusedImport struct {
+	Path string
+}
+
+func (err *UnusedImport) Error() string {
+	return fmt.Sprintf("Import '%s' has never been used", err.Path)
+}
+
+func (err *UnusedImport) Code() string {
+	return "UnusedImport"
+}
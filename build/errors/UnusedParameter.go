@@ -0,0 +1,18 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// UnusedParameter represents unused function parameters.
+type UnusedParameter struct {
+	Name string
+}
+
+func (err *UnusedParameter) Error() string {
+	return fmt.Sprintf("Parameter '%s' has never been used", err.Name)
+}
+
+func (err *UnusedParameter) Code() string {
+	return "UnusedParameter"
+}
@@ -0,0 +1,18 @@
+package errors
+
+import (
+	"fmt"
+)
+
+// UnusedReturnValue represents calls of functions without side effects whose return value is thrown away.
+type UnusedReturnValue struct {
+	FunctionName string
+}
+
+func (err *UnusedReturnValue) Error() string {
+	return fmt.Sprintf("Return value of '%s' has never been used and the function has no side effects", err.FunctionName)
+}
+
+func (err *UnusedReturnValue) Code() string {
+	return "UnusedReturnValue"
+}
@@ -12,3 +12,7 @@ type UnusedVariable struct {
 func (err *UnusedVariable) Error() string {
 	return fmt.Sprintf("Variable '%s' has never been used", err.Name)
 }
+
+func (err *UnusedVariable) Code() string {
+	return "UnusedVariable"
+}
@@ -12,3 +12,7 @@ type VariableAlreadyExists struct {
 func (err *VariableAlreadyExists) Error() string {
 	return fmt.Sprintf("Variable '%s' already exists", err.Name)
 }
+
+func (err *VariableAlreadyExists) Code() string {
+	return "VariableAlreadyExists"
+}
@@ -2,6 +2,7 @@ package errors
 
 // simple is the base class for all errors.
 type simple struct {
+	ErrorCode       string
 	Message         string
 	RightSideCursor bool
 }
@@ -10,6 +11,10 @@ func (err *simple) Error() string {
 	return err.Message
 }
 
+func (err *simple) Code() string {
+	return err.ErrorCode
+}
+
 func (err *simple) CursorRight() bool {
 	return err.RightSideCursor
 }
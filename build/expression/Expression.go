@@ -66,6 +66,12 @@ func (expr *Expression) EachOperation(callBack func(*Expression) error) error {
 		return callBack(expr)
 	}
 
+	// Logical operators only evaluate the right operand when needed.
+	// We rely on the compiler evaluating both operands on its own.
+	if expr.IsLogical() {
+		return callBack(expr)
+	}
+
 	for _, child := range expr.Children {
 		err := child.EachOperation(callBack)
 
@@ -87,7 +93,7 @@ func (expr *Expression) SortByRegisterCount() {
 		child.SortByRegisterCount()
 	}
 
-	if expr.IsFunctionCall || (expr.Token.Kind == token.Operator && operators.All[string(expr.Token.Bytes)].OperandOrderImportant) {
+	if expr.IsFunctionCall || expr.IsArrayAccess() || len(expr.Children) < 2 || (expr.Token.Kind == token.Operator && operators.All[string(expr.Token.Bytes)].OperandOrderImportant) {
 		return
 	}
 
@@ -112,7 +118,7 @@ func (expr *Expression) RegisterCount() int {
 		count += child.RegisterCount()
 	}
 
-	if expr.Token.Kind == token.Operator && count == 0 {
+	if (expr.Token.Kind == token.Operator || expr.IsArrayAccess()) && count == 0 {
 		count = 1
 	}
 
@@ -131,6 +137,37 @@ func (expr *Expression) Replace(other *Expression) {
 	expr.Parent = parent
 }
 
+// IsLogical returns true if the expression is a logical `&&` or `||` operation.
+func (expr *Expression) IsLogical() bool {
+	if expr.Token.Kind != token.Operator {
+		return false
+	}
+
+	operator := expr.Token.Text()
+	return operator == "&&" || operator == "||"
+}
+
+// IsArrayAccess returns true if the expression is an array element access like `a[i]`.
+// The first child is the array and the second child is the index.
+func (expr *Expression) IsArrayAccess() bool {
+	return expr.Token.Kind == token.ArrayStart
+}
+
+// ContainsCall returns true if the expression or one of its children is a function call.
+func (expr *Expression) ContainsCall() bool {
+	if expr.IsFunctionCall {
+		return true
+	}
+
+	for _, child := range expr.Children {
+		if child.ContainsCall() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IsLeaf returns true if the expression is a leaf node with no children.
 func (expr *Expression) IsLeaf() bool {
 	return !expr.IsFunctionCall && len(expr.Children) == 0
@@ -168,6 +205,14 @@ func (expr *Expression) write(builder *strings.Builder) {
 	children := expr.Children
 	operator := expr.Token.Text()
 
+	if expr.IsArrayAccess() {
+		children[0].write(builder)
+		builder.WriteByte('[')
+		children[1].write(builder)
+		builder.WriteByte(']')
+		return
+	}
+
 	if expr.IsFunctionCall {
 		builder.WriteString(expr.Token.Text())
 		operator = ","
@@ -175,10 +220,18 @@ func (expr *Expression) write(builder *strings.Builder) {
 
 	builder.WriteByte('(')
 
+	// Unary operations
+	if len(children) == 1 && !expr.IsFunctionCall {
+		builder.WriteString(operator)
+		children[0].write(builder)
+		builder.WriteByte(')')
+		return
+	}
+
 	for index, operand := range children {
 		operand.write(builder)
 
-		if index != len(children)-1 || (len(children) == 1 && !expr.IsFunctionCall) {
+		if index != len(children)-1 {
 			builder.WriteString(operator)
 		}
 	}
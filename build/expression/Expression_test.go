@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/akyoto/assert"
+	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/expression"
 	"github.com/akyoto/q/build/token"
 )
@@ -46,6 +47,20 @@ func TestExpressionFromTokens(t *testing.T) {
 		{"Operator priority 6", "1+2*3+4*5", "((1+(2*3))+(4*5))"},
 		{"Operator priority 7", "1+2*3*4*5*6", "(1+((((2*3)*4)*5)*6))"},
 		{"Operator priority 8", "1*2*3+4*5*6", "(((1*2)*3)+((4*5)*6))"},
+		{"Bitwise operator priority", "1|2^3&4", "(1|(2^(3&4)))"},
+		{"Bitwise operator priority 2", "1&2+3", "(1&(2+3))"},
+		{"Shift operator priority", "1<<2+3", "((1<<2)+3)"},
+		{"Shift operator priority 2", "1|2<<3*4", "(1|((2<<3)*4))"},
+		{"Unary operator", "-a", "(-a)"},
+		{"Unary operator 2", "~a+1", "((~a)+1)"},
+		{"Unary operator 3", "1-~a*2", "(1-((~a)*2))"},
+		{"Unary operator 4", "-(1+2)*3", "((-(1+2))*3)"},
+		{"Unary operator 5", "-a.b(1)+2", "((-(a.b(1)))+2)"},
+		{"Unary operator 6", "~-5", "(~-5)"},
+		{"Array access", "a[1]", "a[1]"},
+		{"Array access 2", "a[i+1]*2", "(a[(i+1)]*2)"},
+		{"Array access 3", "1+a[b[0]]", "(1+a[b[0]])"},
+		{"Array access 4", "-a[0]+f(a[1], 2)", "((-a[0])+f(a[1],2))"},
 		{"Complex", "(1+2-3*4)*(5+6-7*8)", "(((1+2)-(3*4))*((5+6)-(7*8)))"},
 		{"Complex 2", "(1+2*3-4)*(5+6*7-8)", "(((1+(2*3))-4)*((5+(6*7))-8))"},
 		{"Complex 3", "(1+2*3-4)*(5+6*7-8)+9-10*11", "(((((1+(2*3))-4)*((5+(6*7))-8))+9)-(10*11))"},
@@ -94,6 +109,142 @@ func TestExpressionFromTokens(t *testing.T) {
 	}
 }
 
+func TestExpressionEachOperationLogical(t *testing.T) {
+	src := []byte("a+1>b&&c<d*2\n")
+	tokens, _ := token.Tokenize(src, []token.Token{})
+	tokens = tokens[:len(tokens)-1]
+
+	expr, err := expression.FromTokens(tokens)
+	assert.Nil(t, err)
+	assert.Equal(t, expr.String(), "(((a+1)>b)&&(c<(d*2)))")
+	assert.True(t, expr.IsLogical())
+	assert.False(t, expr.Children[0].IsLogical())
+
+	var operations []string
+
+	err = expr.EachOperation(func(sub *expression.Expression) error {
+		operations = append(operations, sub.Token.Text())
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.DeepEqual(t, operations, []string{"&&"})
+}
+
+func TestExpressionContainsCall(t *testing.T) {
+	src := []byte("f(g(x),2)+y*3\n")
+	tokens, _ := token.Tokenize(src, []token.Token{})
+	tokens = tokens[:len(tokens)-1]
+
+	expr, err := expression.FromTokens(tokens)
+	assert.Nil(t, err)
+
+	call := expr.Children[0]
+	assert.True(t, expr.ContainsCall())
+	assert.True(t, call.ContainsCall())
+	assert.True(t, call.Children[0].ContainsCall())
+	assert.False(t, call.Children[1].ContainsCall())
+	assert.False(t, expr.Children[1].ContainsCall())
+}
+
+func TestExpressionFold(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Expression string
+		Result     string
+	}{
+		{"Number", "1", "1"},
+		{"Basic calculation", "2+3*4", "14"},
+		{"Grouping", "(2+3)*4", "20"},
+		{"Division", "7/2", "3"},
+		{"Modulo", "-7%3", "-1"},
+		{"Bitwise", "5&3|8^1", "9"},
+		{"Shift", "1<<3>>1", "4"},
+		{"Unary", "-(2+3)", "-5"},
+		{"Unary 2", "~5", "-6"},
+		{"Overflow", "9223372036854775807+1", "-9223372036854775808"},
+		{"Overflow 2", "4611686018427387904*4", "0"},
+		{"Division overflow", "(-9223372036854775807-1)/-1", "(-9223372036854775808/-1)"},
+		{"Modulo overflow", "(-9223372036854775807-1)%-1", "(-9223372036854775808%-1)"},
+		{"Partial", "a+2*3", "(a+6)"},
+		{"Partial 2", "(1+2)*a-(3-4)", "((3*a)--1)"},
+		{"Function calls", "f(1+2)+3", "(f((1+2))+3)"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			src := []byte(test.Expression + "\n")
+			tokens, _ := token.Tokenize(src, []token.Token{})
+			tokens = tokens[:len(tokens)-1]
+
+			expr, err := expression.FromTokens(tokens)
+			assert.Nil(t, err)
+			assert.Nil(t, expr.Fold())
+			assert.Equal(t, expr.String(), test.Result)
+		})
+	}
+}
+
+func TestExpressionFoldDivisionByZero(t *testing.T) {
+	src := []byte("1+2/(3-3)\n")
+	tokens, _ := token.Tokenize(src, []token.Token{})
+	tokens = tokens[:len(tokens)-1]
+
+	expr, err := expression.FromTokens(tokens)
+	assert.Nil(t, err)
+	err = expr.Fold()
+	assert.NotNil(t, err)
+	assert.Equal(t, errors.Code(err), errors.Code(errors.DivisionByZero))
+
+	// The error refers to the division operator
+	offset, hasOffset := errors.Offset(err)
+	assert.True(t, hasOffset)
+	assert.Equal(t, offset, uint16(3))
+}
+
+func TestExpressionFoldTexts(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Expression string
+		Result     string
+	}{
+		{"Text", `"a"`, "a"},
+		{"Concatenation", `"Hello " + "World"`, "Hello World"},
+		{"Concatenation 2", `"a" + "b" + "c"`, "abc"},
+		{"Grouping", `"a" + ("b" + "c")`, "abc"},
+		{"Function calls", `f("a" + "b", 1 + 2)`, "f(ab,(1+2))"},
+		{"Numbers", "1+2", "(1+2)"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			src := []byte(test.Expression + "\n")
+			tokens, _ := token.Tokenize(src, []token.Token{})
+			tokens = tokens[:len(tokens)-1]
+
+			expr, err := expression.FromTokens(tokens)
+			assert.Nil(t, err)
+			assert.Nil(t, expr.FoldTexts())
+			assert.Equal(t, expr.String(), test.Result)
+		})
+	}
+}
+
+func TestExpressionFoldTextsInvalid(t *testing.T) {
+	for _, src := range []string{`"a" + 1`, `1 + "a"`, `"a" + b`, `"a" + ("b" + c)`} {
+		tokens, _ := token.Tokenize([]byte(src+"\n"), []token.Token{})
+		tokens = tokens[:len(tokens)-1]
+
+		expr, err := expression.FromTokens(tokens)
+		assert.Nil(t, err)
+		assert.NotNil(t, expr.FoldTexts())
+	}
+}
+
 func BenchmarkExpression(b *testing.B) {
 	src := []byte("(1+2-3*4)*(5+6-7*8)\n")
 	tokens, _ := token.Tokenize(src, []token.Token{})
@@ -0,0 +1,184 @@
+package expression
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/token"
+	"github.com/akyoto/q/build/types"
+)
+
+// Fold replaces operations on number literals with their result.
+// The calculations use the same wrapping 64-bit semantics as the CPU.
+func (expr *Expression) Fold() error {
+	if expr.IsLeaf() || expr.IsFunctionCall || expr.Token.Kind != token.Operator {
+		return nil
+	}
+
+	for _, child := range expr.Children {
+		err := child.Fold()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	numbers := make([]int64, len(expr.Children))
+
+	for i, child := range expr.Children {
+		if !child.IsLeaf() || child.Token.Kind != token.Number {
+			return nil
+		}
+
+		number, err := token.ParseInt(child.Token.Text())
+
+		if err != nil {
+			return nil
+		}
+
+		numbers[i] = number
+	}
+
+	result, ok, err := calculate(expr.Token, numbers)
+
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, child := range expr.Children {
+		child.Close()
+	}
+
+	expr.Children = expr.Children[:0]
+	expr.Type = types.Int
+	expr.Token = token.Token{
+		Kind:     token.Number,
+		Position: expr.Token.Position,
+		Bytes:    strconv.AppendInt(nil, result, 10),
+	}
+
+	return nil
+}
+
+// FoldTexts replaces the concatenation of text literals via '+' with a single text literal.
+// Texts can only be concatenated at compile time, therefore adding anything else to a text literal is an error.
+func (expr *Expression) FoldTexts() error {
+	if expr.IsLeaf() {
+		return nil
+	}
+
+	for _, child := range expr.Children {
+		err := child.FoldTexts()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if expr.IsFunctionCall || expr.Token.Kind != token.Operator || expr.Token.Text() != "+" || len(expr.Children) != 2 {
+		return nil
+	}
+
+	left := expr.Children[0]
+	right := expr.Children[1]
+	isLeftText := left.IsLeaf() && left.Token.Kind == token.Text
+	isRightText := right.IsLeaf() && right.Token.Kind == token.Text
+
+	if !isLeftText && !isRightText {
+		return nil
+	}
+
+	if !isLeftText || !isRightText {
+		return errors.New(errors.InvalidConcatenation)
+	}
+
+	position := left.Token.Position
+	text := make([]byte, 0, len(left.Token.Bytes)+len(right.Token.Bytes))
+	text = append(text, left.Token.Bytes...)
+	text = append(text, right.Token.Bytes...)
+
+	for _, child := range expr.Children {
+		child.Close()
+	}
+
+	expr.Children = expr.Children[:0]
+	expr.Type = types.Text
+	expr.Token = token.Token{
+		Kind:     token.Text,
+		Position: position,
+		Bytes:    text,
+	}
+
+	return nil
+}
+
+// calculate performs the operation on constant operands.
+// It reports false if the operation can't be calculated at compile time.
+func calculate(operatorToken token.Token, numbers []int64) (int64, bool, error) {
+	operator := operatorToken.Text()
+
+	if len(numbers) == 1 {
+		switch operator {
+		case "-":
+			return -numbers[0], true, nil
+
+		case "~":
+			return ^numbers[0], true, nil
+		}
+
+		return 0, false, nil
+	}
+
+	if len(numbers) != 2 {
+		return 0, false, nil
+	}
+
+	a := numbers[0]
+	b := numbers[1]
+
+	switch operator {
+	case "+":
+		return a + b, true, nil
+
+	case "-":
+		return a - b, true, nil
+
+	case "*":
+		return a * b, true, nil
+
+	case "/", "%":
+		if b == 0 {
+			return 0, false, errors.NewAt(errors.DivisionByZero, operatorToken.Position)
+		}
+
+		// The CPU traps on the overflow, therefore the result is left to the runtime
+		if a == math.MinInt64 && b == -1 {
+			return 0, false, nil
+		}
+
+		if operator == "%" {
+			return a % b, true, nil
+		}
+
+		return a / b, true, nil
+
+	case "&":
+		return a & b, true, nil
+
+	case "|":
+		return a | b, true, nil
+
+	case "^":
+		return a ^ b, true, nil
+
+	// The CPU only uses the lowest 6 bits of the shift count
+	case "<<":
+		return a << (uint64(b) & 63), true, nil
+
+	case ">>":
+		return a >> (uint64(b) & 63), true, nil
+	}
+
+	return 0, false, nil
+}
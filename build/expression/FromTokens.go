@@ -1,6 +1,8 @@
 package expression
 
 import (
+	"bytes"
+
 	"github.com/akyoto/q/build/errors"
 	"github.com/akyoto/q/build/operators"
 	"github.com/akyoto/q/build/token"
@@ -18,23 +20,38 @@ func FromTokens(tokens []token.Token) (*Expression, error) {
 	groupLevel := 0
 	groupPosition := 0
 
+	// Array indices like a[i] are handled in the same way.
+	arrayLevel := 0
+	arrayPosition := 0
+
 	// Create a root node and use it as our current expression.
 	current := New()
 
-	// Next current is used for when we want to change the current node
-	// after we receive the next operand.
-	var nextCurrent *Expression
-
 	// Last operand is saved for when we encounter a function call.
 	// We assume that the last operand was the function name.
 	// It is also used for the function call detection itself.
 	// We set this variable back to nil when we see an operator.
 	var lastOperand *Expression
 
+	// An operator in a position where we expect an operand
+	// is a unary operator like in -x or ~x.
+	expectOperand := true
+
+	// Tokens before this position have already been consumed by a unary operator.
+	skipUntil := 0
+
 	// We iterate over all tokens and adjust the expression tree as we go.
 	for i, t := range tokens {
+		if i < skipUntil {
+			continue
+		}
+
 		switch t.Kind {
 		case token.GroupStart:
+			if arrayLevel != 0 {
+				continue
+			}
+
 			if groupLevel == 0 {
 				groupPosition = i + 1
 			}
@@ -43,6 +60,10 @@ func FromTokens(tokens []token.Token) (*Expression, error) {
 			continue
 
 		case token.GroupEnd:
+			if arrayLevel != 0 {
+				continue
+			}
+
 			groupLevel--
 
 			if groupLevel == 0 {
@@ -56,6 +77,7 @@ func FromTokens(tokens []token.Token) (*Expression, error) {
 					}
 
 					lastOperand.Children = parameters
+					expectOperand = false
 					continue
 				}
 
@@ -67,19 +89,63 @@ func FromTokens(tokens []token.Token) (*Expression, error) {
 
 				lastOperand = operand
 				current.AddChild(operand)
+				expectOperand = false
+			}
 
-				if nextCurrent != nil {
-					current = nextCurrent
-					nextCurrent = nil
-				}
+			continue
+
+		case token.ArrayStart:
+			if groupLevel != 0 {
+				continue
 			}
 
+			if arrayLevel == 0 {
+				arrayPosition = i + 1
+			}
+
+			arrayLevel++
 			continue
 
-		default:
+		case token.ArrayEnd:
 			if groupLevel != 0 {
 				continue
 			}
+
+			arrayLevel--
+
+			if arrayLevel != 0 {
+				continue
+			}
+
+			if lastOperand == nil {
+				return nil, errors.New(errors.InvalidExpression)
+			}
+
+			if arrayPosition == i {
+				return nil, errors.New(errors.MissingArrayIndex)
+			}
+
+			index, err := FromTokens(tokens[arrayPosition:i])
+
+			if err != nil {
+				return nil, err
+			}
+
+			// The array access replaces the array operand
+			access := New()
+			access.Token = tokens[arrayPosition-1]
+			parent := lastOperand.Parent
+			access.AddChild(lastOperand)
+			access.AddChild(index)
+			parent.AddChild(access)
+			lastOperand = access
+			expectOperand = false
+			continue
+
+		default:
+			if groupLevel != 0 || arrayLevel != 0 {
+				continue
+			}
 		}
 
 		switch t.Kind {
@@ -87,26 +153,53 @@ func FromTokens(tokens []token.Token) (*Expression, error) {
 			operand := FromToken(t)
 			lastOperand = operand
 			current.AddChild(operand)
-
-			if nextCurrent != nil {
-				current = nextCurrent
-				nextCurrent = nil
-			}
+			expectOperand = false
 
 		case token.Operator:
 			lastOperand = nil
 
+			if expectOperand && isUnary(t) {
+				end := operandEnd(tokens, i+1)
+
+				if end == i+1 {
+					return nil, errors.New(errors.MissingOperand)
+				}
+
+				operand, err := FromTokens(tokens[i+1 : end])
+
+				if err != nil {
+					return nil, err
+				}
+
+				unary := New()
+				unary.Token = t
+				unary.AddChild(operand)
+				current.AddChild(unary)
+				expectOperand = false
+				skipUntil = end
+				continue
+			}
+
+			expectOperand = true
+
 			if current.Token.Kind != token.Operator {
 				current.Token = t
 				continue
 			}
 
 			// Compare operator priority
-			oldOperator := current.Token.Text()
-			oldOperatorPriority := operators.All[oldOperator].Priority
 			newOperator := t.Text()
 			newOperatorPriority := operators.All[newOperator].Priority
 
+			// Walk up the tree as long as the parent operation
+			// has the same or a higher priority than the new one.
+			for current.Parent != nil && operators.All[current.Parent.Token.Text()].Priority >= newOperatorPriority {
+				current = current.Parent
+			}
+
+			oldOperator := current.Token.Text()
+			oldOperatorPriority := operators.All[oldOperator].Priority
+
 			if newOperatorPriority > oldOperatorPriority {
 				// Let's say we have the expression (1 + 2 * 3)
 				// At first, we encountered 1 + 2 and generated this tree:
@@ -131,19 +224,30 @@ func FromTokens(tokens []token.Token) (*Expression, error) {
 				newOperation.AddChild(lastChild)
 				newOperation.SetParent(current)
 
-				// The new operator becomes the current expression until we added the second operand entirely.
-				nextCurrent = current
+				// The new operator becomes the current expression.
 				current = newOperation
 				continue
 			}
 
+			// The new operation takes the place of the current expression
+			// and uses the current expression as its left operand.
+			parent := current.Parent
 			newOperation := New()
 			newOperation.Token = t
 			current.SetParent(newOperation)
+
+			if parent != nil {
+				newOperation.SetParent(parent)
+			}
+
 			current = newOperation
 		}
 	}
 
+	if arrayLevel != 0 {
+		return nil, errors.New(&errors.MissingCharacter{Character: "]"})
+	}
+
 	// Walk up the tree and return the top level node.
 	for current.Parent != nil {
 		current = current.Parent
@@ -171,6 +275,10 @@ func FromToken(t token.Token) *Expression {
 	case token.Number:
 		operand.Type = types.Int
 
+		if bytes.IndexByte(t.Bytes, '.') != -1 {
+			operand.Type = types.Float64
+		}
+
 	case token.Text:
 		operand.Type = types.Text
 	}
@@ -178,6 +286,69 @@ func FromToken(t token.Token) *Expression {
 	return operand
 }
 
+// isUnary tells you whether the operator can be used as a prefix of a single operand.
+func isUnary(t token.Token) bool {
+	symbol := t.Text()
+	return symbol == "-" || operators.All[symbol].Kind == operators.Unary
+}
+
+// operandEnd returns the position after the operand that starts at the given position.
+// The operand can be preceded by further unary operators and include
+// function calls, groups and field accesses because they bind more tightly.
+func operandEnd(tokens []token.Token, start int) int {
+	i := start
+
+	for i < len(tokens) && tokens[i].Kind == token.Operator && isUnary(tokens[i]) {
+		i++
+	}
+
+	for i < len(tokens) {
+		switch tokens[i].Kind {
+		case token.GroupStart:
+			i = groupEnd(tokens, i)
+
+		case token.Identifier, token.Number, token.Text:
+			i++
+
+			for i < len(tokens) && (tokens[i].Kind == token.GroupStart || tokens[i].Kind == token.ArrayStart) {
+				i = groupEnd(tokens, i)
+			}
+
+		default:
+			return i
+		}
+
+		if i >= len(tokens) || tokens[i].Kind != token.Operator || tokens[i].Text() != "." {
+			return i
+		}
+
+		i++
+	}
+
+	return i
+}
+
+// groupEnd returns the position after the group or array index that starts at the given position.
+func groupEnd(tokens []token.Token, start int) int {
+	groupLevel := 0
+
+	for i := start; i < len(tokens); i++ {
+		switch tokens[i].Kind {
+		case token.GroupStart, token.ArrayStart:
+			groupLevel++
+
+		case token.GroupEnd, token.ArrayEnd:
+			groupLevel--
+
+			if groupLevel == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(tokens)
+}
+
 // multiExpressionList generates an expression for an argument list.
 // Expressions must be separated by the Separator token.
 func multiExpressionList(tokens []token.Token) ([]*Expression, error) {
@@ -14,6 +14,10 @@ func (err *Error) Error() string {
 	return err.Message
 }
 
+func (err *Error) Code() string {
+	return "InvalidInstruction"
+}
+
 func (err *Error) CursorRight() bool {
 	return err.RightSideCursor
 }
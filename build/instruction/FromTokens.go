@@ -3,6 +3,7 @@ package instruction
 import (
 	"fmt"
 
+	"github.com/akyoto/q/build/operators"
 	"github.com/akyoto/q/build/token"
 )
 
@@ -15,6 +16,24 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 	blocks := []Kind{}
 
 	for i, t := range tokens {
+		// Lines inside inline assembly blocks are not parsed as statements
+		if inAssembly(blocks) {
+			switch t.Kind {
+			case token.NewLine, token.BlockEnd:
+				if start != i {
+					instruction.Kind = Assembly
+				}
+
+			case token.Comment:
+
+			case token.BlockStart:
+				return nil, &Error{"Blocks can't be used inside 'asm'", i, false}
+
+			default:
+				continue
+			}
+		}
+
 		switch t.Kind {
 		case token.NewLine:
 			if start == i {
@@ -23,11 +42,11 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 			}
 
 			switch instruction.Kind {
-			case Comment:
-				instruction.Kind = Invalid
-				start = i + 1
+			case Return, Expect, Ensure, Break, Continue, Defer, Assignment, Assembly, Invalid:
+				if inSwitch(blocks) {
+					return nil, &Error{"Expected a case block inside 'switch'", start, false}
+				}
 
-			case Return, Expect, Ensure, Assignment, Invalid:
 				instruction.Tokens = tokens[start:i]
 				instruction.Position = start
 				instructions = append(instructions, instruction)
@@ -41,7 +60,7 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 				continue
 			}
 
-			if t.Text() != "=" {
+			if operators.All[t.Text()].Kind != operators.Assignment {
 				continue
 			}
 
@@ -75,6 +94,10 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 				continue
 			}
 
+			if inSwitch(blocks) {
+				return nil, &Error{"Expected a case block inside 'switch'", start, false}
+			}
+
 			instruction.Tokens = tokens[start : i+1]
 			instruction.Position = start
 			instructions = append(instructions, instruction)
@@ -114,9 +137,13 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 				instruction.Kind = Assignment
 			case "mut":
 				instruction.Kind = Assignment
+			case "const":
+				instruction.Kind = Assignment
 			case "if":
 				instruction.Kind = IfStart
-			case "for":
+			case "else":
+				instruction.Kind = ElseStart
+			case "for", "repeat":
 				instruction.Kind = ForStart
 			case "struct":
 				instruction.Kind = StructStart
@@ -124,17 +151,45 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 				instruction.Kind = Return
 			case "loop":
 				instruction.Kind = LoopStart
+			case "while":
+				instruction.Kind = WhileStart
+			case "switch":
+				instruction.Kind = SwitchStart
 			case "expect":
 				instruction.Kind = Expect
 			case "ensure":
 				instruction.Kind = Ensure
+			case "break":
+				instruction.Kind = Break
+			case "continue":
+				instruction.Kind = Continue
+			case "defer":
+				instruction.Kind = Defer
+			case "asm":
+				instruction.Kind = AssemblyStart
 			default:
 				return nil, &Error{"Keyword not implemented", i, false}
 			}
 
 		case token.BlockStart:
+			// Every block inside a switch is a case that starts with its values or 'else'
+			if inSwitch(blocks) {
+				switch instruction.Kind {
+				case Invalid, ElseStart:
+					instruction.Kind = CaseStart
+
+				default:
+					return nil, &Error{"Expected a case block inside 'switch'", start, false}
+				}
+			}
+
+			// Braces at the start of a statement open an anonymous block
+			if instruction.Kind == Invalid && start == i {
+				instruction.Kind = BlockStart
+			}
+
 			switch instruction.Kind {
-			case IfStart, ForStart, LoopStart:
+			case IfStart, ElseStart, ForStart, LoopStart, WhileStart, SwitchStart, CaseStart, BlockStart, AssemblyStart:
 				// OK.
 
 			default:
@@ -153,19 +208,45 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 		case token.BlockEnd:
 			block := blocks[len(blocks)-1]
 
+			// The last line of an inline assembly block can end with the closing brace
+			if instruction.Kind == Assembly {
+				instruction.Tokens = tokens[start:i]
+				instruction.Position = start
+				instructions = append(instructions, instruction)
+				start = i
+			}
+
 			switch block {
 			case IfStart:
 				instruction.Kind = IfEnd
 
+			case ElseStart:
+				instruction.Kind = ElseEnd
+
 			case ForStart:
 				instruction.Kind = ForEnd
 
 			case LoopStart:
 				instruction.Kind = LoopEnd
 
+			case WhileStart:
+				instruction.Kind = WhileEnd
+
+			case SwitchStart:
+				instruction.Kind = SwitchEnd
+
+			case CaseStart:
+				instruction.Kind = CaseEnd
+
 			case StructStart:
 				instruction.Kind = StructEnd
 
+			case BlockStart:
+				instruction.Kind = BlockEnd
+
+			case AssemblyStart:
+				instruction.Kind = AssemblyEnd
+
 			default:
 				return nil, &Error{fmt.Sprintf("Not implemented: %v", block), i, false}
 			}
@@ -180,7 +261,12 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 			blocks = blocks[:len(blocks)-1]
 
 		case token.Comment:
-			instruction.Kind = Comment
+			// Comments inside an instruction can only come from block comments spanning multiple lines
+			if start != i {
+				return nil, &Error{"Comments spanning multiple lines can't be used inside an instruction", i, false}
+			}
+
+			start = i + 1
 		}
 	}
 
@@ -192,3 +278,13 @@ func FromTokens(tokens []token.Token) ([]Instruction, *Error) {
 
 	return instructions, nil
 }
+
+// inAssembly tells you whether the innermost block is an inline assembly block.
+func inAssembly(blocks []Kind) bool {
+	return len(blocks) > 0 && blocks[len(blocks)-1] == AssemblyStart
+}
+
+// inSwitch tells you whether the innermost block is a switch block.
+func inSwitch(blocks []Kind) bool {
+	return len(blocks) > 0 && blocks[len(blocks)-1] == SwitchStart
+}
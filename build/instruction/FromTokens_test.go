@@ -20,11 +20,30 @@ func TestInstructionsFromTokens(t *testing.T) {
 			{instruction.Call, nil, 13},
 			{instruction.LoopEnd, nil, 17},
 		}},
+		{[]byte("const a = 1\nb = a\n"), []instruction.Instruction{
+			{instruction.Assignment, nil, 0},
+			{instruction.Assignment, nil, 5},
+		}},
+		{[]byte("a += 1\nb %= a\n"), []instruction.Instruction{
+			{instruction.Assignment, nil, 0},
+			{instruction.Assignment, nil, 4},
+		}},
 		{[]byte("if x > 1 {\nx = 2\n}\n"), []instruction.Instruction{
 			{instruction.IfStart, nil, 0},
 			{instruction.Assignment, nil, 6},
 			{instruction.IfEnd, nil, 10},
 		}},
+		{[]byte("if x > 1 {\nx = 2\n} else if x < 1 {\nx = 3\n} else {\nx = 4\n}\n"), []instruction.Instruction{
+			{instruction.IfStart, nil, 0},
+			{instruction.Assignment, nil, 6},
+			{instruction.IfEnd, nil, 10},
+			{instruction.ElseStart, nil, 11},
+			{instruction.Assignment, nil, 18},
+			{instruction.ElseEnd, nil, 22},
+			{instruction.ElseStart, nil, 23},
+			{instruction.Assignment, nil, 26},
+			{instruction.ElseEnd, nil, 30},
+		}},
 		{[]byte("for i = 0..2 {}\n"), []instruction.Instruction{
 			{instruction.ForStart, nil, 0},
 			{instruction.ForEnd, nil, 7},
@@ -34,6 +53,54 @@ func TestInstructionsFromTokens(t *testing.T) {
 			{instruction.Call, nil, 7},
 			{instruction.ForEnd, nil, 10},
 		}},
+		{[]byte("while x < 10 {\nx = x + 1\n}\n"), []instruction.Instruction{
+			{instruction.WhileStart, nil, 0},
+			{instruction.Assignment, nil, 6},
+			{instruction.WhileEnd, nil, 12},
+		}},
+		{[]byte("loop {\nbreak\n}\n"), []instruction.Instruction{
+			{instruction.LoopStart, nil, 0},
+			{instruction.Break, nil, 3},
+			{instruction.LoopEnd, nil, 5},
+		}},
+		{[]byte("repeat 3 {\nbreak\n}\n"), []instruction.Instruction{
+			{instruction.ForStart, nil, 0},
+			{instruction.Break, nil, 4},
+			{instruction.ForEnd, nil, 6},
+		}},
+		{[]byte("for 0..2 {\ncontinue\n}\n"), []instruction.Instruction{
+			{instruction.ForStart, nil, 0},
+			{instruction.Continue, nil, 6},
+			{instruction.ForEnd, nil, 8},
+		}},
+		{[]byte("switch x {\n1, 2 {\na()\n}\nelse {\n}\n}\n"), []instruction.Instruction{
+			{instruction.SwitchStart, nil, 0},
+			{instruction.CaseStart, nil, 4},
+			{instruction.Call, nil, 9},
+			{instruction.CaseEnd, nil, 13},
+			{instruction.CaseStart, nil, 15},
+			{instruction.CaseEnd, nil, 18},
+			{instruction.SwitchEnd, nil, 20},
+		}},
+		{[]byte("{\na = 1\n}\nb()\n"), []instruction.Instruction{
+			{instruction.BlockStart, nil, 0},
+			{instruction.Assignment, nil, 2},
+			{instruction.BlockEnd, nil, 6},
+			{instruction.Call, nil, 8},
+		}},
+		{[]byte("asm {\nmov rax, 1\nsyscall\n}\nasm { inc rax }\n"), []instruction.Instruction{
+			{instruction.AssemblyStart, nil, 0},
+			{instruction.Assembly, nil, 3},
+			{instruction.Assembly, nil, 8},
+			{instruction.AssemblyEnd, nil, 10},
+			{instruction.AssemblyStart, nil, 12},
+			{instruction.Assembly, nil, 14},
+			{instruction.AssemblyEnd, nil, 16},
+		}},
+		{[]byte("defer close(f)\nwrite(f)\n"), []instruction.Instruction{
+			{instruction.Defer, nil, 0},
+			{instruction.Call, nil, 6},
+		}},
 	}
 
 	for _, pattern := range usagePatterns {
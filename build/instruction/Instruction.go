@@ -24,7 +24,8 @@ func (instr *Instruction) String() string {
 			break
 		}
 
-		if t.Kind == token.Keyword || t.Kind == token.Separator {
+		// The mnemonic in inline assembly is followed by its operands
+		if t.Kind == token.Keyword || t.Kind == token.Separator || (instr.Kind == Assembly && index == 0) {
 			builder.WriteString(t.Text())
 			builder.WriteByte(' ')
 			continue
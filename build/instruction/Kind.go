@@ -19,6 +19,12 @@ const (
 	// IfEnd represents the end of the branch.
 	IfEnd
 
+	// ElseStart represents the start of the alternative branch.
+	ElseStart
+
+	// ElseEnd represents the end of the alternative branch.
+	ElseEnd
+
 	// ForStart represents the start of the for loop.
 	ForStart
 
@@ -31,12 +37,45 @@ const (
 	// LoopEnd represents the end of the infinite loop.
 	LoopEnd
 
+	// WhileStart represents the start of the while loop.
+	WhileStart
+
+	// WhileEnd represents the end of the while loop.
+	WhileEnd
+
+	// SwitchStart represents the start of the switch block.
+	SwitchStart
+
+	// SwitchEnd represents the end of the switch block.
+	SwitchEnd
+
+	// CaseStart represents the start of a case inside a switch block.
+	CaseStart
+
+	// CaseEnd represents the end of a case inside a switch block.
+	CaseEnd
+
 	// StructStart represents the start of the struct.
 	StructStart
 
 	// StructEnd represents the end of the struct.
 	StructEnd
 
+	// BlockStart represents the start of an anonymous block.
+	BlockStart
+
+	// BlockEnd represents the end of an anonymous block.
+	BlockEnd
+
+	// AssemblyStart represents the start of an inline assembly block.
+	AssemblyStart
+
+	// AssemblyEnd represents the end of an inline assembly block.
+	AssemblyEnd
+
+	// Assembly represents a single line inside an inline assembly block.
+	Assembly
+
 	// Return represents the return statement.
 	Return
 
@@ -46,6 +85,15 @@ const (
 	// Ensure represents the ensure statement.
 	Ensure
 
+	// Break represents the break statement.
+	Break
+
+	// Continue represents the continue statement.
+	Continue
+
+	// Defer represents the defer statement.
+	Defer
+
 	// Comment represents a comment.
 	Comment
 )
@@ -65,6 +113,12 @@ func (kind Kind) String() string {
 	case IfEnd:
 		return "IfEnd"
 
+	case ElseStart:
+		return "ElseStart"
+
+	case ElseEnd:
+		return "ElseEnd"
+
 	case ForStart:
 		return "ForStart"
 
@@ -77,18 +131,60 @@ func (kind Kind) String() string {
 	case LoopEnd:
 		return "LoopEnd"
 
+	case WhileStart:
+		return "WhileStart"
+
+	case WhileEnd:
+		return "WhileEnd"
+
+	case SwitchStart:
+		return "SwitchStart"
+
+	case SwitchEnd:
+		return "SwitchEnd"
+
+	case CaseStart:
+		return "CaseStart"
+
+	case CaseEnd:
+		return "CaseEnd"
+
 	case StructStart:
 		return "StructStart"
 
 	case StructEnd:
 		return "StructEnd"
 
+	case BlockStart:
+		return "BlockStart"
+
+	case BlockEnd:
+		return "BlockEnd"
+
+	case AssemblyStart:
+		return "AssemblyStart"
+
+	case AssemblyEnd:
+		return "AssemblyEnd"
+
+	case Assembly:
+		return "Assembly"
+
 	case Expect:
 		return "Expect"
 
 	case Ensure:
 		return "Ensure"
 
+	case Break:
+		return "Break"
+
+	case Continue:
+		return "Continue"
+
+	case Defer:
+		return "Defer"
+
 	case Invalid:
 		return "Invalid"
 
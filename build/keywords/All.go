@@ -2,14 +2,24 @@ package keywords
 
 // All defines the keywords used in the language.
 var All = map[string]bool{
-	"ensure": true,
-	"expect": true,
-	"for":    true,
-	"if":     true,
-	"import": true,
-	"let":    true,
-	"loop":   true,
-	"mut":    true,
-	"return": true,
-	"struct": true,
+	"asm":      true,
+	"break":    true,
+	"const":    true,
+	"continue": true,
+	"defer":    true,
+	"else":     true,
+	"ensure":   true,
+	"expect":   true,
+	"for":      true,
+	"if":       true,
+	"import":   true,
+	"let":      true,
+	"loop":     true,
+	"mut":      true,
+	"repeat":   true,
+	"return":   true,
+	"step":     true,
+	"struct":   true,
+	"switch":   true,
+	"while":    true,
 }
@@ -0,0 +1,16 @@
+package macho
+
+// Header64Size is equal to the size of the Mach-O header in bytes.
+const Header64Size = 32
+
+// Header64 contains general information.
+type Header64 struct {
+	Magic            uint32
+	CPUType          uint32
+	CPUSubType       uint32
+	FileType         uint32
+	LoadCommandCount uint32
+	LoadCommandsSize uint32
+	Flags            uint32
+	Reserved         uint32
+}
@@ -0,0 +1,10 @@
+package macho
+
+// Protection defines the memory access rights of a segment.
+type Protection uint32
+
+const (
+	ProtectionReadable   Protection = 0x1
+	ProtectionWritable   Protection = 0x2
+	ProtectionExecutable Protection = 0x4
+)
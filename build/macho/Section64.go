@@ -0,0 +1,20 @@
+package macho
+
+// Section64Size is equal to the size of a section header in bytes.
+const Section64Size = 80
+
+// Section64 describes a section inside of a segment.
+type Section64 struct {
+	Name             [16]byte
+	SegmentName      [16]byte
+	Address          uint64
+	Size             uint64
+	Offset           uint32
+	Align            uint32
+	RelocationOffset uint32
+	RelocationCount  uint32
+	Flags            SectionFlags
+	Reserved1        uint32
+	Reserved2        uint32
+	Reserved3        uint32
+}
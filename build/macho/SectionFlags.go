@@ -0,0 +1,10 @@
+package macho
+
+// SectionFlags defines the type and attributes of a section.
+type SectionFlags uint32
+
+const (
+	SectionFlagsRegular          SectionFlags = 0x0
+	SectionFlagsSomeInstructions SectionFlags = 0x400
+	SectionFlagsPureInstructions SectionFlags = 0x80000000
+)
@@ -0,0 +1,19 @@
+package macho
+
+// Segment64Size is equal to the size of a segment load command without sections.
+const Segment64Size = 72
+
+// Segment64 maps a part of the file into memory.
+type Segment64 struct {
+	Command           uint32
+	CommandSize       uint32
+	Name              [16]byte
+	VirtualAddress    uint64
+	VirtualSize       uint64
+	FileOffset        uint64
+	FileSize          uint64
+	MaxProtection     Protection
+	InitialProtection Protection
+	SectionCount      uint32
+	Flags             uint32
+}
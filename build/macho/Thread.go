@@ -0,0 +1,16 @@
+package macho
+
+// ThreadSize is equal to the size of the thread load command in bytes.
+const ThreadSize = 184
+
+// Thread sets the initial register state of the main thread.
+type Thread struct {
+	Command     uint32
+	CommandSize uint32
+	Flavor      uint32
+	Count       uint32
+	Registers   [21]uint64
+}
+
+// ThreadRegisterRIP is the index of the instruction pointer in the thread state.
+const ThreadRegisterRIP = 16
@@ -0,0 +1,199 @@
+package macho
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/akyoto/asm"
+)
+
+const (
+	baseAddress  = 0x400000
+	pageSize     = 0x1000
+	sectionAlign = 16
+)
+
+const (
+	magic64                = 0xfeedfacf
+	cpuTypeX86_64          = 0x01000007
+	cpuSubTypeX86_64All    = 3
+	fileTypeExecute        = 2
+	flagsNoUndefined       = 0x1
+	commandSegment64       = 0x19
+	commandUnixThread      = 0x5
+	threadStateX86_64      = 4
+	threadStateX86_64Count = 42
+)
+
+// MachO64 represents a Mach-O 64-bit file.
+type MachO64 struct {
+	Header64
+	PageZero    Segment64
+	Text        Segment64
+	Sections    []Section64
+	Thread      Thread
+	CodePadding []byte
+	Code        []byte
+	DataPadding []byte
+	Data        []byte
+}
+
+// New creates a new 64-bit Mach-O binary.
+// The binary is statically linked and starts via a unix thread command
+// so that it doesn't depend on the dynamic linker.
+// The relative pointers are data references that are relative to the instruction pointer.
+func New(a *asm.Assembler, relativePointers []asm.Pointer) *MachO64 {
+	code := a.Code()
+	data := a.Data()
+	pointers := a.Pointers()
+
+	macho := &MachO64{
+		Header64: Header64{
+			Magic:            magic64,
+			CPUType:          cpuTypeX86_64,
+			CPUSubType:       cpuSubTypeX86_64All,
+			FileType:         fileTypeExecute,
+			LoadCommandCount: 3,
+			Flags:            flagsNoUndefined,
+		},
+		PageZero: Segment64{
+			Command:     commandSegment64,
+			CommandSize: Segment64Size,
+			Name:        name("__PAGEZERO"),
+			VirtualSize: baseAddress,
+		},
+		Text: Segment64{
+			Command:           commandSegment64,
+			CommandSize:       Segment64Size + 2*Section64Size,
+			Name:              name("__TEXT"),
+			VirtualAddress:    baseAddress,
+			MaxProtection:     ProtectionReadable | ProtectionExecutable,
+			InitialProtection: ProtectionReadable | ProtectionExecutable,
+			SectionCount:      2,
+		},
+		Thread: Thread{
+			Command:     commandUnixThread,
+			CommandSize: ThreadSize,
+			Flavor:      threadStateX86_64,
+			Count:       threadStateX86_64Count,
+		},
+		Code: code,
+		Data: data,
+	}
+
+	macho.LoadCommandsSize = macho.PageZero.CommandSize + macho.Text.CommandSize + macho.Thread.CommandSize
+	offset := uint64(Header64Size + macho.LoadCommandsSize)
+
+	// Code
+	padding := calculatePadding(offset, sectionAlign)
+	offset += padding
+	macho.CodePadding = bytes.Repeat([]byte{0}, int(padding))
+	codeOffset := offset
+	offset += uint64(len(code))
+
+	// Data
+	padding = calculatePadding(offset, sectionAlign)
+	offset += padding
+	macho.DataPadding = bytes.Repeat([]byte{0}, int(padding))
+	dataOffset := offset
+	offset += uint64(len(data))
+
+	// The text segment maps the whole file including the headers
+	macho.Text.FileSize = offset
+	macho.Text.VirtualSize = offset + calculatePadding(offset, pageSize)
+
+	macho.Sections = []Section64{
+		{
+			Name:        name("__text"),
+			SegmentName: name("__TEXT"),
+			Address:     baseAddress + codeOffset,
+			Size:        uint64(len(code)),
+			Offset:      uint32(codeOffset),
+			Align:       4,
+			Flags:       SectionFlagsPureInstructions | SectionFlagsSomeInstructions,
+		},
+		{
+			Name:        name("__const"),
+			SegmentName: name("__TEXT"),
+			Address:     baseAddress + dataOffset,
+			Size:        uint64(len(data)),
+			Offset:      uint32(dataOffset),
+			Align:       4,
+			Flags:       SectionFlagsRegular,
+		},
+	}
+
+	// Entry point
+	macho.Thread.Registers[ThreadRegisterRIP] = baseAddress + codeOffset
+
+	// Add section offset to all string addresses
+	for _, pointer := range pointers {
+		oldAddressSlice := code[pointer.Position : pointer.Position+4]
+		newAddress := uint32(baseAddress) + uint32(dataOffset) + pointer.Address
+		binary.LittleEndian.PutUint32(oldAddressSlice, newAddress)
+	}
+
+	// Replace the data addresses by their distance to the end of the instruction
+	for _, pointer := range relativePointers {
+		displacementSlice := code[pointer.Position : pointer.Position+4]
+		displacement := uint32(dataOffset) + pointer.Address - (uint32(codeOffset) + pointer.Position + 4)
+		binary.LittleEndian.PutUint32(displacementSlice, displacement)
+	}
+
+	return macho
+}
+
+// WriteToFile writes the Mach-O binary to a file.
+func (macho *MachO64) WriteToFile(fileName string) error {
+	file, err := os.Create(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = macho.WriteTo(file)
+	closeErr := file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+// WriteTo writes the Mach-O binary to the writer.
+func (macho *MachO64) WriteTo(writer io.Writer) (int64, error) {
+	buffer := bytes.Buffer{}
+	macho.writeTo(&buffer)
+	return buffer.WriteTo(writer)
+}
+
+//nolint:errcheck
+func (macho *MachO64) writeTo(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, &macho.Header64)
+	binary.Write(buffer, binary.LittleEndian, &macho.PageZero)
+	binary.Write(buffer, binary.LittleEndian, &macho.Text)
+
+	for _, section := range macho.Sections {
+		binary.Write(buffer, binary.LittleEndian, &section)
+	}
+
+	binary.Write(buffer, binary.LittleEndian, &macho.Thread)
+	buffer.Write(macho.CodePadding)
+	buffer.Write(macho.Code)
+	buffer.Write(macho.DataPadding)
+	buffer.Write(macho.Data)
+}
+
+// name converts a segment or section name to its fixed size representation.
+func name(text string) [16]byte {
+	var result [16]byte
+	copy(result[:], text)
+	return result
+}
+
+func calculatePadding(n uint64, align uint64) uint64 {
+	return (align - (n % align)) % align
+}
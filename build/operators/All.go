@@ -12,6 +12,7 @@ var All = map[string]*Operator{
 	"-=":  {"-=", 2, Assignment, true},
 	"*=":  {"*=", 2, Assignment, true},
 	"/=":  {"/=", 2, Assignment, true},
+	"%=":  {"%=", 2, Assignment, true},
 	">>=": {">>=", 2, Assignment, true},
 	"<<=": {"<<=", 2, Assignment, true},
 
@@ -34,14 +35,26 @@ var All = map[string]*Operator{
 	"<": {"<", 7, Comparison, true},
 	">": {">", 7, Comparison, true},
 
+	// Bitwise operations
+	"|": {"|", 8, Default, false},
+	"^": {"^", 9, Default, false},
+	"&": {"&", 10, Default, false},
+
 	// Arithmetic operations
-	"+": {"+", 8, Default, false},
-	"-": {"-", 8, Default, false},
+	"+": {"+", 11, Default, false},
+	"-": {"-", 11, Default, true},
+
+	"*": {"*", 12, Default, false},
+	"/": {"/", 12, Default, true},
+	"%": {"%", 12, Default, true},
+
+	// Shift operations
+	"<<": {"<<", 12, Default, true},
+	">>": {">>", 12, Default, true},
 
-	"*": {"*", 9, Default, false},
-	"/": {"/", 9, Default, true},
-	"%": {"%", 9, Default, true},
+	// Unary operations
+	"~": {"~", 13, Unary, true},
 
 	// Package and field access
-	".": {".", 10, Default, true},
+	".": {".", 14, Default, true},
 }
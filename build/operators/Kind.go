@@ -12,6 +12,9 @@ const (
 
 	// Comparison compares two values.
 	Comparison
+
+	// Unary operators take a single operand.
+	Unary
 )
 
 // String returns the text representation.
@@ -23,6 +26,9 @@ func (kind Kind) String() string {
 	case Comparison:
 		return "Comparison"
 
+	case Unary:
+		return "Unary"
+
 	case Default:
 		return "Default"
 
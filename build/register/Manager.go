@@ -7,6 +7,8 @@ type Manager struct {
 	Call        List
 	Syscall     List
 	ReturnValue List
+	Float       List
+	Stack       *Register
 }
 
 // NewManager creates a new register manager.
@@ -29,6 +31,23 @@ func NewManager() *Manager {
 		{ID: 12, Name: "r13"},
 		{ID: 13, Name: "r14"},
 		{ID: 14, Name: "r15"},
+		{ID: 15, Name: "xmm0"},
+		{ID: 16, Name: "xmm1"},
+		{ID: 17, Name: "xmm2"},
+		{ID: 18, Name: "xmm3"},
+		{ID: 19, Name: "xmm4"},
+		{ID: 20, Name: "xmm5"},
+		{ID: 21, Name: "xmm6"},
+		{ID: 22, Name: "xmm7"},
+		{ID: 23, Name: "xmm8"},
+		{ID: 24, Name: "xmm9"},
+		{ID: 25, Name: "xmm10"},
+		{ID: 26, Name: "xmm11"},
+		{ID: 27, Name: "xmm12"},
+		{ID: 28, Name: "xmm13"},
+		{ID: 29, Name: "xmm14"},
+		{ID: 30, Name: "xmm15"},
+		{ID: 31, Name: "rsp"},
 	}
 
 	// To simplify the lists below,
@@ -48,6 +67,7 @@ func NewManager() *Manager {
 	r13 := &registers[12]
 	r14 := &registers[13]
 	r15 := &registers[14]
+	rsp := &registers[31]
 
 	// Register configuration
 	manager := &Manager{
@@ -98,8 +118,18 @@ func NewManager() *Manager {
 			rcx,
 			r11,
 		},
+		Stack: rsp,
 	}
 
+	// Floating-point registers are only used for calculations,
+	// values are stored in general purpose registers.
+	for i := 15; i <= 30; i++ {
+		manager.Float = append(manager.Float, &registers[i])
+	}
+
+	manager.All = append(manager.All, manager.Float...)
+	manager.All = append(manager.All, rsp)
+
 	return manager
 }
 
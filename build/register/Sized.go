@@ -0,0 +1,68 @@
+package register
+
+// subRegisters maps the 64-bit register names to the names
+// of their lower 32, 16 and 8 bits.
+var subRegisters = map[string][3]string{
+	"rax": {"eax", "ax", "al"},
+	"rcx": {"ecx", "cx", "cl"},
+	"rdx": {"edx", "dx", "dl"},
+	"rbx": {"ebx", "bx", "bl"},
+	"rsp": {"esp", "sp", "spl"},
+	"rbp": {"ebp", "bp", "bpl"},
+	"rsi": {"esi", "si", "sil"},
+	"rdi": {"edi", "di", "dil"},
+	"r8":  {"r8d", "r8w", "r8b"},
+	"r9":  {"r9d", "r9w", "r9b"},
+	"r10": {"r10d", "r10w", "r10b"},
+	"r11": {"r11d", "r11w", "r11b"},
+	"r12": {"r12d", "r12w", "r12b"},
+	"r13": {"r13d", "r13w", "r13b"},
+	"r14": {"r14d", "r14w", "r14b"},
+	"r15": {"r15d", "r15w", "r15b"},
+}
+
+// Sized returns the name of the lower part of the register with the given number of bytes,
+// e.g. "eax" for 4 bytes and "al" for 1 byte of "rax".
+// Registers without smaller parts and other byte counts return the full name.
+func (register *Register) Sized(byteCount byte) string {
+	names, exists := subRegisters[register.Name]
+
+	if !exists {
+		return register.Name
+	}
+
+	switch byteCount {
+	case 4:
+		return names[0]
+
+	case 2:
+		return names[1]
+
+	case 1:
+		return names[2]
+	}
+
+	return register.Name
+}
+
+// ByteCount returns the size of the register part with the given name and the name of the full register.
+// Names of full registers have a size of 8 bytes.
+func ByteCount(name string) (string, byte) {
+	for full, names := range subRegisters {
+		switch name {
+		case full:
+			return full, 8
+
+		case names[0]:
+			return full, 4
+
+		case names[1]:
+			return full, 2
+
+		case names[2]:
+			return full, 1
+		}
+	}
+
+	return name, 8
+}
@@ -0,0 +1,103 @@
+package token
+
+// IsCharacterStart tells you whether the code starts with a character literal.
+func IsCharacterStart(code []byte) bool {
+	return len(code) > 0 && code[0] == '\''
+}
+
+// CharacterPrefix returns the character literal at the start of the code
+// up to the closing quote or the end of the line.
+func CharacterPrefix(code []byte) []byte {
+	end := 1
+
+	for end < len(code) && code[end] != '\n' {
+		if code[end] == '\\' {
+			end += 2
+			continue
+		}
+
+		end++
+
+		if code[end-1] == '\'' {
+			break
+		}
+	}
+
+	if end > len(code) {
+		end = len(code)
+	}
+
+	return code[:end]
+}
+
+// InvalidEscapeSequence returns the first unknown escape sequence
+// in the text or character literal at the start of the code.
+// It returns nil if all escape sequences are valid.
+func InvalidEscapeSequence(code []byte) []byte {
+	if len(code) == 0 || (code[0] != '"' && code[0] != '\'') {
+		return nil
+	}
+
+	for i := 1; i+1 < len(code) && code[i] != code[0] && code[i] != '\n'; i++ {
+		if code[i] != '\\' {
+			continue
+		}
+
+		_, isValid := escapeSequence(code[i+1])
+
+		if !isValid {
+			return code[i : i+2]
+		}
+
+		i++
+	}
+
+	return nil
+}
+
+// characterLiteral returns the value of the character literal at the start of the buffer
+// and the number of bytes including the quotes. The length is zero if the literal is invalid.
+func characterLiteral(buffer []byte) (byte, uint16) {
+	if len(buffer) < 3 {
+		return 0, 0
+	}
+
+	if buffer[1] == '\\' {
+		value, isValid := escapeSequence(buffer[2])
+
+		if !isValid || len(buffer) < 4 || buffer[3] != '\'' {
+			return 0, 0
+		}
+
+		return value, 4
+	}
+
+	if buffer[1] == '\'' || buffer[1] == '\n' || buffer[2] != '\'' {
+		return 0, 0
+	}
+
+	return buffer[1], 3
+}
+
+// escapeSequence returns the character that the escape sequence
+// consisting of a backslash and the given character stands for.
+func escapeSequence(c byte) (byte, bool) {
+	switch c {
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	case '\\':
+		return '\\', true
+	case '"':
+		return '"', true
+	case '\'':
+		return '\'', true
+	case '0':
+		return '\000', true
+	}
+
+	return 0, false
+}
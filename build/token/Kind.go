@@ -54,6 +54,9 @@ const (
 
 	// ArrayEnd represents ']'.
 	ArrayEnd
+
+	// Annotation represents a name starting with '@'.
+	Annotation
 )
 
 // String returns the text representation.
@@ -107,6 +110,9 @@ func (kind Kind) String() string {
 	case ArrayEnd:
 		return "ArrayEnd"
 
+	case Annotation:
+		return "Annotation"
+
 	case Invalid:
 		return "Invalid"
 
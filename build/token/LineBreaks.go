@@ -0,0 +1,25 @@
+package token
+
+import "bytes"
+
+// LineBreaks returns the number of line breaks in the token
+// and the position of the last one, which is -1 if there are none.
+// Besides new lines, only comments spanning multiple lines contain line breaks.
+func (t Token) LineBreaks() (int, int) {
+	switch t.Kind {
+	case NewLine:
+		return 1, int(t.Position)
+
+	case Comment:
+		last := bytes.LastIndexByte(t.Bytes, '\n')
+
+		if last == -1 {
+			return 0, -1
+		}
+
+		return bytes.Count(t.Bytes, newLineBytes), int(t.Position) + last
+
+	default:
+		return 0, -1
+	}
+}
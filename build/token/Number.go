@@ -0,0 +1,71 @@
+package token
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseInt converts the text of a number token to an integer.
+// Underscores between digits are ignored.
+// Hexadecimal (0x), octal (0o) and binary (0b) literals may
+// describe any 64-bit pattern and wrap around to negative numbers.
+func ParseInt(text string) (int64, error) {
+	text = strings.ReplaceAll(text, "_", "")
+	digits := strings.TrimPrefix(text, "-")
+
+	if len(digits) < 2 || digits[0] != '0' || !isBasePrefix(digits[1]) {
+		return strconv.ParseInt(text, 10, 64)
+	}
+
+	number, err := strconv.ParseUint(digits, 0, 64)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(digits) != len(text) {
+		return -int64(number), nil
+	}
+
+	return int64(number), nil
+}
+
+// IsNumberStart reports whether the code starts with something
+// that is meant to be a number literal.
+func IsNumberStart(code []byte) bool {
+	if len(code) > 0 && code[0] == '-' {
+		code = code[1:]
+	}
+
+	if len(code) == 0 {
+		return false
+	}
+
+	return (code[0] >= '0' && code[0] <= '9') || looksLikeNumber(identifierPrefix(code))
+}
+
+// NumberPrefix returns the number literal at the start of the code.
+func NumberPrefix(code []byte) []byte {
+	end := 0
+
+	if len(code) > 0 && code[0] == '-' {
+		end++
+	}
+
+	for end < len(code) && (isIdentifierCharacter(code[end]) || code[end] == '.') {
+		end++
+	}
+
+	return code[:end]
+}
+
+// identifierPrefix returns the identifier characters at the start of the code.
+func identifierPrefix(code []byte) []byte {
+	end := 0
+
+	for end < len(code) && isIdentifierCharacter(code[end]) {
+		end++
+	}
+
+	return code[:end]
+}
@@ -0,0 +1,37 @@
+package token_test
+
+import (
+	"testing"
+
+	"github.com/akyoto/assert"
+	"github.com/akyoto/q/build/token"
+)
+
+func TestParseInt(t *testing.T) {
+	numbers := []struct {
+		Text     string
+		Expected int64
+	}{
+		{"0", 0},
+		{"42", 42},
+		{"-42", -42},
+		{"0xFF", 255},
+		{"0xDEADBEEF", 3735928559},
+		{"-0x10", -16},
+		{"0xFFFFFFFFFFFFFFFF", -1},
+		{"0b1010", 10},
+		{"0o17", 15},
+		{"017", 17},
+		{"1_000_000", 1000000},
+		{"0b1111_0000", 240},
+	}
+
+	for _, number := range numbers {
+		value, err := token.ParseInt(number.Text)
+		assert.Nil(t, err)
+		assert.Equal(t, value, number.Expected)
+	}
+
+	_, err := token.ParseInt("0x10000000000000000")
+	assert.NotNil(t, err)
+}
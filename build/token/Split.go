@@ -0,0 +1,16 @@
+package token
+
+// Split returns the token lists between the tokens of the given kind.
+func Split(tokens []Token, kind Kind) [][]Token {
+	var lists [][]Token
+	start := 0
+
+	for i, token := range tokens {
+		if token.Kind == kind {
+			lists = append(lists, tokens[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(lists, tokens[start:])
+}
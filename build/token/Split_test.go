@@ -0,0 +1,21 @@
+package token_test
+
+import (
+	"testing"
+
+	"github.com/akyoto/assert"
+	"github.com/akyoto/q/build/token"
+)
+
+func TestSplit(t *testing.T) {
+	tokens, _ := token.Tokenize([]byte("1, -2, x\n"), nil)
+	tokens = tokens[:len(tokens)-1]
+	lists := token.Split(tokens, token.Separator)
+	assert.Equal(t, len(lists), 3)
+	assert.Equal(t, token.List(lists[0]).String(), "1")
+	assert.Equal(t, token.List(lists[1]).String(), "-2")
+	assert.Equal(t, token.List(lists[2]).String(), "x")
+
+	single := token.Split(tokens[:1], token.Separator)
+	assert.Equal(t, len(single), 1)
+}
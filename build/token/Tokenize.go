@@ -2,6 +2,7 @@ package token
 
 import (
 	"bytes"
+	"strconv"
 
 	"github.com/akyoto/q/build/keywords"
 	"github.com/akyoto/q/build/operators"
@@ -18,6 +19,7 @@ var (
 	arrayEndBytes   = []byte{']'}
 	separatorBytes  = []byte{','}
 	accessorBytes   = []byte{'.'}
+	notBytes        = []byte{'~'}
 	rangeBytes      = []byte{'.', '.'}
 	questionBytes   = []byte{'?'}
 	newLineBytes    = []byte{'\n'}
@@ -30,6 +32,7 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 		c              byte
 		processedBytes uint16
 		lastTokenKind  Kind
+		groups         int
 		token          = Token{Invalid, 0, nil}
 	)
 
@@ -58,6 +61,11 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 
 			token = Token{Identifier, processedBytes, buffer[processedBytes : i+1]}
 
+			// Names like '_100' would be confused with numbers
+			if looksLikeNumber(token.Bytes) {
+				return tokens, processedBytes
+			}
+
 			if keywords.All[string(token.Bytes)] {
 				token.Kind = Keyword
 			}
@@ -65,6 +73,49 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 		// Numbers
 		case (c >= '0' && c <= '9') || (c == '-' && lastTokenKind != Number && lastTokenKind != Identifier && lastTokenKind != GroupEnd && lastTokenKind != ArrayEnd && buffer[i+1] >= '0' && buffer[i+1] <= '9'):
 			processedBytes = i
+			decimalPoint := false
+			digitStart := i
+
+			if c == '-' {
+				digitStart++
+			}
+
+			// Hexadecimal, octal and binary literals
+			if buffer[digitStart] == '0' && digitStart+1 < uint16(len(buffer)) && isBasePrefix(buffer[digitStart+1]) {
+				prefix := buffer[digitStart+1]
+				i = digitStart + 1
+
+				for {
+					i++
+
+					if i >= uint16(len(buffer)) {
+						return tokens, processedBytes
+					}
+
+					c = buffer[i]
+
+					if c == '_' {
+						if !isDigitSeparator(buffer, i, prefix) {
+							return tokens, processedBytes
+						}
+
+						continue
+					}
+
+					if !isDigitInBase(c, prefix) {
+						break
+					}
+				}
+
+				// The prefix must be followed by at least one valid digit
+				if i == digitStart+2 || isIdentifierCharacter(c) {
+					return tokens, processedBytes
+				}
+
+				i--
+				token = Token{Number, processedBytes, buffer[processedBytes : i+1]}
+				break
+			}
 
 			for {
 				i++
@@ -75,6 +126,20 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 
 				c = buffer[i]
 
+				// A single decimal point followed by a digit makes it a floating-point number
+				if c == '.' && !decimalPoint && i+1 < uint16(len(buffer)) && buffer[i+1] >= '0' && buffer[i+1] <= '9' {
+					decimalPoint = true
+					continue
+				}
+
+				if c == '_' {
+					if !isDigitSeparator(buffer, i, '0') {
+						return tokens, processedBytes
+					}
+
+					continue
+				}
+
 				if c < '0' || c > '9' {
 					i--
 					break
@@ -101,11 +166,81 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 				}
 			}
 
+			// Comments behind other tokens on the same line or inside brackets are removed
+			// so that their contents can't become a part of the instruction.
+			if !startsLine(lastTokenKind, groups) {
+				processedBytes = i + 1
+				break
+			}
+
 			trimmed := bytes.TrimSpace(buffer[processedBytes+1 : i+1])
 			token = Token{Comment, processedBytes, trimmed}
 
+		// Annotations
+		case c == '@':
+			processedBytes = i
+
+			for {
+				i++
+
+				if i >= uint16(len(buffer)) {
+					return tokens, processedBytes
+				}
+
+				c = buffer[i]
+
+				if !isIdentifierCharacter(c) {
+					i--
+					break
+				}
+			}
+
+			// The '@' must be followed by a name
+			if i == processedBytes {
+				return tokens, processedBytes
+			}
+
+			token = Token{Annotation, processedBytes, buffer[processedBytes : i+1]}
+
+		// Block comments
+		case c == '/' && i+1 < uint16(len(buffer)) && buffer[i+1] == '*':
+			processedBytes = i
+			nesting := 0
+
+			for {
+				if i+1 >= uint16(len(buffer)) {
+					return tokens, processedBytes
+				}
+
+				if buffer[i] == '/' && buffer[i+1] == '*' {
+					nesting++
+					i += 2
+				} else if buffer[i] == '*' && buffer[i+1] == '/' {
+					nesting--
+					i += 2
+				} else {
+					i++
+				}
+
+				if nesting == 0 {
+					i--
+					break
+				}
+			}
+
+			comment := buffer[processedBytes : i+1]
+
+			// Comments spanning multiple lines are always kept
+			// because their line breaks are needed for the line numbers.
+			if !startsLine(lastTokenKind, groups) && bytes.IndexByte(comment, '\n') == -1 {
+				processedBytes = i + 1
+				break
+			}
+
+			token = Token{Comment, processedBytes, comment}
+
 		// Operators
-		case c == '=' || c == ':' || c == '+' || c == '-' || c == '*' || c == '/' || c == '<' || c == '>' || c == '!':
+		case c == '=' || c == ':' || c == '+' || c == '-' || c == '*' || c == '/' || c == '<' || c == '>' || c == '!' || c == '%' || c == '&' || c == '|' || c == '^':
 			processedBytes = i
 
 			for {
@@ -117,12 +252,18 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 
 				c = buffer[i]
 
-				if !(c == '=' || c == ':' || c == '+' || c == '-' || c == '*' || c == '/' || c == '<' || c == '>' || c == '!') {
+				if !(c == '=' || c == ':' || c == '+' || c == '-' || c == '*' || c == '/' || c == '<' || c == '>' || c == '!' || c == '%' || c == '&' || c == '|' || c == '^') {
 					i--
 					break
 				}
 			}
 
+			// Unary operators can directly follow a binary operator like in 'x*-1',
+			// therefore the longest sequence that is a known operator is used.
+			for i > processedBytes && operators.All[string(buffer[processedBytes:i+1])] == nil {
+				i--
+			}
+
 			token = Token{Operator, processedBytes, buffer[processedBytes : i+1]}
 
 			if operators.All[string(token.Bytes)] == nil {
@@ -145,19 +286,13 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 				c = buffer[i]
 
 				if escape {
-					switch c {
-					case 'n':
-						text = append(text, '\n')
-					case 'r':
-						text = append(text, '\r')
-					case '\\':
-						text = append(text, '\\')
-					case '"':
-						text = append(text, '"')
-					case '0':
-						text = append(text, '\000')
+					escaped, isValid := escapeSequence(c)
+
+					if !isValid {
+						return tokens, processedBytes
 					}
 
+					text = append(text, escaped)
 					escape = false
 					continue
 				}
@@ -176,6 +311,18 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 
 			token = Token{Text, processedBytes + 1, text}
 
+		// Characters
+		case c == '\'':
+			processedBytes = i
+			value, length := characterLiteral(buffer[i:])
+
+			if length == 0 {
+				return tokens, processedBytes
+			}
+
+			i += length - 1
+			token = Token{Number, processedBytes, strconv.AppendInt(nil, int64(value), 10)}
+
 		// Parentheses start
 		case c == '(':
 			token = Token{GroupStart, i, groupStartBytes}
@@ -213,6 +360,10 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 				token = Token{Operator, i, accessorBytes}
 			}
 
+		// Bitwise NOT
+		case c == '~':
+			token = Token{Operator, i, notBytes}
+
 		// Question
 		case c == '?':
 			token = Token{Question, i, questionBytes}
@@ -224,6 +375,13 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 
 		// Handle token
 		if token.Kind != Invalid {
+			switch token.Kind {
+			case GroupStart, ArrayStart:
+				groups++
+			case GroupEnd, ArrayEnd:
+				groups--
+			}
+
 			tokens = append(tokens, token)
 			processedBytes = i + 1
 			lastTokenKind = token.Kind
@@ -235,3 +393,61 @@ func Tokenize(buffer []byte, tokens []Token) ([]Token, uint16) {
 
 	return tokens, processedBytes
 }
+
+// startsLine reports whether a token following the given kind
+// is the first one on its line and outside of any brackets.
+func startsLine(lastTokenKind Kind, groups int) bool {
+	return (lastTokenKind == Invalid || lastTokenKind == NewLine) && groups <= 0
+}
+
+// isDigitSeparator reports whether the underscore at the given index
+// is surrounded by digits of the number base given by the prefix.
+func isDigitSeparator(buffer []byte, i uint16, prefix byte) bool {
+	return i+1 < uint16(len(buffer)) && isDigitInBase(buffer[i-1], prefix) && isDigitInBase(buffer[i+1], prefix)
+}
+
+// looksLikeNumber reports whether the identifier consists of
+// a leading underscore followed by digits and underscores only.
+func looksLikeNumber(identifier []byte) bool {
+	if len(identifier) < 2 || identifier[0] != '_' {
+		return false
+	}
+
+	for _, c := range identifier[1:] {
+		if (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isBasePrefix reports whether the character after a leading zero
+// starts a hexadecimal, octal or binary literal.
+func isBasePrefix(c byte) bool {
+	return c == 'x' || c == 'o' || c == 'b'
+}
+
+// isDigitInBase reports whether the character is a valid digit
+// for the number base given by the prefix.
+// Any other prefix stands for decimal numbers.
+func isDigitInBase(c byte, prefix byte) bool {
+	switch prefix {
+	case 'x':
+		return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+
+	case 'o':
+		return c >= '0' && c <= '7'
+
+	case 'b':
+		return c == '0' || c == '1'
+
+	default:
+		return c >= '0' && c <= '9'
+	}
+}
+
+// isIdentifierCharacter reports whether the character can be part of an identifier.
+func isIdentifierCharacter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_'
+}
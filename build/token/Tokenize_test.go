@@ -47,10 +47,98 @@ func TestTokenize(t *testing.T) {
 			{token.BlockEnd, 29, []byte{'}'}},
 			{token.NewLine, 30, []byte{'\n'}},
 		}},
+		{[]byte("x = 3.14 * -0.5\n"), []token.Token{
+			{token.Identifier, 0, []byte("x")},
+			{token.Operator, 2, []byte("=")},
+			{token.Number, 4, []byte("3.14")},
+			{token.Operator, 9, []byte("*")},
+			{token.Number, 11, []byte("-0.5")},
+			{token.NewLine, 15, []byte{'\n'}},
+		}},
+		{[]byte("x*-1 + a+-b\n"), []token.Token{
+			{token.Identifier, 0, []byte("x")},
+			{token.Operator, 1, []byte("*")},
+			{token.Number, 2, []byte("-1")},
+			{token.Operator, 5, []byte("+")},
+			{token.Identifier, 7, []byte("a")},
+			{token.Operator, 8, []byte("+")},
+			{token.Operator, 9, []byte("-")},
+			{token.Identifier, 10, []byte("b")},
+			{token.NewLine, 11, []byte{'\n'}},
+		}},
+		{[]byte("x = 0xFF + 0b1010 - 0o17 * -0x1a\n"), []token.Token{
+			{token.Identifier, 0, []byte("x")},
+			{token.Operator, 2, []byte("=")},
+			{token.Number, 4, []byte("0xFF")},
+			{token.Operator, 9, []byte("+")},
+			{token.Number, 11, []byte("0b1010")},
+			{token.Operator, 18, []byte("-")},
+			{token.Number, 20, []byte("0o17")},
+			{token.Operator, 25, []byte("*")},
+			{token.Number, 27, []byte("-0x1a")},
+			{token.NewLine, 32, []byte{'\n'}},
+		}},
+		{[]byte("x = 1_000_000 + 0xFF_FF\n"), []token.Token{
+			{token.Identifier, 0, []byte("x")},
+			{token.Operator, 2, []byte("=")},
+			{token.Number, 4, []byte("1_000_000")},
+			{token.Operator, 14, []byte("+")},
+			{token.Number, 16, []byte("0xFF_FF")},
+			{token.NewLine, 23, []byte{'\n'}},
+		}},
 		{[]byte("# A comment.\n"), []token.Token{
 			{token.Comment, 0, []byte("A comment.")},
 			{token.NewLine, 12, []byte{'\n'}},
 		}},
+		{[]byte("# Unbalanced ( [ \" in a comment.\n"), []token.Token{
+			{token.Comment, 0, []byte("Unbalanced ( [ \" in a comment.")},
+			{token.NewLine, 32, []byte{'\n'}},
+		}},
+		{[]byte("f(x) # ) comment\n"), []token.Token{
+			{token.Identifier, 0, []byte("f")},
+			{token.GroupStart, 1, []byte{'('}},
+			{token.Identifier, 2, []byte("x")},
+			{token.GroupEnd, 3, []byte{')'}},
+			{token.NewLine, 16, []byte{'\n'}},
+		}},
+		{[]byte("f(# ( comment\nx)\n"), []token.Token{
+			{token.Identifier, 0, []byte("f")},
+			{token.GroupStart, 1, []byte{'('}},
+			{token.NewLine, 13, []byte{'\n'}},
+			{token.Identifier, 14, []byte("x")},
+			{token.GroupEnd, 15, []byte{')'}},
+			{token.NewLine, 16, []byte{'\n'}},
+		}},
+		{[]byte("/* A /* nested */ comment. */\n"), []token.Token{
+			{token.Comment, 0, []byte("/* A /* nested */ comment. */")},
+			{token.NewLine, 29, []byte{'\n'}},
+		}},
+		{[]byte("f(/* ) */ x) /* ( */\n"), []token.Token{
+			{token.Identifier, 0, []byte("f")},
+			{token.GroupStart, 1, []byte{'('}},
+			{token.Identifier, 10, []byte("x")},
+			{token.GroupEnd, 11, []byte{')'}},
+			{token.NewLine, 20, []byte{'\n'}},
+		}},
+		{[]byte("x = 'A' + '\\n' - '\\''\n"), []token.Token{
+			{token.Identifier, 0, []byte("x")},
+			{token.Operator, 2, []byte("=")},
+			{token.Number, 4, []byte("65")},
+			{token.Operator, 8, []byte("+")},
+			{token.Number, 10, []byte("10")},
+			{token.Operator, 15, []byte("-")},
+			{token.Number, 17, []byte("39")},
+			{token.NewLine, 21, []byte{'\n'}},
+		}},
+		{[]byte("\"a\\tb\\\\c\\\"d\\n\"\n"), []token.Token{
+			{token.Text, 1, []byte("a\tb\\c\"d\n")},
+			{token.NewLine, 14, []byte{'\n'}},
+		}},
+		{[]byte("x /* A\ncomment. */\n"), []token.Token{
+			{token.Identifier, 0, []byte("x")},
+			{token.Comment, 2, []byte("/* A\ncomment. */")},
+			{token.NewLine, 18, []byte{'\n'}},
+		}},
 	}
 
 	for _, pattern := range usagePatterns {
@@ -58,6 +146,7 @@ func TestTokenize(t *testing.T) {
 		processed := uint16(0)
 		tokens, processed = token.Tokenize(pattern.Source, tokens)
 		assert.Equal(t, processed, uint16(len(pattern.Source)))
+		assert.Equal(t, len(tokens), len(pattern.Expected))
 
 		for index := range tokens {
 			assert.Equal(t, tokens[index].Kind, pattern.Expected[index].Kind)
@@ -68,3 +157,63 @@ func TestTokenize(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenizeInvalidNumbers(t *testing.T) {
+	sources := []string{
+		"x = 0x\n",
+		"x = 0b102\n",
+		"x = 0o8\n",
+		"x = 0xFG\n",
+		"x = 1__000\n",
+		"x = 1000_\n",
+		"x = _100\n",
+		"x = 0x_FF\n",
+	}
+
+	for _, source := range sources {
+		tokens, processed := token.Tokenize([]byte(source), nil)
+		assert.Equal(t, processed, uint16(4))
+		assert.Equal(t, len(tokens), 2)
+	}
+}
+
+func TestTokenizeInvalidCharacters(t *testing.T) {
+	sources := []string{
+		"x = ''\n",
+		"x = 'ab'\n",
+		"x = 'a\n",
+		"x = '\\q'\n",
+		"x = '''\n",
+	}
+
+	for _, source := range sources {
+		tokens, processed := token.Tokenize([]byte(source), nil)
+		assert.Equal(t, processed, uint16(4))
+		assert.Equal(t, len(tokens), 2)
+		assert.True(t, token.IsCharacterStart([]byte(source[processed:])))
+	}
+
+	assert.DeepEqual(t, token.CharacterPrefix([]byte("'ab' + 1\n")), []byte("'ab'"))
+	assert.DeepEqual(t, token.CharacterPrefix([]byte("'\\'' + 1\n")), []byte("'\\''"))
+	assert.DeepEqual(t, token.CharacterPrefix([]byte("'abc\n")), []byte("'abc"))
+}
+
+func TestTokenizeInvalidEscapeSequences(t *testing.T) {
+	sources := []string{
+		"x = \"a\\qb\"\n",
+		"x = \"\\\\\\x\"\n",
+		"x = '\\q'\n",
+	}
+
+	for _, source := range sources {
+		tokens, processed := token.Tokenize([]byte(source), nil)
+		assert.Equal(t, processed, uint16(4))
+		assert.Equal(t, len(tokens), 2)
+	}
+
+	assert.DeepEqual(t, token.InvalidEscapeSequence([]byte("\"a\\qb\"\n")), []byte("\\q"))
+	assert.DeepEqual(t, token.InvalidEscapeSequence([]byte("\"\\\\\\x\"\n")), []byte("\\x"))
+	assert.DeepEqual(t, token.InvalidEscapeSequence([]byte("'\\q'\n")), []byte("\\q"))
+	assert.Nil(t, token.InvalidEscapeSequence([]byte("\"a\\tb\" + \"\\q\"\n")))
+	assert.Nil(t, token.InvalidEscapeSequence([]byte("x\n")))
+}
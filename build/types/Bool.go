@@ -0,0 +1,3 @@
+package types
+
+var Bool = &Type{Name: "Bool", Size: 1}
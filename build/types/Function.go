@@ -0,0 +1,3 @@
+package types
+
+var Function = &Type{Name: "Function", Size: 8}
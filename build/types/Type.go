@@ -2,9 +2,10 @@ package types
 
 // Type represents a type in the type system.
 type Type struct {
-	Name   string
-	Size   uint
-	Fields []*Field
+	Name     string
+	Size     uint
+	Fields   []*Field
+	Unsigned bool
 }
 
 // FieldByName returns the field with the given name.
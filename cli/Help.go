@@ -15,10 +15,29 @@ func Help() {
 	log.Error.Println("")
 	log.Error.Println("Builds an executable from the source files in the directory.")
 	log.Error.Println("")
-	log.Error.Println("-a --assembly Show assembly output.")
-	log.Error.Println("-t --time     Show compilation timings.")
-	log.Error.Println("-v --verbose  Enables all optional information.")
-	log.Error.Println("-O --optimize Optimizes for performance.")
+	log.Error.Println("-a --assembly         Show assembly output.")
+	log.Error.Println("-t --time             Show compilation timings.")
+	log.Error.Println("-v --verbose          Enables all optional information.")
+	log.Error.Println("-O --optimize         Optimizes for performance.")
+	log.Error.Println("--overflow-checks     Exits with code 101 on integer overflows.")
+	log.Error.Println("--stack-guard         Exits with code 102 when recursion exhausts the stack.")
+	log.Error.Println("--warn-pure-calls     Warns about unused return values of functions without side effects.")
+	log.Error.Println("--warn-counter-writes Warns about assignments to the counter of a for loop inside the loop.")
+	log.Error.Println("--pie                 Builds a position-independent executable for Linux.")
+	log.Error.Println("-g --debug            Adds DWARF line number information for debuggers.")
+	log.Error.Println("--build-id            Adds the compiler version and a hash of the program to the executable.")
+	log.Error.Println("-r --run              Runs the executable after building it.")
+	log.Error.Println("--target=             Operating system: linux (default) or darwin.")
+	log.Error.Println("--cpu=                Instruction set: baseline (default) or haswell.")
+	log.Error.Println("--emit-asm            Writes the assembly to stdout instead of an executable.")
+	log.Error.Println("--emit-asm=           Writes the assembly to the given file instead of an executable.")
+	log.Error.Println("--verify-only         Compiles the program without writing an executable.")
+	log.Error.Println("--errors=             Error format: text (default) or json.")
+	log.Error.Println("--cache               Reuses unchanged functions from previous builds.")
+	log.Error.Println("--cache=              Reuses unchanged functions from the given cache directory.")
+	log.Error.Println("--keep-intermediate   Writes the machine code of each function to the 'intermediate' directory.")
+	log.Error.Println("--keep-intermediate=  Writes the machine code of each function to the given directory.")
+	log.Error.Println("--inline-threshold=   Maximum number of instructions of automatically inlined functions (default 2).")
 	log.Error.Println("")
 	log.Error.Println(color.YellowString("# system"))
 	log.Error.Println("")
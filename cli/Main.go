@@ -2,6 +2,10 @@ package cli
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/akyoto/q/build"
 	"github.com/akyoto/q/build/log"
@@ -12,10 +16,28 @@ import (
 // We never call os.Exit directly here because it's bad for testing.
 func Main() int {
 	var (
-		assembly  = false
-		timings   = false
-		optimize  = false
-		directory = "."
+		assembly         = false
+		timings          = false
+		optimize         = false
+		overflow         = false
+		stackGuard       = false
+		pureCalls        = false
+		counterWrites    = false
+		pie              = false
+		debug            = false
+		buildID          = false
+		run              = false
+		emitAssembly     = false
+		verifyOnly       = false
+		keepIntermediate = false
+		assemblyPath     = ""
+		errorFormat      = "text"
+		cache            = ""
+		intermediate     = ""
+		inlineThreshold  = build.DefaultInlineThreshold
+		directory        = "."
+		target           = build.Linux
+		cpu              = build.Baseline
 	)
 
 	if len(os.Args) < 2 {
@@ -43,6 +65,71 @@ func Main() int {
 	for i := 2; i < len(os.Args); i++ {
 		argument := os.Args[i]
 
+		if strings.HasPrefix(argument, "--target=") {
+			targetName := strings.TrimPrefix(argument, "--target=")
+			target = build.Targets[targetName]
+
+			if target == nil {
+				log.Error.Printf("Unknown target '%s'\n", targetName)
+				return 2
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(argument, "--cpu=") {
+			cpuName := strings.TrimPrefix(argument, "--cpu=")
+			cpu = build.CPUs[cpuName]
+
+			if cpu == nil {
+				log.Error.Printf("Unknown CPU '%s'\n", cpuName)
+				return 2
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(argument, "--emit-asm=") {
+			emitAssembly = true
+			assemblyPath = strings.TrimPrefix(argument, "--emit-asm=")
+			continue
+		}
+
+		if strings.HasPrefix(argument, "--errors=") {
+			errorFormat = strings.TrimPrefix(argument, "--errors=")
+
+			if errorFormat != "text" && errorFormat != "json" {
+				log.Error.Printf("Unknown error format '%s'\n", errorFormat)
+				return 2
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(argument, "--cache=") {
+			cache = strings.TrimPrefix(argument, "--cache=")
+			continue
+		}
+
+		if strings.HasPrefix(argument, "--keep-intermediate=") {
+			keepIntermediate = true
+			intermediate = strings.TrimPrefix(argument, "--keep-intermediate=")
+			continue
+		}
+
+		if strings.HasPrefix(argument, "--inline-threshold=") {
+			value := strings.TrimPrefix(argument, "--inline-threshold=")
+			threshold, err := strconv.Atoi(value)
+
+			if err != nil || threshold < 0 {
+				log.Error.Printf("Invalid inline threshold '%s'\n", value)
+				return 2
+			}
+
+			inlineThreshold = threshold
+			continue
+		}
+
 		switch argument {
 		case "-a", "--assembly":
 			assembly = true
@@ -57,6 +144,49 @@ func Main() int {
 		case "-O", "--optimize":
 			optimize = true
 
+		case "--overflow-checks":
+			overflow = true
+
+		case "--stack-guard":
+			stackGuard = true
+
+		case "--warn-pure-calls":
+			pureCalls = true
+
+		case "--warn-counter-writes":
+			counterWrites = true
+
+		case "--pie":
+			pie = true
+
+		case "-g", "--debug":
+			debug = true
+
+		case "--build-id":
+			buildID = true
+
+		case "-r", "--run":
+			run = true
+
+		case "--emit-asm":
+			emitAssembly = true
+
+		case "--verify-only":
+			verifyOnly = true
+
+		case "--cache":
+			cacheDirectory, err := os.UserCacheDir()
+
+			if err != nil {
+				log.Error.Println(err)
+				return 1
+			}
+
+			cache = filepath.Join(cacheDirectory, "q")
+
+		case "--keep-intermediate":
+			keepIntermediate = true
+
 		default:
 			directory = argument
 			stat, err := os.Stat(directory)
@@ -83,9 +213,65 @@ func Main() int {
 	b.ShowAssembly = assembly
 	b.ShowTimings = timings
 	b.Optimize = optimize
+	b.OverflowChecks = overflow
+	b.StackGuard = stackGuard
+	b.PureCallWarnings = pureCalls
+	b.CounterWriteWarnings = counterWrites
+	b.PIE = pie
+	b.Debug = debug
+	b.BuildID = buildID
+	b.Target = target
+	b.CPU = cpu
+	b.CacheDirectory = cache
+	b.IntermediateDirectory = intermediate
+	b.InlineThreshold = inlineThreshold
+	b.VerifyOnly = verifyOnly
+
+	if keepIntermediate && intermediate == "" {
+		b.IntermediateDirectory = filepath.Join(b.MainPackage.Path, "intermediate")
+	}
+
+	if emitAssembly {
+		b.EmitAssembly = os.Stdout
+
+		if assemblyPath != "" {
+			file, err := os.Create(assemblyPath)
+
+			if err != nil {
+				log.Error.Println(err)
+				return 1
+			}
+
+			defer file.Close()
+			b.EmitAssembly = file
+		}
+	}
+
 	err = b.Run()
 
 	if err != nil {
+		if errorFormat == "json" {
+			_ = build.WriteErrorsJSON(os.Stdout, err)
+			return 1
+		}
+
+		log.Error.Println(err)
+		return 1
+	}
+
+	if !run {
+		return 0
+	}
+
+	err = b.RunExecutable()
+
+	if err != nil {
+		exitError, ok := err.(*exec.ExitError)
+
+		if ok {
+			return exitError.ExitCode()
+		}
+
 		log.Error.Println(err)
 		return 1
 	}
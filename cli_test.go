@@ -1,10 +1,23 @@
 package main_test
 
 import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/json"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/akyoto/assert"
+	"github.com/akyoto/q/build"
+	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/log"
 	"github.com/akyoto/q/cli"
 )
 
@@ -20,6 +33,31 @@ func TestCLI(t *testing.T) {
 		{[]string{"q", "system"}, 0},
 		{[]string{"q", "build", "non-existing-directory"}, 1},
 		{[]string{"q", "build", "examples/hello/hello.q"}, 2},
+		{[]string{"q", "build", "--run", "examples/fibonacci"}, 89},
+		{[]string{"q", "build", "-r", "examples/files"}, 0},
+		{[]string{"q", "build", "--overflow-checks", "-r", "examples/overflow"}, build.OverflowExitCode},
+		{[]string{"q", "build", "--overflow-checks", "-O", "-r", "examples/overflow"}, build.OverflowExitCode},
+		{[]string{"q", "build", "--emit-asm=" + filepath.Join(t.TempDir(), "hello.s"), "examples/hello"}, 0},
+		{[]string{"q", "build", "--emit-asm=" + filepath.Join("non-existing-directory", "hello.s"), "examples/hello"}, 1},
+		{[]string{"q", "build", "--cache=" + t.TempDir(), "examples/hello"}, 0},
+		{[]string{"q", "build", "--keep-intermediate=" + t.TempDir(), "examples/hello"}, 0},
+		{[]string{"q", "build", "--inline-threshold=0", "examples/inline"}, 0},
+		{[]string{"q", "build", "--inline-threshold=-1", "examples/inline"}, 2},
+		{[]string{"q", "build", "-g", "-r", "examples/defer"}, 0},
+		{[]string{"q", "build", "--stack-guard", "-r", "examples/fibonacci"}, 89},
+		{[]string{"q", "build", "--stack-guard", "-O", "-r", "examples/tailcall"}, 0},
+		{[]string{"q", "build", "--stack-guard", "-r", "examples/tailcall"}, build.StackGuardExitCode},
+		{[]string{"q", "build", "--stack-guard", "--target=darwin", "examples/hello"}, 1},
+		{[]string{"q", "build", "--debug", "--target=darwin", "examples/hello"}, 1},
+		{[]string{"q", "build", "--pie", "-r", "examples/strings"}, 0},
+		{[]string{"q", "build", "--pie", "--target=darwin", "examples/hello"}, 1},
+		{[]string{"q", "build", "--pie", "--debug", "examples/hello"}, 1},
+		{[]string{"q", "build", "--build-id", "-g", "-r", "examples/hello"}, 0},
+		{[]string{"q", "build", "--build-id", "--target=darwin", "examples/hello"}, 1},
+		{[]string{"q", "build", "--build-id", "--pie", "examples/hello"}, 1},
+		{[]string{"q", "build", "--cpu=haswell", "examples/shift"}, 0},
+		{[]string{"q", "build", "--warn-counter-writes", "examples/loops"}, 0},
+		{[]string{"q", "build", "--cpu=invalid", "examples/hello"}, 2},
 	}
 
 	for _, example := range examples {
@@ -38,3 +76,658 @@ func TestCLI(t *testing.T) {
 	assert.Nil(t, err)
 	assert.True(t, stat.Size() > 0)
 }
+
+func TestEmitAssembly(t *testing.T) {
+	assembly := Assembly(t, "examples/strings")
+	assert.Contains(t, assembly, ".intel_syntax noprefix\n")
+	assert.Contains(t, assembly, "_start:\n\tcall q_main\n")
+	assert.Contains(t, assembly, "\nq_main:\n")
+	assert.Contains(t, assembly, "\nq_helloWorld:\n")
+	assert.Contains(t, assembly, "\nq_makeHello.data_8:\n\t.byte 0x48, 0x65, 0x6c, 0x6c, 0x6f\n")
+	assert.True(t, CountMatches(assembly, `\tmov qword ptr \[\w+\+8\], 5\n`) > 0)
+}
+
+func TestCPU(t *testing.T) {
+	assembly := func(cpu *build.CPU) string {
+		return AssemblyWith(t, "examples/shift", func(b *build.Build) {
+			b.CPU = cpu
+		})
+	}
+
+	// Shifts by a variable need the shift count in rcx unless BMI2 is available
+	baseline := assembly(build.Baseline)
+	assert.Contains(t, baseline, ", cl\n")
+	assert.NotContains(t, baseline, "\tshlx ")
+
+	haswell := assembly(build.Haswell)
+	assert.Contains(t, haswell, "\tshlx ")
+	assert.NotContains(t, haswell, ", cl\n")
+}
+
+func TestSavedRegisters(t *testing.T) {
+	assembly := Assembly(t, "examples/registers")
+
+	pushCount := func(function string) int {
+		return strings.Count(FunctionAssembly(t, assembly, function), "\tpush ")
+	}
+
+	// Registers modified by the functions called by the callee need to be saved
+	assert.Equal(t, pushCount("main"), 6)
+
+	// Variables in call registers are moved to registers the callee doesn't modify
+	assert.Equal(t, pushCount("offset"), 0)
+	assert.Equal(t, CountMatches(assembly, `\nq_offset:\n\tmov \w+, rdi\n\tmov rdi, 7\n\tcall q_scale\n`), 1)
+}
+
+func TestEpilogue(t *testing.T) {
+	returnCount := func(assembly string, function string) int {
+		return strings.Count(FunctionAssembly(t, assembly, function), "\tret")
+	}
+
+	// Functions with cleanup code share a single epilogue
+	deferred := Assembly(t, "examples/defer")
+	assert.Equal(t, returnCount(deferred, "early"), 1)
+	assert.Contains(t, deferred, "\tjmp q_early.return\n")
+
+	arrays := Assembly(t, "examples/return")
+	assert.Equal(t, returnCount(arrays, "buffer"), 1)
+	assert.Equal(t, strings.Count(arrays, "\tadd rsp, 16\n"), 1)
+
+	// Functions without cleanup code return directly
+	assert.Equal(t, returnCount(arrays, "sign"), 3)
+}
+
+func TestCompareZero(t *testing.T) {
+	assembly := func(optimize bool) string {
+		return AssemblyWith(t, "examples/else", func(b *build.Build) {
+			b.Optimize = optimize
+		})
+	}
+
+	// Optimized builds test the register instead of comparing it with zero
+	assert.True(t, CountMatches(assembly(false), `\tcmp \w+, 0\n`) > 0)
+	assert.NotContains(t, assembly(false), "\ttest ")
+	assert.True(t, CountMatches(assembly(true), `\ttest \w+, \w+\n`) > 0)
+	assert.Equal(t, CountMatches(assembly(true), `\tcmp \w+, 0\n`), 0)
+}
+
+func TestZeroWithXor(t *testing.T) {
+	count := func(optimize bool) string {
+		assembly := AssemblyWith(t, "examples/bool", func(b *build.Build) {
+			b.Optimize = optimize
+		})
+
+		return FunctionAssembly(t, assembly, "count")
+	}
+
+	// Optimized builds clear registers with a xor when the flags aren't needed
+	assert.Equal(t, CountMatches(count(false), `\tmov \w+, 0\n`), 2)
+	assert.NotContains(t, count(false), "\txor ")
+	assert.Equal(t, CountMatches(count(true), `\tmov \w+, 0\n`), 0)
+
+	xors := regexp.MustCompile(`\txor (\w+), (\w+)\n`).FindAllStringSubmatch(count(true), -1)
+	assert.Equal(t, len(xors), 2)
+
+	for _, xor := range xors {
+		assert.Equal(t, xor[1], xor[2])
+	}
+}
+
+func TestForwardMoves(t *testing.T) {
+	assembly := func(optimize bool) string {
+		return AssemblyWith(t, "examples/functions", func(b *build.Build) {
+			b.Optimize = optimize
+		})
+	}
+
+	// Copies of the return value that are moved to the parameter register right away
+	copies := func(assembly string) int {
+		count := 0
+
+		for _, moves := range regexp.MustCompile(`\tmov (\w+), rax\n\tmov rdi, (\w+)\n`).FindAllStringSubmatch(assembly, -1) {
+			if moves[1] == moves[2] {
+				count++
+			}
+		}
+
+		return count
+	}
+
+	// Return values that are only passed on don't need to be copied to the variable register first
+	assert.Equal(t, copies(assembly(false)), 4)
+	assert.Equal(t, copies(assembly(true)), 0)
+	assert.Equal(t, strings.Count(assembly(true), "\tmov rdi, rax\n"), 4)
+}
+
+func TestMultiplyAdd(t *testing.T) {
+	assembly := func(optimize bool) string {
+		return AssemblyWith(t, "examples/multiplyadd", func(b *build.Build) {
+			b.Optimize = optimize
+		})
+	}
+
+	// Multiplications with 1, 2, 4 or 8 followed by an addition need a single lea instead of 3 instructions
+	assert.True(t, CountMatches(assembly(false), `\tmov \w+, \w+\n\timul \w+, 4\n\tadd \w+, \w+\n`) > 0)
+	assert.True(t, CountMatches(assembly(true), `\tlea \w+, \[\w+\+\w+\*4\]\n`) > 0)
+	assert.Equal(t, strings.Count(assembly(false), "\tlea "), 0)
+	assert.Equal(t, strings.Count(assembly(true), "\tlea "), 3)
+
+	// Other factors keep the multiplication which is computed before the addition
+	assert.True(t, CountMatches(assembly(true), `\tmov \w+, \w+\n\timul \w+, \w+\n\tadd \w+, \w+\n`) > 0)
+}
+
+func TestConstantCondition(t *testing.T) {
+	main := FunctionAssembly(t, Assembly(t, "examples/constantif"), "main")
+
+	// Only the branches that are taken remain and they don't need a comparison or a jump
+	assert.Equal(t, strings.Count(main, "\tsyscall\n"), 3)
+	assert.NotContains(t, main, "\tjmp ")
+	assert.Equal(t, CountMatches(main, `\tj\w+ main\.(if|else)_`), 0)
+}
+
+func TestArrayAddress(t *testing.T) {
+	cache := t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		assembly := AssemblyWith(t, "examples/array", func(b *build.Build) {
+			b.CacheDirectory = cache
+		})
+
+		// Arrays after the first one are addressed relative to the stack pointer
+		assert.True(t, CountMatches(assembly, `\tlea \w+, \[rsp\+16\]\n`) > 0)
+
+		// Functions restored from the cache produce the same program
+		b, err := build.New("examples/array")
+		assert.Nil(t, err)
+		b.CacheDirectory = cache
+		b.ExecutablePath = filepath.Join(t.TempDir(), "array")
+		assert.Nil(t, b.Run())
+
+		result, err := exec.Command(b.ExecutablePath).Output()
+		assert.Nil(t, err)
+		assert.Equal(t, string(result), "9\n82\nHello\n285\n5\n")
+	}
+}
+
+func TestSpill(t *testing.T) {
+	// Variables that are not needed by the current statement move to the stack
+	spill := Assembly(t, "examples/spill")
+	assert.True(t, CountMatches(spill, `\tmov qword ptr \[rsp\], \w+\n`) > 0)
+	assert.True(t, CountMatches(spill, `\tmov \w+, qword ptr \[rsp\]\n`) > 0)
+
+	// Statements inside of blocks spill the variables declared in the block
+	assert.Contains(t, FunctionAssembly(t, spill, "nested"), "qword ptr [rsp")
+
+	// Functions with enough registers never spill
+	assert.NotContains(t, Assembly(t, "examples/registers"), "qword ptr [rsp")
+}
+
+func TestLiveVariables(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.Info.SetOutput(output)
+	defer log.Info.SetOutput(io.Discard)
+
+	b, err := build.New("examples/registers")
+	assert.Nil(t, err)
+	b.ShowAssembly = true
+	defer os.Remove(b.ExecutablePath)
+	assert.Nil(t, b.Run())
+
+	// The peak is shown in front of the assembly of each function
+	assert.Contains(t, output.String(), "live variables: 5\nmain:\n")
+	assert.Contains(t, output.String(), "live variables: 2\noffset:\n")
+}
+
+func TestSourceLines(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.Info.SetOutput(output)
+	defer log.Info.SetOutput(io.Discard)
+
+	b, err := build.New("examples/fibonacci")
+	assert.Nil(t, err)
+	b.ShowAssembly = true
+	defer os.Remove(b.ExecutablePath)
+	assert.Nil(t, b.Run())
+
+	// Instructions show the line they were generated for, labels don't
+	assert.True(t, CountMatches(output.String(), `    add \w+=c, \w+=b  # line 15\n`) > 0)
+	assert.Contains(t, output.String(), "\nfor_1:\n")
+
+	// The assembly output stays free of annotations
+	assert.NotContains(t, Assembly(t, "examples/fibonacci"), "# line")
+}
+
+func TestUnusedCode(t *testing.T) {
+	// Builtins are generated at the call site and the entry code has no runtime routines
+	hello := Assembly(t, "examples/hello")
+	text := hello[:strings.Index(hello, "\n.data\n")]
+	assert.Equal(t, strings.Count(text, ":\n"), 2)
+	assert.Contains(t, hello, "_start:\n\tcall q_main\n")
+	assert.NotContains(t, text, "\tdiv ")
+
+	// Functions of imported packages are only included if they are called
+	read := Assembly(t, "examples/read")
+	assert.Contains(t, read, "\nq_sys.write:\n")
+	assert.NotContains(t, read, "\nq_sys.read:\n")
+	assert.NotContains(t, read, "\nq_sys.open:\n")
+
+	// Functions that can't be reached from 'main' are excluded together with their callees
+	directory := t.TempDir()
+	code := "main() {\n\tprint(used())\n}\n\nused() -> Int {\n\treturn 1\n}\n\nunused() {\n\tprint(helper(2))\n}\n\nhelper(x Int) -> Int {\n\tprint(x)\n\tprint(x)\n\treturn x\n}\n"
+	err := os.WriteFile(filepath.Join(directory, "main.q"), []byte(code), 0644)
+	assert.Nil(t, err)
+	unused := Assembly(t, directory)
+	assert.NotContains(t, unused, "\nq_unused:\n")
+	assert.NotContains(t, unused, "\nq_helper:\n")
+	assert.NotContains(t, unused, "call q_helper")
+}
+
+func TestSizes(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.Info.SetOutput(output)
+	defer log.Info.SetOutput(io.Discard)
+
+	b, err := build.New("examples/hello")
+	assert.Nil(t, err)
+	b.ShowAssembly = true
+	b.ExecutablePath = filepath.Join(t.TempDir(), "hello")
+	assert.Nil(t, b.Run())
+
+	stat, err := os.Stat(b.ExecutablePath)
+	assert.Nil(t, err)
+	assert.True(t, stat.Size() < 300)
+	assert.Contains(t, output.String(), "Data:")
+	assert.Contains(t, output.String(), strconv.FormatInt(stat.Size(), 10)+" bytes\n")
+}
+
+func TestInline(t *testing.T) {
+	assembly := func(threshold int) string {
+		return AssemblyWith(t, "examples/inline", func(b *build.Build) {
+			b.InlineThreshold = threshold
+		})
+	}
+
+	// Annotations force the decision regardless of the threshold
+	defaultThreshold := assembly(build.DefaultInlineThreshold)
+	assert.NotContains(t, defaultThreshold, "call q_square")
+	assert.NotContains(t, defaultThreshold, "call q_scale")
+	assert.Contains(t, defaultThreshold, "call q_twice")
+
+	noInlining := assembly(0)
+	assert.Contains(t, noInlining, "call q_square")
+	assert.NotContains(t, noInlining, "call q_scale")
+	assert.Contains(t, noInlining, "call q_twice")
+}
+
+func TestMutualRecursion(t *testing.T) {
+	assembly := AssemblyWith(t, "examples/forward", func(b *build.Build) {
+		b.InlineThreshold = 1000
+	})
+
+	// Functions calling each other are never inlined, regardless of the threshold
+	assert.Contains(t, assembly, "call q_ping")
+	assert.Contains(t, assembly, "call q_pong")
+	assert.Contains(t, assembly, "\nq_ping:\n")
+	assert.Contains(t, assembly, "\nq_pong:\n")
+}
+
+func TestKeepIntermediate(t *testing.T) {
+	directory := t.TempDir()
+	b, err := build.New("examples/functions")
+	assert.Nil(t, err)
+	b.IntermediateDirectory = directory
+	assert.Nil(t, b.Run())
+
+	index, err := os.ReadFile(filepath.Join(directory, build.IntermediateIndexFile))
+	assert.Nil(t, err)
+	assert.Contains(t, string(index), " main\n")
+
+	// Each function is stored with its offset in the code section and its size
+	for _, line := range strings.Split(strings.TrimSpace(string(index)), "\n") {
+		fields := strings.Fields(line)
+		assert.Equal(t, len(fields), 3)
+
+		code, err := os.ReadFile(filepath.Join(directory, fields[2]+".bin"))
+		assert.Nil(t, err)
+		assert.Equal(t, strconv.Itoa(len(code)), fields[1])
+	}
+}
+
+func TestCache(t *testing.T) {
+	directory := t.TempDir()
+	cache := t.TempDir()
+
+	write := func(name string, code string) {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(code), 0644)
+		assert.Nil(t, err)
+	}
+
+	compile := func() (string, int32) {
+		b, err := build.New(directory)
+		assert.Nil(t, err)
+		b.CacheDirectory = cache
+		assert.Nil(t, b.Run())
+
+		output, err := exec.Command(b.ExecutablePath).Output()
+		assert.Nil(t, err)
+		return string(output), b.Environment.Cache.Hits
+	}
+
+	write("main.q", "main() {\n\tprint(double(three()))\n}\n")
+	write("double.q", "double(x Int) -> Int {\n\treturn x * 2\n}\n")
+	write("three.q", "three() -> Int {\n\treturn 3\n}\n")
+
+	output, hits := compile()
+	assert.Equal(t, output, "6\n")
+	assert.Equal(t, hits, int32(0))
+
+	output, hits = compile()
+	assert.Equal(t, output, "6\n")
+	assert.True(t, hits > 0)
+
+	// Changing a function invalidates all of its callers
+	write("three.q", "three() -> Int {\n\treturn 4\n}\n")
+	output, _ = compile()
+	assert.Equal(t, output, "8\n")
+
+	// Entries of a different compiler build are never used
+	assert.True(t, build.CompilerID() != "")
+	assert.Equal(t, build.CompilerID(), build.NewCache(cache).Compiler)
+
+	b, err := build.New(directory)
+	assert.Nil(t, err)
+	b.CacheDirectory = cache
+	assert.Nil(t, b.Scan())
+	b.Environment.Cache.Compiler = "other"
+	_, err = b.Compile()
+	assert.Nil(t, err)
+	assert.Equal(t, b.Environment.Cache.Hits, int32(0))
+}
+
+func TestRead(t *testing.T) {
+	b, err := build.New("examples/read")
+	assert.Nil(t, err)
+	b.ExecutablePath = filepath.Join(t.TempDir(), "read")
+	assert.Nil(t, b.Run())
+
+	cmd := exec.Command(b.ExecutablePath)
+	cmd.Stdin = strings.NewReader("Hello\n")
+	output, err := cmd.Output()
+	assert.Nil(t, err)
+	assert.Equal(t, string(output), "6\nHello\n")
+}
+
+func TestArguments(t *testing.T) {
+	for _, stackGuard := range []bool{false, true} {
+		b, err := build.New("examples/args")
+		assert.Nil(t, err)
+		b.ExecutablePath = filepath.Join(t.TempDir(), "args")
+		b.StackGuard = stackGuard
+		assert.Nil(t, b.Run())
+
+		output, err := exec.Command(b.ExecutablePath, "hello", "world").Output()
+		assert.Nil(t, err)
+		assert.Equal(t, string(output), "3\nhello\n")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	for _, stackGuard := range []bool{false, true} {
+		b, err := build.New("examples/env")
+		assert.Nil(t, err)
+		b.ExecutablePath = filepath.Join(t.TempDir(), "env")
+		b.StackGuard = stackGuard
+		assert.Nil(t, b.Run())
+
+		cmd := exec.Command(b.ExecutablePath)
+		cmd.Env = []string{"GREETINGS=no", "GREETING=Hello", "GREET=Hi"}
+		output, err := cmd.Output()
+		assert.Nil(t, err)
+		assert.Equal(t, string(output), "Hello\nHi\nnot set\n")
+	}
+}
+
+func TestStackGuard(t *testing.T) {
+	directory := t.TempDir()
+	err := os.WriteFile(filepath.Join(directory, "main.q"), []byte("main() {\n\tprint(depth(0))\n}\n\ndepth(n Int) -> Int {\n\treturn depth(n + 1) + 1\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	for _, optimize := range []bool{false, true} {
+		b, err := build.New(directory)
+		assert.Nil(t, err)
+		b.StackGuard = true
+		b.Optimize = optimize
+		assert.Nil(t, b.Run())
+
+		err = exec.Command(b.ExecutablePath).Run()
+		exitError, ok := err.(*exec.ExitError)
+		assert.True(t, ok)
+		assert.Equal(t, exitError.ExitCode(), build.StackGuardExitCode)
+	}
+}
+
+func TestDebug(t *testing.T) {
+	directory := t.TempDir()
+	err := os.WriteFile(filepath.Join(directory, "main.q"), []byte("main() {\n\tlet x = 3\n\n\tprint(x + 4)\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	b, err := build.New(directory)
+	assert.Nil(t, err)
+	b.Debug = true
+	assert.Nil(t, b.Run())
+
+	output, err := exec.Command(b.ExecutablePath).Output()
+	assert.Nil(t, err)
+	assert.Equal(t, string(output), "7\n")
+
+	executable, err := elf.Open(b.ExecutablePath)
+	assert.Nil(t, err)
+	defer executable.Close()
+
+	data, err := executable.DWARF()
+	assert.Nil(t, err)
+
+	unit, err := data.Reader().Next()
+	assert.Nil(t, err)
+	assert.NotNil(t, unit)
+
+	lines, err := data.LineReader(unit)
+	assert.Nil(t, err)
+
+	text := executable.Section(".text")
+	assert.NotNil(t, text)
+	found := map[int]bool{}
+	entry := dwarf.LineEntry{}
+
+	for lines.Next(&entry) == nil {
+		if entry.EndSequence {
+			continue
+		}
+
+		assert.Equal(t, entry.File.Name, filepath.Join(directory, "main.q"))
+		assert.True(t, entry.Address >= text.Addr && entry.Address < text.Addr+text.Size)
+		found[entry.Line] = true
+	}
+
+	assert.DeepEqual(t, found, map[int]bool{2: true, 4: true})
+}
+
+func TestBuildID(t *testing.T) {
+	buildID := func(directory string) []byte {
+		b, err := build.New(directory)
+		assert.Nil(t, err)
+		b.BuildID = true
+		b.ExecutablePath = filepath.Join(t.TempDir(), "program")
+		assert.Nil(t, b.Run())
+
+		output, err := exec.Command(b.ExecutablePath).Output()
+		assert.Nil(t, err)
+		assert.True(t, len(output) > 0)
+
+		executable, err := elf.Open(b.ExecutablePath)
+		assert.Nil(t, err)
+		defer executable.Close()
+
+		comment, err := executable.Section(".comment").Data()
+		assert.Nil(t, err)
+		assert.Equal(t, string(comment), "q "+build.CompilerVersion()+"\x00")
+
+		note, err := executable.Section(".note.gnu.build-id").Data()
+		assert.Nil(t, err)
+		assert.Equal(t, len(note), 36)
+		assert.DeepEqual(t, note[12:16], []byte("GNU\x00"))
+		return note[16:]
+	}
+
+	hello := buildID("examples/hello")
+	assert.DeepEqual(t, buildID("examples/hello"), hello)
+	assert.NotEqual(t, string(buildID("examples/strings")), string(hello))
+}
+
+func TestVerifyOnly(t *testing.T) {
+	directory := t.TempDir()
+	source, err := os.ReadFile("examples/hello/hello.q")
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, "hello.q"), source, 0644))
+
+	os.Args = []string{"q", "build", "--verify-only", "-r", directory}
+	assert.Equal(t, cli.Main(), 0)
+
+	_, err = os.Stat(filepath.Join(directory, filepath.Base(directory)))
+	assert.True(t, os.IsNotExist(err))
+
+	// Errors found during code generation are reported as well
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, "hello.q"), []byte("main() {\n\tprint(x)\n}\n"), 0644))
+	assert.Equal(t, cli.Main(), 1)
+}
+
+func TestErrorsJSON(t *testing.T) {
+	directory := t.TempDir()
+	source := "main() {\n\tprint(\"\")\n\tprint(x)\n}\n\nf() {\n\tg()\n}\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, "main.q"), []byte(source), 0644))
+
+	b, err := build.New(directory)
+	assert.Nil(t, err)
+	err = b.Run()
+	assert.NotNil(t, err)
+
+	// Errors of all functions are reported in the order of their position
+	output := &bytes.Buffer{}
+	assert.Nil(t, build.WriteErrorsJSON(output, err))
+
+	var list []struct {
+		File    string `json:"file"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	assert.Nil(t, json.Unmarshal(output.Bytes(), &list))
+	assert.Equal(t, len(list), 2)
+	assert.True(t, strings.HasSuffix(list[0].File, "main.q"))
+	assert.Equal(t, list[0].Line, 3)
+	assert.Equal(t, list[0].Column, 2)
+	assert.Equal(t, list[0].Code, "UnknownVariable")
+	assert.Equal(t, list[0].Message, "Unknown variable 'x'")
+	assert.Equal(t, list[1].Line, 7)
+	assert.Equal(t, list[1].Column, 2)
+	assert.Equal(t, list[1].Code, "UnknownFunction")
+
+	os.Args = []string{"q", "build", "--errors=json", directory}
+	assert.Equal(t, cli.Main(), 1)
+
+	os.Args = []string{"q", "build", "--errors=xml", directory}
+	assert.Equal(t, cli.Main(), 2)
+}
+
+func TestMultipleErrors(t *testing.T) {
+	// Errors found while scanning different files are reported together
+	directory := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, "a.q"), []byte("main() {\n}\n\n/* comment\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, "b.q"), []byte("x = 1\n"), 0644))
+
+	b, err := build.New(directory)
+	assert.Nil(t, err)
+	err = b.Run()
+	list, isList := err.(build.ErrorList)
+	assert.True(t, isList)
+	assert.Equal(t, len(list), 2)
+	assert.Equal(t, errors.Code(list[0]), "UnterminatedComment")
+	assert.Equal(t, errors.Code(list[1]), "ParameterOpeningBracket")
+
+	// Callers of a function with an error don't report it a second time
+	directory = t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, "main.q"), []byte("main() {\n\tf()\n}\n\nf() {\n\tg()\n}\n"), 0644))
+
+	b, err = build.New(directory)
+	assert.Nil(t, err)
+	err = b.Run()
+	_, isList = err.(build.ErrorList)
+	assert.False(t, isList)
+	assert.Equal(t, errors.Code(err), "UnknownFunction")
+
+	// The number of reported errors is limited
+	directory = t.TempDir()
+	source := strings.Builder{}
+	source.WriteString("main() {\n}\n")
+
+	for i := 0; i < build.MaxErrors+5; i++ {
+		source.WriteString("\nf" + strconv.Itoa(i) + "() {\n\tprint(x)\n}\n")
+	}
+
+	assert.Nil(t, os.WriteFile(filepath.Join(directory, "main.q"), []byte(source.String()), 0644))
+
+	b, err = build.New(directory)
+	assert.Nil(t, err)
+	err = b.Run()
+	list, isList = err.(build.ErrorList)
+	assert.True(t, isList)
+	assert.Equal(t, len(list), build.MaxErrors+1)
+	assert.Equal(t, list[build.MaxErrors].Error(), (&errors.TooManyErrors{Omitted: 5}).Error())
+	assert.Equal(t, errors.Code(list[0]), "UnknownVariable")
+}
+
+func TestBytes(t *testing.T) {
+	for _, target := range []*build.Target{build.Linux, build.Darwin} {
+		b, err := build.New("examples/hello")
+		assert.Nil(t, err)
+		b.Target = target
+		b.ExecutablePath = filepath.Join(t.TempDir(), "hello")
+		assert.Nil(t, b.Run())
+
+		written, err := os.ReadFile(b.ExecutablePath)
+		assert.Nil(t, err)
+
+		b, err = build.New("examples/hello")
+		assert.Nil(t, err)
+		b.Target = target
+		b.ExecutablePath = filepath.Join(t.TempDir(), "hello")
+		executable, err := b.Bytes()
+		assert.Nil(t, err)
+		assert.DeepEqual(t, executable, written)
+
+		_, err = os.Stat(b.ExecutablePath)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestPIE(t *testing.T) {
+	directory := t.TempDir()
+	err := os.WriteFile(filepath.Join(directory, "main.q"), []byte("main() {\n\tprint(\"Hello\")\n\tlet f = greet\n\tf()\n}\n\ngreet() {\n\tprint(\"World\")\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	b, err := build.New(directory)
+	assert.Nil(t, err)
+	b.PIE = true
+	assert.Nil(t, b.Run())
+
+	output, err := exec.Command(b.ExecutablePath).Output()
+	assert.Nil(t, err)
+	assert.Equal(t, string(output), "Hello\nWorld\n")
+
+	executable, err := elf.Open(b.ExecutablePath)
+	assert.Nil(t, err)
+	defer executable.Close()
+	assert.Equal(t, executable.Type, elf.ET_DYN)
+}
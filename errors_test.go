@@ -1,12 +1,16 @@
 package main_test
 
 import (
+	"bytes"
+	"io"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/akyoto/assert"
+	"github.com/akyoto/q/build"
 	"github.com/akyoto/q/build/errors"
+	"github.com/akyoto/q/build/log"
 )
 
 func TestErrors(t *testing.T) {
@@ -14,36 +18,110 @@ func TestErrors(t *testing.T) {
 		File          string
 		ExpectedError error
 	}{
+		{"annotation-without-function.q", errors.MissingAnnotatedFunction},
+		{"array-invalid-size.q", errors.InvalidArraySize},
+		{"asm-invalid-immediate.q", &errors.InvalidOperand{Operand: "4294967296"}},
+		{"asm-invalid-immediate-byte.q", &errors.InvalidOperand{Operand: "256"}},
+		{"asm-invalid-operands.q", &errors.InvalidOperands{Mnemonic: "inc"}},
+		{"asm-operand-sizes.q", &errors.InvalidOperands{Mnemonic: "mov"}},
+		{"asm-stack-pointer.q", &errors.InvalidOperand{Operand: "rsp"}},
+		{"asm-unknown-mnemonic.q", &errors.UnknownMnemonic{Name: "jmp"}},
+		{"assert-in-expression.q", errors.AssertInExpression},
+		{"assert-parameter-count.q", &errors.ParameterCount{FunctionName: "assert", CountGiven: 2, CountRequired: 1}},
+		{"break-outside-loop.q", errors.BreakOutsideLoop},
+		{"const-assignment.q", &errors.ConstantAssignment{Name: "limit"}},
+		{"const-assignment-local.q", &errors.ConstantAssignment{Name: "count"}},
+		{"const-not-constant.q", errors.NotConstant},
+		{"continue-outside-loop.q", errors.ContinueOutsideLoop},
+		{"defer-inside-block.q", errors.DeferInsideBlock},
+		{"discard-compound.q", errors.InvalidExpression},
+		{"division-by-zero.q", errors.DivisionByZero},
+		{"division-by-zero-remainder.q", errors.DivisionByZero},
+		{"else-without-if.q", errors.MissingIf},
 		{"ensure-no-return-type.q", errors.EnsureWithoutFunctionType},
+		{"exceeded-max-parameters.q", errors.ExceededMaxParameters},
+		{"exceeded-max-spill-slots.q", errors.ExceededMaxSpillSlots},
+		{"for-descending-range.q", &errors.EmptyRange{Start: 10, Limit: 0}},
+		{"for-empty-range.q", &errors.EmptyRange{Start: 5, Limit: 5}},
+		{"for-invalid-step.q", errors.InvalidStep},
 		{"for-missing-upper-limit.q", errors.MissingRangeLimit},
 		{"for-missing-range.q", errors.MissingRange},
 		{"for-missing-start-value.q", errors.MissingRangeStart},
 		{"immutable-variable.q", &errors.ImmutableVariable{Name: "a"}},
+		{"inline-recursive.q", errors.RecursiveInline},
 		{"import-already-exists.q", &errors.ImportNameAlreadyExists{Name: "sys", ImportPath: "sys"}},
 		{"ineffective-assignment.q", &errors.IneffectiveAssignment{Name: "a"}},
+		{"invalid-cast.q", &errors.InvalidCast{From: "Int64", To: "Bool"}},
+		{"invalid-cast-parameters.q", &errors.ParameterCount{FunctionName: "Int32", CountGiven: 2, CountRequired: 1}},
+		{"invalid-character-literal.q", &errors.InvalidCharacterLiteral{Expression: "'ab'"}},
+		{"invalid-concatenation.q", errors.InvalidConcatenation},
+		{"invalid-escape-sequence.q", &errors.InvalidEscapeSequence{Sequence: "\\%"}},
+		{"invalid-main-parameters.q", errors.InvalidMainParameters},
+		{"invalid-number-leading-underscore.q", &errors.InvalidNumber{Expression: "_100"}},
+		{"invalid-number-literal.q", &errors.InvalidNumber{Expression: "0b102"}},
+		{"invalid-number-underscores.q", &errors.InvalidNumber{Expression: "1__000"}},
+		{"invalid-type-env.q", &errors.InvalidType{Name: "Float64", Expected: "Int64", ParameterName: "name"}},
+		{"invalid-type-float.q", &errors.InvalidType{Name: "Int64", Expected: "Float64"}},
 		{"invalid-type-field-assign.q", &errors.InvalidType{Name: "Int64", Expected: "Int32"}},
+		{"invalid-type-condition.q", &errors.InvalidType{Name: "Int64", Expected: "Bool"}},
+		{"invalid-type-hex.q", &errors.InvalidType{Name: "Float64", Expected: "Int64"}},
+		{"invalid-type-len-env.q", &errors.InvalidType{Name: "Int64", Expected: "Text"}},
+		{"invalid-type-len-number.q", &errors.InvalidType{Name: "Int64", Expected: "Text"}},
+		{"invalid-type-len-variable.q", &errors.InvalidType{Name: "Int64", Expected: "Text"}},
+		{"invalid-type-logical.q", &errors.InvalidType{Name: "Int64", Expected: "Bool"}},
+		{"invalid-type-function-call.q", &errors.InvalidType{Name: "Int64", Expected: "Function"}},
+		{"invalid-type-min.q", &errors.InvalidType{Name: "Float64", Expected: "Int64", ParameterName: "b"}},
+		{"invalid-type-unsigned.q", &errors.InvalidType{Name: "Int64", Expected: "UInt64", ParameterName: "x"}},
+		{"invalid-type-narrowing.q", &errors.InvalidType{Name: "Int64", Expected: "Int32", ParameterName: "x"}},
+		{"invalid-type-widening-signed.q", &errors.InvalidType{Name: "Int32", Expected: "UInt64", ParameterName: "x"}},
+		{"load-invalid-byte-count.q", errors.InvalidByteCount},
 		{"missing-opening-bracket.q", &errors.MissingCharacter{Character: "("}},
 		{"missing-closing-bracket.q", &errors.MissingCharacter{Character: ")"}},
+		{"missing-operand.q", errors.MissingOperand},
+		{"missing-operator-number.q", errors.MissingOperator},
+		{"missing-operator.q", errors.MissingOperator},
 		{"missing-return-type.q", errors.MissingReturnType},
 		{"missing-return-value.q", &errors.MissingReturnValue{ReturnType: "Int64"}},
 		{"missing-struct-name.q", errors.MissingStructName},
 		{"missing-type.q", &errors.MissingType{Of: "length"}},
 		{"package-doesnt-exist.q", &errors.PackageDoesntExist{ImportPath: "non.existing.package"}},
 		{"parameter-count.q", &errors.ParameterCount{FunctionName: "sum", CountGiven: 1, CountRequired: 2}},
+		{"print-function.q", &errors.InvalidPrintParameter{FunctionName: "print", Parameter: "f"}},
+		{"print-parameter-count.q", &errors.ParameterCount{FunctionName: "print", CountGiven: 0, CountRequired: 1}},
+		{"print-struct.q", &errors.InvalidPrintParameter{FunctionName: "print", Parameter: "p"}},
+		{"repeat-missing-count.q", errors.MissingRepeatCount},
+		{"repeat-zero.q", &errors.EmptyRepeat{Count: 0}},
 		{"return-without-type.q", errors.ReturnWithoutFunctionType},
+		{"sizeof-expected-type-name.q", errors.ExpectedTypeName},
+		{"sizeof-unknown-type.q", &errors.UnknownType{Name: "Pont", CorrectName: "Point"}},
+		{"switch-case-after-else.q", errors.CaseAfterElse},
+		{"switch-duplicate-case.q", &errors.DuplicateCase{Value: 2}},
+		{"switch-missing-value.q", errors.MissingSwitchValue},
+		{"switch-not-constant.q", errors.NotConstant},
 		{"unnecessary-newlines.q", errors.UnnecessaryNewlines},
+		{"unterminated-comment.q", errors.UnterminatedComment},
 		{"unused-variable.q", &errors.UnusedVariable{Name: "a"}},
+		{"unused-variable-block.q", &errors.UnusedVariable{Name: "a"}},
 		{"unused-mutable.q", &errors.UnmodifiedMutable{Name: "a"}},
+		{"unknown-constant-suggestion.q", &errors.UnknownVariable{Name: "limt", CorrectName: "limit"}},
 		{"unknown-field.q", &errors.UnknownField{Name: "z", TypeName: "Point"}},
 		{"unknown-field-suggestion.q", &errors.UnknownField{Name: "xx", CorrectName: "x", TypeName: "Point"}},
+		{"unknown-annotation.q", &errors.UnknownAnnotation{Name: "@inlined"}},
 		{"unknown-function.q", &errors.UnknownFunction{Name: "z"}},
 		{"unknown-function-suggestion.q", &errors.UnknownFunction{Name: "prin", CorrectName: "print"}},
+		{"unknown-function-builtin-suggestion.q", &errors.UnknownFunction{Name: "cpuidd", CorrectName: "cpuid"}},
 		{"unknown-expression.q", &errors.UnknownExpression{Expression: "\")"}},
 		{"unknown-variable.q", &errors.UnknownVariable{Name: "a"}},
 		{"unknown-variable-suggestion.q", &errors.UnknownVariable{Name: "lengt", CorrectName: "length"}},
+		{"unknown-variable-scope.q", &errors.UnknownVariable{Name: "a"}},
+		{"unknown-variable-block.q", &errors.UnknownVariable{Name: "a"}},
+		{"unknown-variable-block-comment.q", &errors.UnknownVariable{Name: "b"}},
 		{"unknown-package.q", &errors.UnknownPackage{Name: "sy", CorrectName: "sys"}},
-		{"unused-parameter.q", &errors.UnusedVariable{Name: "b"}},
+		{"unknown-type-suggestion.q", &errors.UnknownType{Name: "Flaot64", CorrectName: "Float64"}},
 		{"variable-already-exists.q", &errors.VariableAlreadyExists{Name: "a"}},
+		{"variable-shadowing.q", &errors.VariableAlreadyExists{Name: "a"}},
+		{"variable-shadowing-for.q", &errors.VariableAlreadyExists{Name: "i"}},
+		{"variable-shadowing-parameter.q", &errors.VariableAlreadyExists{Name: "a"}},
 	}
 
 	for _, test := range tests {
@@ -54,6 +132,122 @@ func TestErrors(t *testing.T) {
 			err := Check(filepath.Join("build", "errors", "testdata", test.File))
 			assert.NotNil(t, err)
 			assert.Contains(t, err.Error(), test.ExpectedError.Error())
+
+			// Tools can access the error properties without parsing the message
+			diagnostic, isDiagnostic := err.(errors.Diagnostic)
+			assert.True(t, isDiagnostic)
+			assert.Equal(t, diagnostic.Code(), errors.Code(test.ExpectedError))
+			assert.Contains(t, diagnostic.Message(), test.ExpectedError.Error())
+			assert.True(t, strings.HasSuffix(diagnostic.Position().Path, test.File))
+			assert.True(t, diagnostic.Position().Line > 0)
+			assert.True(t, diagnostic.Position().Column > 0)
+
+			// Names that are not similar enough must not be suggested
+			if !strings.Contains(test.ExpectedError.Error(), "did you mean") {
+				assert.NotContains(t, err.Error(), "did you mean")
+			}
+		})
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		File             string
+		ExpectedPosition string
+	}{
+		{"division-by-zero.q", "division-by-zero.q:3:12: [main] "},
+		{"division-by-zero-remainder.q", "division-by-zero-remainder.q:3:10: [main] "},
+		{"ensure-no-return-type.q", "ensure-no-return-type.q:2:2: [main] "},
+		{"exceeded-max-parameters.q", "exceeded-max-parameters.q:5:45: [f] "},
+		{"for-missing-range.q", "for-missing-range.q:2:6: [main] "},
+		{"inline-recursive.q", "inline-recursive.q:5:1: [countdown] "},
+		{"missing-operand.q", "missing-operand.q:3:10: [main] "},
+		{"switch-duplicate-case.q", "switch-duplicate-case.q:9:3: [main] "},
+		{"unknown-expression.q", "unknown-expression.q:1:9: "},
+		{"unknown-function-suggestion.q", "unknown-function-suggestion.q:2:2: [main] "},
+		{"unknown-variable.q", "unknown-variable.q:2:2: [main] "},
+		{"unknown-variable-block-comment.q", "unknown-variable-block-comment.q:6:39: [main] "},
+		{"unknown-variable-scope.q", "unknown-variable-scope.q:8:2: [main] "},
+		{"unused-variable-block.q", "unused-variable-block.q:3:7: [main] "},
+		{"unterminated-comment.q", "unterminated-comment.q:5:1: "},
+		{"variable-already-exists.q", "variable-already-exists.q:3:6: [main] "},
+	}
+
+	for _, test := range tests {
+		test := test
+		name := strings.TrimSuffix(test.File, ".q")
+
+		t.Run(name, func(t *testing.T) {
+			err := Check(filepath.Join("build", "errors", "testdata", test.File))
+			assert.NotNil(t, err)
+			assert.Contains(t, err.Error(), test.ExpectedPosition)
+		})
+	}
+}
+
+func TestPureCallWarnings(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.Error.SetOutput(output)
+	defer log.Error.SetOutput(io.Discard)
+
+	// Calls for side effects and discarded values with '_' are fine
+	err := CheckWith(filepath.Join("build", "errors", "testdata", "warn-pure-call.q"), func(env *build.Environment) {
+		env.PureCallWarnings = true
+	})
+
+	assert.Nil(t, err)
+	assert.Contains(t, output.String(), "warn-pure-call.q:2:2: [main] "+(&errors.UnusedReturnValue{FunctionName: "square"}).Error())
+	assert.Equal(t, strings.Count(output.String(), "\n"), 1)
+
+	// The warning is opt-in
+	output.Reset()
+	err = Check(filepath.Join("build", "errors", "testdata", "warn-pure-call.q"))
+	assert.Nil(t, err)
+	assert.Equal(t, output.String(), "")
+}
+
+func TestCounterWriteWarnings(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.Error.SetOutput(output)
+	defer log.Error.SetOutput(io.Discard)
+
+	// Only assignments to the counter itself are reported
+	err := CheckWith(filepath.Join("build", "errors", "testdata", "warn-counter-write.q"), func(env *build.Environment) {
+		env.CounterWriteWarnings = true
+	})
+
+	assert.Nil(t, err)
+	assert.Contains(t, output.String(), "warn-counter-write.q:3:3: [main] "+(&errors.LoopCounterWrite{Name: "i"}).Error())
+	assert.Equal(t, strings.Count(output.String(), "\n"), 1)
+
+	// The warning is opt-in
+	output.Reset()
+	err = Check(filepath.Join("build", "errors", "testdata", "warn-counter-write.q"))
+	assert.Nil(t, err)
+	assert.Equal(t, output.String(), "")
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		File            string
+		ExpectedWarning error
+	}{
+		{"warn-unused-parameter.q", &errors.UnusedParameter{Name: "b"}},
+	}
+
+	defer log.Error.SetOutput(io.Discard)
+
+	for _, test := range tests {
+		test := test
+		name := strings.TrimSuffix(test.File, ".q")
+
+		t.Run(name, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			log.Error.SetOutput(output)
+			err := Check(filepath.Join("build", "errors", "testdata", test.File))
+			assert.Nil(t, err)
+			assert.Contains(t, output.String(), test.ExpectedWarning.Error())
+			assert.Equal(t, strings.Count(output.String(), "\n"), 1)
 		})
 	}
 }
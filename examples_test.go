@@ -1,7 +1,14 @@
 package main_test
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/akyoto/assert"
+	"github.com/akyoto/q/build"
 )
 
 // examples is a list of examples with their expected output and exit code.
@@ -12,14 +19,80 @@ var examples = []struct {
 	ExpectedExitCode int
 }{
 	{"hello", "Hello\n", 0},
+	{"args", "1\n", 0},
+	{"array", "9\n82\nHello\n285\n5\n", 0},
+	{"assembly", "Hello\n", 76},
+	{"assert", "3\nassert.q:6:2: assert [x > 5]\n", 103},
+	{"bitwise", "5 & 3 == 1\n5 | 2 == 7\n5 ^ 3 == 6\n5 & 4294967295 == 5\n5 | 3 & 2 ^ 1 == 7\n", 0},
+	{"blocks", "", 42},
+	{"bool", "x > 5\nfound\nodd\nin range\n", 27},
+	{"break", "", 38},
+	{"callback", "37\n42\n40\n7\n", 8},
+	{"cast", "2\n65534\n-2\n25536\n3\n-3\n0.5\n129\noverflow\n", 0},
+	{"characters", "65\n26\nHi\n1\n0\n", 0},
+	{"comments", "3\n7\n5\n", 0},
+	{"compound", "10 %= 3 == 1\n-7 %= 3 == -1\n", 2},
+	{"concat", "Hello World\nMultiple texts are joined\n5\nLine 1\nLine 2\n", 0},
 	{"contracts", "f: expect [n < 10]\n", 1},
+	{"constantif", "level 2\nlevel * 2 == 4\n7\n", 0},
+	{"constants", "32\n30\n64\n", 4},
+	{"continue", "", 33},
+	{"cpuid", "vendor\nsame vendor\n6\n", 0},
+	{"defer", "working\n1\ncleanup\nexit\n10\nexit\n0\n6\n6\n", 0},
+	{"discard", "Hello\n", 7},
+	{"division", "", 0},
+	{"else", "zero\none\ntwo\nmany\na == 3\n", 0},
+	{"escapes", "a\tb\n\"quoted\"\nback\\slash\nit's\ntwo\nlines\n", 0},
+	{"exit", "10\n", 42},
 	{"fibonacci", "", 89},
+	{"float", "12.56636\n3.75\n9.5\n3.5\n-3.14159\n0.785398\n0.3\n2.0\n6.0\n1.0e+20\n-1.234568e+22\ninf\n-inf\nnan\n", 0},
+	{"forward", "9\n1\n1\ndefined later\n", 0},
 	{"files", "", 0},
 	{"functions", "123456789\n123456789\n123456789\n123456789\n", 0},
-	{"loops", "Hello\nHello\nHello\n\nH\nHe\nHel\nHell\nHello\n", 0},
-	{"memory", "ABCD\n", 0},
+	{"hex", "0x0\n0xff\n0xdeadbeef\n0xffffffffffffffff\n0x8000000000000000\n0x100a\n0x1000\n", 0},
+	{"identical", "100\n100\n625\n625\n3\n", 32},
+	{"inline", "49\n10\n13\n", 0},
+	{"length", "5\n6\n11\nHelloWorld!", 66},
+	{"literals", "255\n10\n15\n3735928559\n-16\n-1\n9223372036854775807\n11\n26\n1000000\n65775\n1000.5\n", 0},
+	{"logical", "a < b && b < 10\na > b || b == 7\nshort-circuit\n1\nboth\nstored\ninside\n", 5},
+	{"loops", "Hello\nHello\nHello\n\nH\nHe\nHel\nHell\nHello\n-3\n-2\n.\n.\n.\n.\n0\n3\n6\n9\n", 0},
+	{"memory", "ABCD\n100255\n65535\n1095216760480\n200\n-56\n-1\n100000\n", 0},
+	{"minmax", "-7\n3\n3\n-14\n10\n0\n9\n", 0},
+	{"multiplyadd", "5\n17\n26\n8\n2\n43\n", 0},
+	{"nested", "1022\n122\n1223\n1125\n455\n", 0},
+	{"overflow", "max + 1\n-9223372036854775808\n", 0},
+	{"powers", "56\n7\n-7168\n30064771072\n56\n-3\n-1\n-7\n3\n-1\n-3\n0\n3\n0\n-7\n-7\n", 0},
+	{"print", "42\n0\n-1234\n-2465\n-9223372036854775808\n7\n8\n15\n7 + 8 = 15\nMultiple texts\nText 4\ntrue false\n-5 -70000\n200 18446744073709551615\n", 0},
+	{"read", "0\n", 0},
+	{"registers", "150\n15\n113\n", 1},
 	{"strings", "HelloWorld", 0},
+	{"remainder", "17 % 5 == 2\na % b == 2\n23\n6\n-2\n4\n", 4},
+	{"repeat", "6\n*****\n9\n", 10},
+	{"return", "-1\n0\n1\n400\n-1\n13\n4\n199\n11\n10\n6\n", 0},
+	{"sizeof", "8\n2\n16\n5\n8\n", 0},
+	{"spill", "3\n7\n15\n56\n16\n20\n101\n189\n", 16},
+	{"shift", "5 << 2 == 20\n-16 >> 2 == -4\n5 << 3 == 40\n5 << 3 >> 1 == 20\n1 << 3 + 1 == 9\n", 0},
 	{"struct", "", 50},
+	{"switch", "zero\nsmall\nthree\nmany\n28\n30\n0\n18\n", 2},
+	{"unsigned", "big > small\nsmall < big\nabove\nisAbove(big, 100)\n100 <= big\n-1 < 1\n", 0},
+	{"unary", "-5 + 3 == -2\n-a + 3 == -2\n- -a == 5\n--a == 5\n~a == -6\n10 - -a * 2 == 20\n~(a & 4) & 7 == 3\na*-1 == -5\na+-b == 10\n(-a)*-b == -25\na==-b\n", 0},
+	{"while", "", 35},
+	{"widening", "Int8 -> Int64\nInt16 -> Int64\nInt32 -> Int64\nUInt8 -> Int64\nInt32 + UInt8\n", 0},
+	{"write", "Hello World!\n0 1 2 3\n", 0},
+}
+
+// optimizedExamples is a list of examples that require an optimized build.
+var optimizedExamples = []struct {
+	Name             string
+	ExpectedOutput   string
+	ExpectedExitCode int
+}{
+	{"assert", "3\nunreachable\n", 0},
+	{"division", "", 0},
+	{"identical", "100\n100\n625\n625\n3\n", 32},
+	{"multiplyadd", "5\n17\n26\n8\n2\n43\n", 0},
+	{"powers", "56\n7\n-7168\n30064771072\n56\n-3\n-1\n-7\n3\n-1\n-3\n0\n3\n0\n-7\n-7\n", 0},
+	{"tailcall", "20000000\n", 0},
 }
 
 func TestExamples(t *testing.T) {
@@ -27,7 +100,72 @@ func TestExamples(t *testing.T) {
 		example := example
 
 		t.Run(example.Name, func(t *testing.T) {
-			Run(t, "./examples/"+example.Name, example.ExpectedOutput, example.ExpectedExitCode)
+			Run(t, "./examples/"+example.Name, example.ExpectedOutput, example.ExpectedExitCode, false)
+		})
+	}
+}
+
+func TestOptimizedExamples(t *testing.T) {
+	for _, example := range optimizedExamples {
+		example := example
+
+		t.Run(example.Name, func(t *testing.T) {
+			Run(t, "./examples/"+example.Name, example.ExpectedOutput, example.ExpectedExitCode, true)
+		})
+	}
+}
+
+func TestReassembledExamples(t *testing.T) {
+	for _, tool := range []string{"as", "ld"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+
+	for _, example := range examples {
+		example := example
+
+		t.Run(example.Name, func(t *testing.T) {
+			directory := t.TempDir()
+			native := filepath.Join(directory, "native")
+			reassembled := filepath.Join(directory, "reassembled")
+			source := filepath.Join(directory, "reassembled.s")
+			object := filepath.Join(directory, "reassembled.o")
+
+			b, err := build.New("./examples/" + example.Name)
+			assert.Nil(t, err)
+			b.ExecutablePath = native
+			assert.Nil(t, b.Run())
+
+			assembly := Assembly(t, "./examples/"+example.Name)
+			assert.Nil(t, os.WriteFile(source, []byte(assembly), 0o644))
+			assert.Nil(t, exec.Command("as", source, "-o", object).Run())
+			assert.Nil(t, exec.Command("ld", object, "-o", reassembled).Run())
+
+			expectedOutput, expectedExitCode := Execute(t, native)
+			output, exitCode := Execute(t, reassembled)
+			assert.Equal(t, exitCode, expectedExitCode)
+			assert.DeepEqual(t, output, expectedOutput)
 		})
 	}
 }
+
+func BenchmarkExamples(b *testing.B) {
+	for _, example := range optimizedExamples {
+		example := example
+
+		for _, optimize := range []bool{false, true} {
+			optimize := optimize
+			name := example.Name
+
+			if optimize {
+				name += "/optimized"
+			}
+
+			b.Run(name, func(b *testing.B) {
+				median := Measure(b, "./examples/"+example.Name, optimize, b.N)
+				b.ReportMetric(float64(median)/float64(time.Nanosecond), "ns/median")
+			})
+		}
+	}
+}
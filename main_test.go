@@ -1,14 +1,20 @@
 package main_test
 
 import (
+	"bytes"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/akyoto/assert"
 	"github.com/akyoto/q/build"
+	"github.com/akyoto/q/build/assembler/instructions"
 	"github.com/akyoto/q/build/log"
 )
 
@@ -20,10 +26,11 @@ func TestMain(m *testing.M) {
 
 // Run builds and runs the program to
 // check if the output matches the expected output.
-func Run(t *testing.T, path string, expectedOutput string, expectedExitCode int) {
+func Run(t *testing.T, path string, expectedOutput string, expectedExitCode int, optimize bool) {
 	build, err := build.New(path)
 	assert.Nil(t, err)
 	assert.True(t, len(build.ExecutablePath) > 0)
+	build.Optimize = optimize
 	defer os.Remove(build.ExecutablePath)
 
 	t.Run("Compile", func(t *testing.T) {
@@ -36,33 +43,111 @@ func Run(t *testing.T, path string, expectedOutput string, expectedExitCode int)
 	})
 
 	t.Run("Output", func(t *testing.T) {
-		cmd := exec.Command(build.ExecutablePath)
-		output, err := cmd.Output()
-		exitCode := 0
+		output, exitCode := Execute(t, build.ExecutablePath)
+		assert.Equal(t, exitCode, expectedExitCode)
+		assert.DeepEqual(t, output, expectedOutput)
+	})
+}
 
-		if err != nil {
-			exitError, ok := err.(*exec.ExitError)
+// Execute runs the executable and returns its output and exit code.
+func Execute(t *testing.T, executable string) (string, int) {
+	t.Helper()
+	output, err := exec.Command(executable).Output()
 
-			if !ok {
-				t.Fatal(err)
-			}
+	if err != nil {
+		exitError, ok := err.(*exec.ExitError)
 
-			exitCode = exitError.ExitCode()
+		if !ok {
+			t.Fatal(err)
 		}
 
-		assert.Equal(t, exitCode, expectedExitCode)
-		assert.DeepEqual(t, string(output), expectedOutput)
+		return string(output), exitError.ExitCode()
+	}
+
+	return string(output), 0
+}
+
+// Measure builds the program and runs it the given number of times
+// to return the median wall-clock execution time.
+func Measure(tb testing.TB, path string, optimize bool, runs int) time.Duration {
+	tb.Helper()
+	build, err := build.New(path)
+	assert.Nil(tb, err)
+	build.Optimize = optimize
+	defer os.Remove(build.ExecutablePath)
+
+	err = build.Run()
+	assert.Nil(tb, err)
+	durations := make([]time.Duration, 0, runs)
+
+	for i := 0; i < runs; i++ {
+		cmd := exec.Command(build.ExecutablePath)
+		start := time.Now()
+		err := cmd.Run()
+		durations = append(durations, time.Since(start))
+
+		if _, isExitError := err.(*exec.ExitError); err != nil && !isExitError {
+			tb.Fatal(err)
+		}
+	}
+
+	sort.Slice(durations, func(a, b int) bool {
+		return durations[a] < durations[b]
 	})
+
+	return durations[len(durations)/2]
+}
+
+// Assembly builds the program and returns the generated assembly.
+func Assembly(t *testing.T, path string) string {
+	return AssemblyWith(t, path, func(*build.Build) {})
+}
+
+// AssemblyWith builds the program after the configuration
+// function modified the build and returns the generated assembly.
+func AssemblyWith(t *testing.T, path string, configure func(*build.Build)) string {
+	t.Helper()
+	output := &bytes.Buffer{}
+	b, err := build.New(path)
+	assert.Nil(t, err)
+	configure(b)
+	b.EmitAssembly = output
+	defer os.Remove(b.ExecutablePath)
+	assert.Nil(t, b.Run())
+	return output.String()
+}
+
+// FunctionAssembly returns the instructions of a single function in the assembly.
+func FunctionAssembly(t *testing.T, assembly string, function string) string {
+	t.Helper()
+	start := strings.Index(assembly, "\n"+instructions.Symbol(function)+":\n")
+	assert.True(t, start != -1)
+	body := assembly[start+1:]
+	return body[:strings.Index(body, "\n\n")]
+}
+
+// CountMatches returns how often the instruction pattern occurs in the assembly.
+// Patterns match any register with `\w+` so that they don't depend on the register allocation.
+func CountMatches(assembly string, pattern string) int {
+	return len(regexp.MustCompile(pattern).FindAllStringIndex(assembly, -1))
 }
 
 // Check creates a build with a single file.
 func Check(inputFile string) error {
+	return CheckWith(inputFile, func(*build.Environment) {})
+}
+
+// CheckWith creates a build with a single file
+// after the configuration function modified the environment.
+func CheckWith(inputFile string, configure func(*build.Environment)) error {
 	compiler, err := build.New(filepath.Dir(inputFile))
 
 	if err != nil {
 		return err
 	}
 
+	configure(compiler.Environment)
+
 	functions, structs, imports, errors := build.FindFunctionsInFile(inputFile, compiler.MainPackage, compiler.Environment)
 	err = compiler.Environment.Import(compiler.MainPackage, functions, structs, imports, errors)
 